@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bonjourmalware/melody/internal/fileutils"
+	"github.com/bonjourmalware/melody/internal/persona"
+	"github.com/bonjourmalware/melody/internal/replay"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	personaCmd = &cobra.Command{
+		Use:   "persona",
+		Short: "Handle Melody web server personas",
+		Long:  `This subcommand is used to handle the personas mimicked by Melody's dummy HTTP/S servers`,
+	}
+	importPersonaCmd = &cobra.Command{
+		Use:   "import",
+		Args:  cobra.ExactArgs(1),
+		Short: "Create a persona replaying a recorded web application",
+		Long: `This subcommand is used to create a persona replaying the exchanges recorded from a real web application.
+The source is either a HAR file, or a directory containing HAR files and pairs of raw HTTP messages named
+<name>.request and <name>.response`,
+		Run: importPersona,
+	}
+
+	// Import
+	personaName    string
+	personasDir    string
+	personaBase    string
+	replayFallback string
+	forceOverwrite bool
+)
+
+func init() {
+	RootCmd.AddCommand(personaCmd)
+
+	personaCmd.AddCommand(importPersonaCmd)
+	importPersonaCmd.Flags().StringVarP(&personaName, "name", "n", "", `Name of the new persona (required)`)
+	importPersonaCmd.Flags().StringVarP(&personasDir, "dir", "d", "", `Personas directory (default to the server.personas.dir value of Melody's config)`)
+	importPersonaCmd.Flags().StringVarP(&personaBase, "base", "b", "", `Built-in persona to use for the error pages and the methods handling`)
+	importPersonaCmd.Flags().StringVarP(&replayFallback, "fallback", "F", "", `Recorded path served for the requests without a matching exchange`)
+	importPersonaCmd.Flags().BoolVarP(&forceOverwrite, "force", "f", false, `Overwrite an existing persona`)
+	_ = importPersonaCmd.MarkFlagRequired("name")
+}
+
+func importPersona(_ *cobra.Command, args []string) {
+	source := args[0]
+
+	dir := personasDir
+	if dir == "" {
+		if melodyConf == nil {
+			fmt.Println("❌ Failed to load Melody's config, use --dir to set the personas directory")
+			return
+		}
+		dir = filepath.Join(meloctlConf.MelodyHomeDir, melodyConf.ServerPersonasDir)
+	}
+
+	// The name is used as the persona's file and recording directory names
+	if personaName == "" || personaName != filepath.Base(personaName) || strings.HasPrefix(personaName, ".") {
+		fmt.Printf("❌ Invalid persona name '%s', it must be a file name not starting with a dot\n", personaName)
+		return
+	}
+
+	personaPath := filepath.Join(dir, personaName+".yml")
+	if ok, _ := fileutils.Exists(personaPath); ok && !forceOverwrite {
+		fmt.Printf("❌ [%s]: persona already exists, use --force to overwrite it\n", personaPath)
+		return
+	}
+
+	rec, err := replay.Import(source)
+	if err != nil {
+		fmt.Printf("❌ [%s]: %s\n", source, err)
+		return
+	}
+
+	if len(rec.Exchanges) == 0 {
+		fmt.Printf("❌ [%s]: no exchange found\n", source)
+		return
+	}
+
+	raw := persona.RawPersona{}
+	if personaBase != "" {
+		var ok bool
+		if raw, ok = persona.Builtin(personaBase); !ok {
+			fmt.Printf("❌ Unknown built-in persona '%s'\n", personaBase)
+			return
+		}
+	}
+
+	raw.Name = personaName
+	raw.Replay = personaName
+	raw.ReplayFallback = replayFallback
+
+	if server := rec.Server(); server != "" {
+		raw.Server = server
+	}
+
+	if order := rec.HeaderOrder(); len(order) > 0 {
+		raw.HeaderOrder = order
+	}
+
+	if err := rec.Save(filepath.Join(dir, personaName)); err != nil {
+		fmt.Printf("❌ [%s]: %s\n", dir, err)
+		return
+	}
+
+	out, err := yaml.Marshal(raw)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if err := ioutil.WriteFile(personaPath, out, 0644); err != nil {
+		fmt.Printf("❌ [%s]: %s\n", personaPath, err)
+		return
+	}
+
+	fmt.Printf("✅ [%s]: imported %d exchanges\n", personaPath, len(rec.Exchanges))
+}
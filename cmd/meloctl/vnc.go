@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bonjourmalware/melody/internal/vncd"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	vncCmd = &cobra.Command{
+		Use:   "vnc",
+		Short: "Handle the VNC events of Melody",
+		Long:  `This subcommand is used to handle the data logged in the VNC events of Melody`,
+	}
+	crackVNCCmd = &cobra.Command{
+		Use:   "crack",
+		Args:  cobra.ExactArgs(2),
+		Short: "Recover the password of a VNC authentication from a wordlist",
+		Long: `This subcommand is used to look for the password of a VNC authentication in a wordlist, one password per line.
+The authentication is given as the "hash" field of a VNC event, in the "$vnc$*<challenge>*<response>" format`,
+		Run: crackVNC,
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(vncCmd)
+	vncCmd.AddCommand(crackVNCCmd)
+}
+
+func crackVNC(_ *cobra.Command, args []string) {
+	hash, wordlistPath := args[0], args[1]
+
+	parts := strings.Split(hash, "*")
+	if len(parts) != 3 || parts[0] != "$vnc$" {
+		fmt.Printf("❌ [%s]: not in the '$vnc$*<challenge>*<response>' format\n", hash)
+		return
+	}
+
+	challenge, err := hex.DecodeString(parts[1])
+	if err != nil {
+		fmt.Printf("❌ [%s]: invalid challenge : %s\n", hash, err)
+		return
+	}
+
+	response, err := hex.DecodeString(parts[2])
+	if err != nil {
+		fmt.Printf("❌ [%s]: invalid response : %s\n", hash, err)
+		return
+	}
+
+	wordlist, err := os.Open(wordlistPath)
+	if err != nil {
+		fmt.Printf("❌ [%s]: %s\n", wordlistPath, err)
+		return
+	}
+	defer wordlist.Close()
+
+	var candidates []string
+	scanner := bufio.NewScanner(wordlist)
+	for scanner.Scan() {
+		candidates = append(candidates, strings.TrimRight(scanner.Text(), "\r"))
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Printf("❌ [%s]: %s\n", wordlistPath, err)
+		return
+	}
+
+	password, ok := vncd.RecoverPassword(challenge, response, candidates)
+	if !ok {
+		fmt.Printf("❌ [%s]: password not found in %d candidates\n", hash, len(candidates))
+		return
+	}
+
+	fmt.Printf("✅ [%s]: %s\n", hash, password)
+}
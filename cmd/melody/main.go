@@ -8,9 +8,12 @@ import (
 	"path/filepath"
 	"syscall"
 
+	"github.com/bonjourmalware/melody/internal/artifacts"
 	"github.com/bonjourmalware/melody/internal/engine"
+	"github.com/bonjourmalware/melody/internal/responders"
 	"github.com/bonjourmalware/melody/internal/rules"
 	"github.com/bonjourmalware/melody/internal/sensor"
+	"github.com/bonjourmalware/melody/internal/udpresponders"
 
 	"github.com/bonjourmalware/melody/internal/logging"
 
@@ -66,10 +69,47 @@ func init() {
 		logging.Std.Println(err)
 		os.Exit(1)
 	}
+	if config.Cfg.ArtifactsEnable {
+		artifacts.Default, err = artifacts.New(config.Cfg.ArtifactsDir, config.Cfg.ArtifactsMaxSize, config.Cfg.ArtifactsRetention)
+		if err != nil {
+			logging.Std.Println(err)
+			os.Exit(1)
+		}
+	}
+
 	loaded := rules.LoadRulesDir(filepath.Join(config.Cfg.HomeDirPath, config.Cfg.RulesDir))
 
 	logging.Std.Printf("Loaded %d rules\n", loaded)
-	logging.Std.Printf("Listing on interface %s\n", config.Cfg.Interface)
+
+	if config.Cfg.RespondersEnable {
+		responders.Loaded, err = responders.LoadDir(filepath.Join(config.Cfg.HomeDirPath, config.Cfg.RespondersDir))
+		if err != nil {
+			logging.Std.Println(err)
+			os.Exit(1)
+		}
+
+		logging.Std.Printf("Loaded %d responders\n", len(responders.Loaded))
+	}
+
+	// The replayed packets are not answered
+	if config.Cfg.RespondersUDPEnable && config.Cfg.PcapFile == nil {
+		udpresponders.Default, err = udpresponders.New(udpresponders.Options{
+			Services:           config.Cfg.RespondersUDPServices,
+			MaxReplySize:       config.Cfg.RespondersUDPMaxReplySize,
+			PerSourceRateLimit: config.Cfg.RespondersUDPPerSourceRateLimit,
+			GlobalRateLimit:    config.Cfg.RespondersUDPGlobalRateLimit,
+		})
+		if err != nil {
+			logging.Std.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if config.Cfg.IsServerOnly() {
+		logging.Std.Println("Running in server-only mode")
+	} else {
+		logging.Std.Printf("Listing on interface %s\n", config.Cfg.Interface)
+	}
 }
 
 func main() {
@@ -0,0 +1,116 @@
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+)
+
+var (
+	// Default is the store used by the events, nil if the artifacts storage is disabled
+	Default *Store
+)
+
+// Artifact describes a stored file
+type Artifact struct {
+	SHA256 string
+	Size   int
+}
+
+// Store writes files to a directory once, named by the hex-encoded SHA-256 of their content
+type Store struct {
+	dir       string
+	maxSize   uint64
+	retention time.Duration
+}
+
+// New creates a Store in the given directory, creating it if needed. The files larger than maxSize are refused, and
+// the files not stored again for longer than retention are removed by Prune, unless retention is 0
+func New(dir string, maxSize uint64, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create the artifacts directory : %s", err)
+	}
+
+	return &Store{dir: dir, maxSize: maxSize, retention: retention}, nil
+}
+
+// MaxSize returns the size of the largest file accepted by the store
+func (s *Store) MaxSize() uint64 {
+	return s.maxSize
+}
+
+// Save writes data to the store if it is not already there, and returns the corresponding Artifact
+func (s *Store) Save(data []byte) (*Artifact, error) {
+	if uint64(len(data)) > s.maxSize {
+		return nil, fmt.Errorf("artifact not stored (over %s : %s)", datasize.ByteSize(s.maxSize).HumanReadable(), (datasize.ByteSize(len(data)) * datasize.B).HumanReadable())
+	}
+
+	sum := sha256.Sum256(data)
+	artifact := &Artifact{SHA256: hex.EncodeToString(sum[:]), Size: len(data)}
+	path := filepath.Join(s.dir, artifact.SHA256)
+
+	// Already stored, keep it for another retention period
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err == nil {
+		return artifact, nil
+	}
+
+	// Write to a temporary file first so that a partial artifact is never visible under its hash
+	tmp, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store artifact %s : %s", artifact.SHA256, err)
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to store artifact %s : %s", artifact.SHA256, err)
+	}
+
+	return artifact, nil
+}
+
+// Prune removes the artifacts that have not been stored again for longer than the retention period, and returns how
+// many were removed
+func (s *Store) Prune() (int, error) {
+	if s.retention == 0 {
+		return 0, nil
+	}
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int
+	limit := time.Now().Add(-s.retention)
+
+	for _, file := range files {
+		// Skip the leftovers of the writes in progress
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") || file.ModTime().After(limit) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(s.dir, file.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,81 @@
+package artifacts
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := New(filepath.Join(dir, "store"), 16, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artifact, err := store.Save([]byte("<?php system($_G"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "07bf78f70f10a0c4669d89e868c303e581ad93a904a3457fbf826b12f2466a6b"
+	if artifact.Size != 16 || artifact.SHA256 != expected {
+		t.Errorf("unexpected artifact %+v", artifact)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "store", artifact.SHA256))
+	if err != nil || string(content) != "<?php system($_G" {
+		t.Errorf("unexpected stored content %q (%v)", content, err)
+	}
+
+	// Storing the same content again does not create another file
+	if _, err := store.Save(content); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "store"))
+	if len(files) != 1 {
+		t.Errorf("expected a single stored file, got %d", len(files))
+	}
+
+	if _, err := store.Save(make([]byte, 17)); err == nil {
+		t.Error("expected an error for an artifact over the maximum size")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := New(dir, 1024, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, _ := store.Save([]byte("old"))
+	recent, _ := store.Save([]byte("recent"))
+
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, old.SHA256), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := store.Prune()
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 removed artifact, got %d (%v)", removed, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, recent.SHA256)); err != nil {
+		t.Errorf("the recent artifact has been removed : %s", err)
+	}
+}
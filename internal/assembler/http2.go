@@ -0,0 +1,176 @@
+package assembler
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+const (
+	// Maximum number of request streams waiting for their END_STREAM flag on a single connection
+	maxPendingHTTP2Streams = 100
+	// Size of the HPACK dynamic table, as advertised by the default SETTINGS of a server
+	http2HeaderTableSize = 4096
+)
+
+// http2Stream holds the data of a request stream until it is complete
+type http2Stream struct {
+	headers *http2.MetaHeadersFrame
+	body    bytes.Buffer
+	length  int64
+}
+
+// isHTTP2Preface checks if the next bytes of the stream are the HTTP/2 client connection preface, which is sent
+// either with prior knowledge or after an h2c upgrade request
+func isHTTP2Preface(buf *bufio.Reader) bool {
+	preface, err := buf.Peek(len(http2.ClientPreface))
+	if err != nil {
+		return false
+	}
+
+	return string(preface) == http2.ClientPreface
+}
+
+// readHTTP2Requests decodes the frames sent by an HTTP/2 client and calls fn for each complete request stream. It
+// reads the whole stream, even if the frames can't be decoded anymore
+func readHTTP2Requests(buf *bufio.Reader, fn func(*http.Request)) {
+	// We must read until EOF in any case
+	defer io.Copy(ioutil.Discard, buf)
+
+	if _, err := buf.Discard(len(http2.ClientPreface)); err != nil {
+		return
+	}
+
+	framer := http2.NewFramer(ioutil.Discard, buf)
+	framer.ReadMetaHeaders = hpack.NewDecoder(http2HeaderTableSize, nil)
+
+	streams := make(map[uint32]*http2Stream)
+
+	// Send what has been received of the streams left open when the connection ends
+	defer func() {
+		for _, stream := range streams {
+			if req, err := stream.toRequest(); err == nil {
+				fn(req)
+			}
+		}
+	}()
+
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			if _, ok := err.(http2.StreamError); ok {
+				continue
+			}
+			return
+		}
+
+		id := frame.Header().StreamID
+		stream, ok := streams[id]
+
+		switch f := frame.(type) {
+		case *http2.MetaHeadersFrame:
+			// A second HEADERS frame on the same stream holds the trailers
+			if !ok {
+				if len(streams) >= maxPendingHTTP2Streams {
+					continue
+				}
+				stream = &http2Stream{headers: f}
+				streams[id] = stream
+			}
+
+			if !f.StreamEnded() {
+				continue
+			}
+
+		case *http2.DataFrame:
+			if !ok {
+				continue
+			}
+
+			data := f.Data()
+			stream.length += int64(len(data))
+			if remaining := int64(config.Cfg.MaxPOSTDataSize) - int64(stream.body.Len()); remaining > 0 {
+				if int64(len(data)) > remaining {
+					data = data[:remaining]
+				}
+				stream.body.Write(data)
+			}
+
+			if !f.StreamEnded() {
+				continue
+			}
+
+		case *http2.RSTStreamFrame:
+			if !ok {
+				continue
+			}
+
+		case *http2.GoAwayFrame:
+			return
+
+		default:
+			continue
+		}
+
+		delete(streams, id)
+
+		req, err := stream.toRequest()
+		if err != nil {
+			continue
+		}
+
+		fn(req)
+	}
+}
+
+// toRequest maps the pseudo-headers and the regular headers of the stream to an http.Request
+func (stream *http2Stream) toRequest() (*http.Request, error) {
+	var err error
+
+	req := &http.Request{
+		Method:        stream.headers.PseudoValue("method"),
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		ProtoMinor:    0,
+		Header:        make(http.Header),
+		Host:          stream.headers.PseudoValue("authority"),
+		RequestURI:    stream.headers.PseudoValue("path"),
+		ContentLength: stream.length,
+		Body:          ioutil.NopCloser(bytes.NewReader(stream.body.Bytes())),
+	}
+
+	for _, field := range stream.headers.RegularFields() {
+		req.Header.Add(http.CanonicalHeaderKey(field.Name), field.Value)
+	}
+
+	if contentLength := req.Header.Get("Content-Length"); contentLength != "" {
+		if length, err := strconv.ParseInt(contentLength, 10, 64); err == nil {
+			req.ContentLength = length
+		}
+	}
+
+	if req.Host == "" {
+		req.Host = req.Header.Get("Host")
+	}
+
+	if req.Method == http.MethodConnect && req.RequestURI == "" {
+		req.URL = &url.URL{Host: req.Host}
+		req.RequestURI = req.Host
+		return req, nil
+	}
+
+	req.URL, err = url.ParseRequestURI(req.RequestURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
@@ -0,0 +1,108 @@
+package assembler
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+func init() {
+	config.Cfg = config.NewConfig()
+}
+
+func encodeHeaders(t *testing.T, fields ...string) []byte {
+	var block bytes.Buffer
+	enc := hpack.NewEncoder(&block)
+
+	for i := 0; i < len(fields); i += 2 {
+		if err := enc.WriteField(hpack.HeaderField{Name: fields[i], Value: fields[i+1]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return block.Bytes()
+}
+
+func TestReadHTTP2Requests(t *testing.T) {
+	var stream bytes.Buffer
+	stream.WriteString(http2.ClientPreface)
+
+	framer := http2.NewFramer(&stream, nil)
+	if err := framer.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID: 1,
+		BlockFragment: encodeHeaders(t,
+			":method", "GET",
+			":scheme", "http",
+			":authority", "example.com",
+			":path", "/console/login?user=admin",
+			"user-agent", "curl/7.74.0",
+		),
+		EndStream:  true,
+		EndHeaders: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID: 3,
+		BlockFragment: encodeHeaders(t,
+			":method", "POST",
+			":scheme", "http",
+			":authority", "example.com",
+			":path", "/upload",
+		),
+		EndHeaders: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := framer.WriteData(3, false, []byte("Enter my ")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := framer.WriteData(3, true, []byte("world")); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []*http.Request
+	readHTTP2Requests(bufio.NewReader(&stream), func(req *http.Request) {
+		requests = append(requests, req)
+	})
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	get := requests[0]
+	if get.Method != "GET" || get.Proto != "HTTP/2.0" || get.Host != "example.com" {
+		t.Errorf("unexpected request line : %s %s %s", get.Method, get.Proto, get.Host)
+	}
+
+	if get.URL.RequestURI() != "/console/login?user=admin" {
+		t.Errorf("unexpected URI : %s", get.URL.RequestURI())
+	}
+
+	if get.Header.Get("User-Agent") != "curl/7.74.0" {
+		t.Errorf("unexpected User-Agent : %s", get.Header.Get("User-Agent"))
+	}
+
+	post := requests[1]
+	body, err := ioutil.ReadAll(post.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if post.Method != "POST" || string(body) != "Enter my world" || post.ContentLength != 14 {
+		t.Errorf("unexpected POST request : %s %q (%d)", post.Method, body, post.ContentLength)
+	}
+}
@@ -2,12 +2,17 @@ package assembler
 
 import (
 	"bufio"
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/dedup"
 	"github.com/bonjourmalware/melody/internal/engine"
 	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/proxyproto"
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/tcpassembly"
 	"github.com/google/gopacket/tcpassembly/tcpreader"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 )
 
@@ -35,7 +40,22 @@ func (h *HTTPStreamFactory) New(net, transport gopacket.Flow) tcpassembly.Stream
 
 func (h *HTTPStream) run() {
 	buf := bufio.NewReader(&h.r)
+
+	send := h.sendRequest
+	if isServerProxied(buf, net.ParseIP(h.net.Src().String())) {
+		// The dummy HTTP server logs these requests with the client address sent by the proxy
+		send = func(req *http.Request) {
+			_, _ = io.Copy(ioutil.Discard, req.Body)
+		}
+	}
+
 	for {
+		// Handles both prior knowledge and h2c upgrades, as the client sends the preface after its upgrade request
+		if isHTTP2Preface(buf) {
+			readHTTP2Requests(buf, send)
+			return
+		}
+
 		req, err := http.ReadRequest(buf)
 		if err == io.EOF {
 			// We must read until we see an EOF... very important!
@@ -43,8 +63,23 @@ func (h *HTTPStream) run() {
 		} else if err != nil {
 
 		} else {
-			ev, _ := events.NewHTTPEvent(req, h.net, h.transport)
-			engine.EventChan <- ev
+			send(req)
 		}
 	}
 }
+
+// isServerProxied checks if the stream starts with the PROXY protocol header of a trusted proxy while the dummy HTTP
+// server logs its requests, in which case the server's events are kept rather than the sniffed ones
+func isServerProxied(buf *bufio.Reader, srcIP net.IP) bool {
+	return config.Cfg.LogHTTPRequests() && proxyproto.IsTrusted(srcIP, config.Cfg.ServerProxyProtocolTrustedCIDRs) &&
+		proxyproto.HasSignature(buf)
+}
+
+func (h *HTTPStream) sendRequest(req *http.Request) {
+	ev, _ := events.NewHTTPEvent(req, h.net, h.transport)
+	if config.Cfg.LogHTTPRequests() {
+		// Let the dummy HTTP server drop its own event for this request
+		dedup.SniffedHTTPRequests.Add(dedup.HTTPKey(ev.SourceIP, ev.SourcePort, ev.Verb, ev.RequestURI))
+	}
+	engine.EventChan <- ev
+}
@@ -0,0 +1,46 @@
+package assembler
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/bonjourmalware/melody/internal/config"
+)
+
+func TestIsServerProxied(t *testing.T) {
+	_, trusted, _ := net.ParseCIDR("10.0.0.0/8")
+	config.Cfg.ServerProxyProtocolTrustedCIDRs = []*net.IPNet{trusted}
+	defer func() {
+		config.Cfg.ServerProxyProtocolTrustedCIDRs = nil
+		config.Cfg.ServerHTTPLogRequests = false
+	}()
+
+	proxied := "PROXY TCP4 192.0.2.1 10.0.0.2 4242 80\r\nGET / HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	tests := []struct {
+		logRequests bool
+		srcIP       string
+		stream      string
+		expected    bool
+	}{
+		{true, "10.0.0.1", proxied, true},
+		{true, "192.0.2.1", proxied, false},
+		{true, "10.0.0.1", "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", false},
+		{false, "10.0.0.1", proxied, false},
+	}
+
+	for _, test := range tests {
+		config.Cfg.ServerHTTPLogRequests = test.logRequests
+		buf := bufio.NewReader(strings.NewReader(test.stream))
+
+		if got := isServerProxied(buf, net.ParseIP(test.srcIP)); got != test.expected {
+			t.Errorf("%+v : got %v", test, got)
+		}
+
+		// The header is left in the buffer
+		if line, _ := buf.ReadString('\n'); !strings.HasPrefix(test.stream, line) {
+			t.Errorf("%+v : unexpected first line %q", test, line)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/fileutils"
+)
+
+const (
+	// KeyTypeRSA2048 generates a 2048 bits RSA key
+	KeyTypeRSA2048 = "rsa-2048"
+	// KeyTypeRSA4096 generates a 4096 bits RSA key
+	KeyTypeRSA4096 = "rsa-4096"
+	// KeyTypeECDSAP256 generates an ECDSA key on the P-256 curve
+	KeyTypeECDSAP256 = "ecdsa-p256"
+	// KeyTypeECDSAP384 generates an ECDSA key on the P-384 curve
+	KeyTypeECDSAP384 = "ecdsa-p384"
+	// KeyTypeEd25519 generates an Ed25519 key
+	KeyTypeEd25519 = "ed25519"
+)
+
+var (
+	// KeyTypes lists the supported key types
+	KeyTypes = []string{
+		KeyTypeRSA2048,
+		KeyTypeRSA4096,
+		KeyTypeECDSAP256,
+		KeyTypeECDSAP384,
+		KeyTypeEd25519,
+	}
+)
+
+// Options describes the properties of a self-signed certificate
+type Options struct {
+	// Subject uses the openssl one-line format, such as "/C=AU/O=Internet Widgits Pty Ltd/CN=localhost"
+	Subject      string
+	SANs         []string
+	KeyType      string
+	ValidityDays int
+}
+
+// ParseSubject parses a distinguished name written in the openssl one-line format
+func ParseSubject(raw string) (pkix.Name, error) {
+	var name pkix.Name
+
+	for _, part := range strings.Split(raw, "/") {
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return name, fmt.Errorf("invalid subject attribute '%s'", part)
+		}
+
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		switch strings.ToUpper(key) {
+		case "CN":
+			name.CommonName = val
+		case "C":
+			name.Country = append(name.Country, val)
+		case "ST":
+			name.Province = append(name.Province, val)
+		case "L":
+			name.Locality = append(name.Locality, val)
+		case "O":
+			name.Organization = append(name.Organization, val)
+		case "OU":
+			name.OrganizationalUnit = append(name.OrganizationalUnit, val)
+		case "STREET":
+			name.StreetAddress = append(name.StreetAddress, val)
+		case "POSTALCODE":
+			name.PostalCode = append(name.PostalCode, val)
+		default:
+			return name, fmt.Errorf("unsupported subject attribute '%s'", key)
+		}
+	}
+
+	return name, nil
+}
+
+func generateKey(keyType string) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyTypeRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case KeyTypeECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+
+	return nil, fmt.Errorf("unsupported key type '%s'", keyType)
+}
+
+// GenerateSelfSigned creates a self-signed certificate and its private key, both PEM encoded
+func GenerateSelfSigned(opts Options) ([]byte, []byte, error) {
+	subject, err := ParseSubject(opts.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if opts.ValidityDays <= 0 {
+		return nil, nil, fmt.Errorf("invalid validity period of %d days", opts.ValidityDays)
+	}
+
+	key, err := generateKey(opts.KeyType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Backdate the certificate a little, so it does not look freshly generated to clients with a skewed clock
+	notBefore := time.Now().Add(-24 * time.Hour).Truncate(time.Hour)
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.AddDate(0, 0, opts.ValidityDays),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	// Only RSA keys are used for key encipherment
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	for _, san := range opts.SANs {
+		if ip := net.ParseIP(san); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, san)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rawKey, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rawKey})
+
+	return certPEM, keyPEM, nil
+}
+
+// generateLock serializes LoadOrGenerate, as the servers sharing the same key pair load it concurrently on startup
+var generateLock sync.Mutex
+
+// LoadOrGenerate loads the key pair at the given paths. If both files are missing and generate is true, a self-signed
+// certificate is created according to opts and written to the given paths. A pair with only one of its files present
+// is an error, so that the file supplied by the user is never overwritten
+func LoadOrGenerate(certPath string, keyPath string, generate bool, opts Options) (tls.Certificate, error) {
+	generateLock.Lock()
+	defer generateLock.Unlock()
+
+	certExists, err := fileutils.Exists(certPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyExists, err := fileutils.Exists(keyPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if (certExists && keyExists) || !generate {
+		return tls.LoadX509KeyPair(certPath, keyPath)
+	}
+
+	if certExists {
+		return tls.Certificate{}, fmt.Errorf("the certificate '%s' exists but its key '%s' is missing", certPath, keyPath)
+	}
+
+	if keyExists {
+		return tls.Certificate{}, fmt.Errorf("the key '%s' exists but its certificate '%s' is missing", keyPath, certPath)
+	}
+
+	certPEM, keyPEM, err := GenerateSelfSigned(opts)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate a certificate for '%s' : %s", certPath, err)
+	}
+
+	if err := writeFile(certPath, certPEM, 0644); err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := writeFile(keyPath, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, perm)
+}
@@ -0,0 +1,173 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestGenerateSelfSigned(t *testing.T) {
+	for _, keyType := range KeyTypes {
+		certPEM, keyPEM, err := GenerateSelfSigned(Options{
+			Subject:      "/C=FR/O=ACME Corp/CN=intranet.acme.local",
+			SANs:         []string{"intranet.acme.local", "192.0.2.1"},
+			KeyType:      keyType,
+			ValidityDays: 365,
+		})
+		if err != nil {
+			t.Fatalf("%s : %s", keyType, err)
+		}
+
+		pair, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Fatalf("%s : %s", keyType, err)
+		}
+
+		cert, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			t.Fatalf("%s : %s", keyType, err)
+		}
+
+		if cert.Subject.CommonName != "intranet.acme.local" || len(cert.Subject.Organization) != 1 || cert.Subject.Organization[0] != "ACME Corp" {
+			t.Errorf("%s : unexpected subject %s", keyType, cert.Subject)
+		}
+
+		if err := cert.VerifyHostname("192.0.2.1"); err != nil {
+			t.Errorf("%s : %s", keyType, err)
+		}
+	}
+}
+
+func TestParseSubjectErrors(t *testing.T) {
+	for _, subject := range []string{"/CN", "/XX=foo", "/CN="} {
+		if _, err := ParseSubject(subject); err == nil {
+			t.Errorf("expected an error for '%s'", subject)
+		}
+	}
+}
+
+func TestLoadOrGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "melody-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "certs", "cert.pem")
+	keyPath := filepath.Join(dir, "certs", "key.pem")
+	opts := Options{Subject: "/CN=localhost", KeyType: KeyTypeECDSAP256, ValidityDays: 1}
+
+	if _, err := LoadOrGenerate(certPath, keyPath, false, opts); err == nil {
+		t.Error("expected an error when the files are missing and the generation is disabled")
+	}
+
+	generated, err := LoadOrGenerate(certPath, keyPath, true, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The generated pair is reused on the next start
+	loaded, err := LoadOrGenerate(certPath, keyPath, true, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(generated.Certificate[0]) != string(loaded.Certificate[0]) {
+		t.Error("the certificate has been generated twice")
+	}
+}
+
+func TestLoadOrGenerateConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "melody-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	opts := Options{Subject: "/CN=localhost", KeyType: KeyTypeECDSAP256, ValidityDays: 1}
+
+	// The servers load the default pair at the same time on the first start
+	var wg sync.WaitGroup
+	loaded := make([]tls.Certificate, 4)
+	for idx := range loaded {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			loaded[idx], _ = LoadOrGenerate(certPath, keyPath, true, opts)
+		}(idx)
+	}
+	wg.Wait()
+
+	for _, cert := range loaded {
+		if len(cert.Certificate) == 0 || string(cert.Certificate[0]) != string(loaded[0].Certificate[0]) {
+			t.Fatal("the certificate has been generated more than once")
+		}
+	}
+
+	if _, err := tls.LoadX509KeyPair(certPath, keyPath); err != nil {
+		t.Error(err)
+	}
+
+	// A pair missing one of its files is not regenerated
+	if err := os.Remove(keyPath); err != nil {
+		t.Fatal(err)
+	}
+
+	before, _ := ioutil.ReadFile(certPath)
+	if _, err := LoadOrGenerate(certPath, keyPath, true, opts); err == nil {
+		t.Error("expected an error when the key is missing")
+	}
+
+	if after, _ := ioutil.ReadFile(certPath); string(before) != string(after) {
+		t.Error("the certificate has been overwritten")
+	}
+}
+
+func TestStoreGetCertificate(t *testing.T) {
+	newCert := func(cn string) tls.Certificate {
+		certPEM, keyPEM, err := GenerateSelfSigned(Options{Subject: "/CN=" + cn, KeyType: KeyTypeECDSAP256, ValidityDays: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	defaultCert := newCert("default")
+	exampleCert := newCert("example")
+	store := NewStore(defaultCert)
+	store.Add([]string{"www.example.com", "*.example.org"}, exampleCert)
+
+	tests := []struct {
+		ServerName string
+		Expected   tls.Certificate
+	}{
+		{"www.example.com", exampleCert},
+		{"WWW.Example.com.", exampleCert},
+		{"mail.example.org", exampleCert},
+		{"a.b.example.org", defaultCert},
+		{"example.org", defaultCert},
+		{"", defaultCert},
+	}
+
+	for _, test := range tests {
+		cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: test.ServerName})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(cert.Certificate[0]) != string(test.Expected.Certificate[0]) {
+			t.Errorf("unexpected certificate for '%s'", test.ServerName)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package certs
+
+import (
+	"crypto/tls"
+	"strings"
+)
+
+// storeEntry associates a certificate to the host names it is served for
+type storeEntry struct {
+	hosts []string
+	cert  *tls.Certificate
+}
+
+// Store selects the certificate to present according to the SNI sent by the client
+type Store struct {
+	defaultCert *tls.Certificate
+	entries     []storeEntry
+}
+
+// NewStore creates a Store serving the given certificate to the clients that did not match any other entry
+func NewStore(defaultCert tls.Certificate) *Store {
+	return &Store{defaultCert: &defaultCert}
+}
+
+// Add registers a certificate for the given host names. Wildcards are supported as the leftmost label, such
+// as "*.example.com"
+func (store *Store) Add(hosts []string, cert tls.Certificate) {
+	var lowerHosts []string
+	for _, host := range hosts {
+		lowerHosts = append(lowerHosts, strings.ToLower(host))
+	}
+
+	store.entries = append(store.entries, storeEntry{hosts: lowerHosts, cert: &cert})
+}
+
+// GetCertificate satisfies the tls.Config GetCertificate callback. The entries are checked in the order they were
+// added, and the default certificate is used if none matches
+func (store *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	serverName := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+
+	if serverName != "" {
+		for _, entry := range store.entries {
+			for _, host := range entry.hosts {
+				if MatchHost(host, serverName) {
+					return entry.cert, nil
+				}
+			}
+		}
+	}
+
+	return store.defaultCert, nil
+}
+
+// MatchHost checks if the lowercase server name matches the host pattern. Wildcards are supported as the leftmost label
+func MatchHost(pattern string, serverName string) bool {
+	if !strings.HasPrefix(pattern, "*.") {
+		return pattern == serverName
+	}
+
+	// The wildcard only matches a single label
+	idx := strings.Index(serverName, ".")
+	return idx > 0 && serverName[idx:] == pattern[1:]
+}
@@ -5,11 +5,20 @@ import (
 	"github.com/bonjourmalware/melody/internal/fileutils"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/bonjourmalware/melody/internal/certs"
 	"github.com/bonjourmalware/melody/internal/clihelper"
+	"github.com/bonjourmalware/melody/internal/credentials"
+	"github.com/bonjourmalware/melody/internal/tarpit"
+	"github.com/bonjourmalware/melody/internal/udpresponders"
+	"github.com/bonjourmalware/melody/internal/vncd"
 
 	"github.com/c2h5oh/datasize"
 
@@ -35,6 +44,43 @@ const (
 	// HTTPSKind is the constant used to define a Kind as HTTPS
 	HTTPSKind = "https"
 
+	// QUICKind is the constant used to define a Kind as QUIC
+	QUICKind = "quic"
+
+	// SNMPKind is the constant used to define a Kind as SNMP
+	SNMPKind = "snmp"
+
+	// SSHKind is the constant used to define a Kind as SSH
+	SSHKind = "ssh"
+
+	// TelnetKind is the constant used to define a Kind as a Telnet session
+	TelnetKind = "telnet"
+
+	// SMTPKind is the constant used to define a Kind as an SMTP session
+	SMTPKind = "smtp"
+
+	// RedisKind is the constant used to define a Kind as a Redis session
+	RedisKind = "redis"
+
+	// VNCKind is the constant used to define a Kind as a VNC session
+	VNCKind = "vnc"
+
+	// WebSocketKind is the constant used to define a Kind as a WebSocket frame
+	WebSocketKind = "websocket"
+
+	// TarpitKind is the constant used to define a Kind as a tarpit summary
+	TarpitKind = "tarpit"
+
+	// ResponderKind is the constant used to define a Kind as a responder session
+	ResponderKind = "responder"
+
+	// ListenModeCapture is the listen mode in which the events are generated from the captured packets
+	ListenModeCapture = "capture"
+
+	// ListenModeServerOnly is the listen mode in which the packet capture is disabled and the HTTP events are generated
+	// by the dummy HTTP server
+	ListenModeServerOnly = "server-only"
+
 	defaultConfig = `---
 logs.dir: "logs/"
 
@@ -55,11 +101,19 @@ logs.tcp.payload.max_size: "10KB"
 logs.udp.payload.max_size: "10KB"
 logs.icmpv4.payload.max_size: "10KB"
 logs.icmpv6.payload.max_size: "10KB"
+logs.smtp.data.max_size: "10KB"
+logs.redis.data.max_size: "10KB"
+
+artifacts.enable: false
+artifacts.dir: "var/artifacts"
+artifacts.max_size: "10MB"
+artifacts.retention: "720h"
 
 rules.dir: "rules/rules-enabled"
 rules.match.protocols: ["all"]
 
 listen.interface: "lo"
+listen.mode: "capture"
 filters.bpf.file: "filter.bpf"
 
 filters.ipv4.proto: []
@@ -71,15 +125,124 @@ server.http.dir: "var/http/serve"
 server.http.response.missing_status_code: 200
 server.http.response.headers:
       Server: "Apache"
+server.http.persona: ""
+server.http.log_requests: false
+server.http.proxy_protocol: false
+server.http.vhosts: []
+server.http.tarpit: ""
 
 server.https.enable: true
 server.https.port: 10443
 server.https.dir: "var/https/serve"
 server.https.crt: "var/https/certs/cert.pem"
 server.https.key: "var/https/certs/key.pem"
+server.https.http2: false
+server.https.autocert.enable: true
+server.https.autocert.subject: "/C=AU/ST=Some-State/O=Internet Widgits Pty Ltd/CN=localhost"
+server.https.autocert.sans: ["localhost"]
+server.https.autocert.key_type: "rsa-2048"
+server.https.autocert.validity_days: 3650
+server.https.certificates: []
 server.https.response.missing_status_code: 200
 server.https.response.headers:
       Server: "Apache"
+server.https.persona: ""
+server.https.proxy_protocol: false
+server.https.vhosts: []
+server.https.tarpit: ""
+
+server.proxy_protocol.trusted_cidrs: ["127.0.0.1/32", "::1/128"]
+
+server.personas.dir: "var/personas"
+
+server.listeners: []
+
+server.ssh.enable: false
+server.ssh.address: ""
+server.ssh.port: 10022
+server.ssh.version: "SSH-2.0-OpenSSH_7.9p1 Debian-10+deb10u2"
+server.ssh.hostname: "debian"
+server.ssh.host_keys: ["var/ssh/ssh_host_rsa_key", "var/ssh/ssh_host_ed25519_key"]
+server.ssh.accept: []
+server.ssh.max_duration: "5m"
+
+server.telnet.enable: false
+server.telnet.address: ""
+server.telnet.ports: [10023]
+server.telnet.banner: ""
+server.telnet.login_prompt: "(none) login: "
+server.telnet.password_prompt: "Password: "
+server.telnet.motd: "\r\n\r\nBusyBox v1.20.2 (2016-05-13 10:20:29 CST) built-in shell (ash)\r\nEnter 'help' for a list of built-in commands.\r\n\r\n"
+server.telnet.prompt: "# "
+server.telnet.accept: []
+server.telnet.max_attempts: 3
+server.telnet.timeout: "30s"
+server.telnet.max_duration: "5m"
+server.telnet.max_size: "10KB"
+
+server.smtp.enable: false
+server.smtp.address: ""
+server.smtp.ports: [10025]
+server.smtp.hostname: "mail.example.com"
+server.smtp.banner: "ESMTP Postfix (Debian/GNU)"
+server.smtp.starttls: true
+server.smtp.max_size: "10MB"
+server.smtp.max_recipients: 100
+server.smtp.max_messages: 10
+server.smtp.timeout: "1m"
+server.smtp.max_duration: "10m"
+
+server.redis.enable: false
+server.redis.address: ""
+server.redis.ports: [16379]
+server.redis.version: "5.0.7"
+server.redis.max_size: "10MB"
+server.redis.max_commands: 1000
+server.redis.timeout: "1m"
+server.redis.max_duration: "10m"
+server.redis.replication.fetch: false
+server.redis.replication.timeout: "30s"
+
+server.vnc.enable: false
+server.vnc.address: ""
+server.vnc.ports: [15900]
+server.vnc.version: "RFB 003.008"
+server.vnc.security_types: [2]
+server.vnc.timeout: "30s"
+
+server.auth.password_storage: "clear"
+server.auth.ntlm.domain: "CORP"
+server.auth.ntlm.computer: "WEB01"
+server.auth.challenges: []
+server.auth.forms: []
+
+server.websocket.paths: []
+server.websocket.max_frames: 100
+server.websocket.max_payload_size: "10KB"
+server.websocket.timeout: "1m"
+server.websocket.max_duration: "10m"
+
+responders.enable: false
+responders.dir: "responders/responders-enabled"
+responders.address: ""
+responders.timeout: "30s"
+responders.max_size: "10KB"
+responders.udp.enable: false
+responders.udp.address: ""
+responders.udp.services:
+  dns: [53]
+  ntp: [123]
+  ssdp: [1900]
+  memcached: [11211]
+responders.udp.max_reply_size: 512
+responders.udp.rate_limit.per_source: 5
+responders.udp.rate_limit.global: 100
+
+server.tarpit.interval: "10s"
+server.tarpit.max_duration: "1h"
+server.tarpit.max_connections: 1000
+server.tarpit.tcp.address: ""
+server.tarpit.tcp.ports: []
 `
 )
 
@@ -97,6 +260,10 @@ var (
 		ICMPv6Kind,
 		HTTPKind,
 		HTTPSKind,
+		QUICKind,
+		SNMPKind,
+		SSHKind,
+		VNCKind,
 	}
 )
 
@@ -141,26 +308,160 @@ type Config struct {
 	BPF      string
 
 	Interface            string   `yaml:"listen.interface"`
+	ListenMode           string   `yaml:"listen.mode"`
 	MaxPOSTDataSizeRaw   string   `yaml:"logs.http.post.max_size"`
 	MaxTCPDataSizeRaw    string   `yaml:"logs.tcp.payload.max_size"`
 	MaxUDPDataSizeRaw    string   `yaml:"logs.udp.payload.max_size"`
 	MaxICMPv4DataSizeRaw string   `yaml:"logs.icmpv4.payload.max_size"`
 	MaxICMPv6DataSizeRaw string   `yaml:"logs.icmpv6.payload.max_size"`
+	MaxSMTPDataSizeRaw   string   `yaml:"logs.smtp.data.max_size"`
+	MaxRedisDataSizeRaw  string   `yaml:"logs.redis.data.max_size"`
 	MatchProtocols       []string `yaml:"rules.match.protocols"`
 
+	ArtifactsEnable       bool   `yaml:"artifacts.enable"`
+	ArtifactsDir          string `yaml:"artifacts.dir"`
+	ArtifactsMaxSizeRaw   string `yaml:"artifacts.max_size"`
+	ArtifactsRetentionRaw string `yaml:"artifacts.retention"`
+
 	ServerHTTPEnable                bool              `yaml:"server.http.enable"`
 	ServerHTTPPort                  int               `yaml:"server.http.port"`
 	ServerHTTPDir                   string            `yaml:"server.http.dir"`
 	ServerHTTPMissingResponseStatus int               `yaml:"server.http.response.missing_status_code"`
 	ServerHTTPHeaders               map[string]string `yaml:"server.http.response.headers"`
-
-	ServerHTTPSEnable                bool              `yaml:"server.https.enable"`
-	ServerHTTPSPort                  int               `yaml:"server.https.port"`
-	ServerHTTPSDir                   string            `yaml:"server.https.dir"`
-	ServerHTTPSMissingResponseStatus int               `yaml:"server.https.response.missing_status_code"`
-	ServerHTTPSCert                  string            `yaml:"server.https.crt"`
-	ServerHTTPSKey                   string            `yaml:"server.https.key"`
-	ServerHTTPSHeaders               map[string]string `yaml:"server.https.response.headers"`
+	ServerHTTPPersona               string            `yaml:"server.http.persona"`
+	ServerHTTPLogRequests           bool              `yaml:"server.http.log_requests"`
+	ServerHTTPProxyProtocol         bool              `yaml:"server.http.proxy_protocol"`
+	ServerHTTPVHosts                []VHost           `yaml:"server.http.vhosts"`
+	ServerHTTPTarpit                string            `yaml:"server.http.tarpit"`
+
+	ServerHTTPSEnable                bool               `yaml:"server.https.enable"`
+	ServerHTTPSPort                  int                `yaml:"server.https.port"`
+	ServerHTTPSDir                   string             `yaml:"server.https.dir"`
+	ServerHTTPSMissingResponseStatus int                `yaml:"server.https.response.missing_status_code"`
+	ServerHTTPSCert                  string             `yaml:"server.https.crt"`
+	ServerHTTPSKey                   string             `yaml:"server.https.key"`
+	ServerHTTPSEnableHTTP2           bool               `yaml:"server.https.http2"`
+	ServerHTTPSAutocertEnable        bool               `yaml:"server.https.autocert.enable"`
+	ServerHTTPSAutocertSubject       string             `yaml:"server.https.autocert.subject"`
+	ServerHTTPSAutocertSANs          []string           `yaml:"server.https.autocert.sans"`
+	ServerHTTPSAutocertKeyType       string             `yaml:"server.https.autocert.key_type"`
+	ServerHTTPSAutocertValidityDays  int                `yaml:"server.https.autocert.validity_days"`
+	ServerHTTPSCertificates          []HTTPSCertificate `yaml:"server.https.certificates"`
+	ServerHTTPSHeaders               map[string]string  `yaml:"server.https.response.headers"`
+	ServerHTTPSPersona               string             `yaml:"server.https.persona"`
+	ServerHTTPSProxyProtocol         bool               `yaml:"server.https.proxy_protocol"`
+	ServerHTTPSVHosts                []VHost            `yaml:"server.https.vhosts"`
+	ServerHTTPSTarpit                string             `yaml:"server.https.tarpit"`
+
+	ServerProxyProtocolTrustedCIDRsRaw []string `yaml:"server.proxy_protocol.trusted_cidrs"`
+	ServerProxyProtocolTrustedCIDRs    []*net.IPNet
+
+	ServerPersonasDir string `yaml:"server.personas.dir"`
+
+	ServerListeners []Listener `yaml:"server.listeners"`
+
+	ServerSSHEnable         bool     `yaml:"server.ssh.enable"`
+	ServerSSHAddress        string   `yaml:"server.ssh.address"`
+	ServerSSHPort           int      `yaml:"server.ssh.port"`
+	ServerSSHVersion        string   `yaml:"server.ssh.version"`
+	ServerSSHHostname       string   `yaml:"server.ssh.hostname"`
+	ServerSSHHostKeys       []string `yaml:"server.ssh.host_keys"`
+	ServerSSHAccept         []string `yaml:"server.ssh.accept"`
+	ServerSSHMaxDurationRaw string   `yaml:"server.ssh.max_duration"`
+	ServerSSHMaxDuration    time.Duration
+
+	ServerTelnetEnable         bool     `yaml:"server.telnet.enable"`
+	ServerTelnetAddress        string   `yaml:"server.telnet.address"`
+	ServerTelnetPorts          []int    `yaml:"server.telnet.ports"`
+	ServerTelnetBanner         string   `yaml:"server.telnet.banner"`
+	ServerTelnetLoginPrompt    string   `yaml:"server.telnet.login_prompt"`
+	ServerTelnetPasswordPrompt string   `yaml:"server.telnet.password_prompt"`
+	ServerTelnetMotd           string   `yaml:"server.telnet.motd"`
+	ServerTelnetPrompt         string   `yaml:"server.telnet.prompt"`
+	ServerTelnetAccept         []string `yaml:"server.telnet.accept"`
+	ServerTelnetMaxAttempts    int      `yaml:"server.telnet.max_attempts"`
+	ServerTelnetTimeoutRaw     string   `yaml:"server.telnet.timeout"`
+	ServerTelnetTimeout        time.Duration
+	ServerTelnetMaxDurationRaw string `yaml:"server.telnet.max_duration"`
+	ServerTelnetMaxDuration    time.Duration
+	ServerTelnetMaxSizeRaw     string `yaml:"server.telnet.max_size"`
+	ServerTelnetMaxSize        uint64
+
+	ServerSMTPEnable         bool   `yaml:"server.smtp.enable"`
+	ServerSMTPAddress        string `yaml:"server.smtp.address"`
+	ServerSMTPPorts          []int  `yaml:"server.smtp.ports"`
+	ServerSMTPHostname       string `yaml:"server.smtp.hostname"`
+	ServerSMTPBanner         string `yaml:"server.smtp.banner"`
+	ServerSMTPStartTLS       bool   `yaml:"server.smtp.starttls"`
+	ServerSMTPMaxSizeRaw     string `yaml:"server.smtp.max_size"`
+	ServerSMTPMaxSize        uint64
+	ServerSMTPMaxRecipients  int    `yaml:"server.smtp.max_recipients"`
+	ServerSMTPMaxMessages    int    `yaml:"server.smtp.max_messages"`
+	ServerSMTPTimeoutRaw     string `yaml:"server.smtp.timeout"`
+	ServerSMTPTimeout        time.Duration
+	ServerSMTPMaxDurationRaw string `yaml:"server.smtp.max_duration"`
+	ServerSMTPMaxDuration    time.Duration
+
+	ServerRedisEnable                bool   `yaml:"server.redis.enable"`
+	ServerRedisAddress               string `yaml:"server.redis.address"`
+	ServerRedisPorts                 []int  `yaml:"server.redis.ports"`
+	ServerRedisVersion               string `yaml:"server.redis.version"`
+	ServerRedisMaxSizeRaw            string `yaml:"server.redis.max_size"`
+	ServerRedisMaxSize               uint64
+	ServerRedisMaxCommands           int    `yaml:"server.redis.max_commands"`
+	ServerRedisTimeoutRaw            string `yaml:"server.redis.timeout"`
+	ServerRedisTimeout               time.Duration
+	ServerRedisMaxDurationRaw        string `yaml:"server.redis.max_duration"`
+	ServerRedisMaxDuration           time.Duration
+	ServerRedisReplicationFetch      bool   `yaml:"server.redis.replication.fetch"`
+	ServerRedisReplicationTimeoutRaw string `yaml:"server.redis.replication.timeout"`
+	ServerRedisReplicationTimeout    time.Duration
+
+	ServerVNCEnable        bool   `yaml:"server.vnc.enable"`
+	ServerVNCAddress       string `yaml:"server.vnc.address"`
+	ServerVNCPorts         []int  `yaml:"server.vnc.ports"`
+	ServerVNCVersion       string `yaml:"server.vnc.version"`
+	ServerVNCSecurityTypes []int  `yaml:"server.vnc.security_types"`
+	ServerVNCTimeoutRaw    string `yaml:"server.vnc.timeout"`
+	ServerVNCTimeout       time.Duration
+
+	ServerAuthPasswordStorage string          `yaml:"server.auth.password_storage"`
+	ServerAuthNTLMDomain      string          `yaml:"server.auth.ntlm.domain"`
+	ServerAuthNTLMComputer    string          `yaml:"server.auth.ntlm.computer"`
+	ServerAuthChallenges      []AuthChallenge `yaml:"server.auth.challenges"`
+	ServerAuthForms           []AuthForm      `yaml:"server.auth.forms"`
+
+	ServerWebSocketPaths          []string `yaml:"server.websocket.paths"`
+	ServerWebSocketMaxFrames      int      `yaml:"server.websocket.max_frames"`
+	ServerWebSocketMaxPayloadRaw  string   `yaml:"server.websocket.max_payload_size"`
+	ServerWebSocketMaxPayload     uint64
+	ServerWebSocketTimeoutRaw     string `yaml:"server.websocket.timeout"`
+	ServerWebSocketTimeout        time.Duration
+	ServerWebSocketMaxDurationRaw string `yaml:"server.websocket.max_duration"`
+	ServerWebSocketMaxDuration    time.Duration
+
+	RespondersEnable     bool   `yaml:"responders.enable"`
+	RespondersDir        string `yaml:"responders.dir"`
+	RespondersAddress    string `yaml:"responders.address"`
+	RespondersTimeoutRaw string `yaml:"responders.timeout"`
+	RespondersMaxSizeRaw string `yaml:"responders.max_size"`
+	RespondersTimeout    time.Duration
+	RespondersMaxSize    uint64
+
+	RespondersUDPEnable             bool             `yaml:"responders.udp.enable"`
+	RespondersUDPAddress            string           `yaml:"responders.udp.address"`
+	RespondersUDPServices           map[string][]int `yaml:"responders.udp.services"`
+	RespondersUDPMaxReplySize       int              `yaml:"responders.udp.max_reply_size"`
+	RespondersUDPPerSourceRateLimit int              `yaml:"responders.udp.rate_limit.per_source"`
+	RespondersUDPGlobalRateLimit    int              `yaml:"responders.udp.rate_limit.global"`
+
+	ServerTarpitIntervalRaw    string `yaml:"server.tarpit.interval"`
+	ServerTarpitInterval       time.Duration
+	ServerTarpitMaxDurationRaw string `yaml:"server.tarpit.max_duration"`
+	ServerTarpitMaxDuration    time.Duration
+	ServerTarpitMaxConnections int    `yaml:"server.tarpit.max_connections"`
+	ServerTarpitTCPAddress     string `yaml:"server.tarpit.tcp.address"`
+	ServerTarpitTCPPorts       []int  `yaml:"server.tarpit.tcp.ports"`
 
 	RawDiscardProto4 []string `yaml:"filters.ipv4.proto"`
 	RawDiscardProto6 []string `yaml:"filters.ipv6.proto"`
@@ -173,7 +474,64 @@ type Config struct {
 	MaxUDPDataSize    uint64
 	MaxICMPv4DataSize uint64
 	MaxICMPv6DataSize uint64
+	MaxSMTPDataSize   uint64
+	MaxRedisDataSize  uint64
 	PcapFile          *os.File
+
+	ArtifactsMaxSize   uint64
+	ArtifactsRetention time.Duration
+}
+
+// HTTPSCertificate describes a certificate presented by the HTTPS server to the clients asking for one of its hosts
+type HTTPSCertificate struct {
+	Hosts []string `yaml:"hosts"`
+	Cert  string   `yaml:"crt"`
+	Key   string   `yaml:"key"`
+}
+
+// VHost describes a virtual host of a dummy server, answering the requests sent to one of its hosts with its own
+// content. The unset values are inherited from the server
+type VHost struct {
+	Name              string            `yaml:"name"`
+	Hosts             []string          `yaml:"hosts"`
+	Dir               string            `yaml:"dir"`
+	MissingStatusCode int               `yaml:"missing_status_code"`
+	Headers           map[string]string `yaml:"headers"`
+	Persona           string            `yaml:"persona"`
+}
+
+// Listener describes an additional dummy HTTP/S server. The unset values are inherited from the server.http or
+// server.https keys, according to the tls value
+type Listener struct {
+	Port              int               `yaml:"port"`
+	Address           string            `yaml:"address"`
+	TLS               bool              `yaml:"tls"`
+	Cert              string            `yaml:"crt"`
+	Key               string            `yaml:"key"`
+	HTTP2             bool              `yaml:"http2"`
+	Dir               string            `yaml:"dir"`
+	MissingStatusCode int               `yaml:"missing_status_code"`
+	Headers           map[string]string `yaml:"headers"`
+	Persona           string            `yaml:"persona"`
+	ProxyProtocol     bool              `yaml:"proxy_protocol"`
+	VHosts            []VHost           `yaml:"vhosts"`
+	Tarpit            string            `yaml:"tarpit"`
+}
+
+// AuthChallenge describes a path protected by an HTTP authentication scheme on the dummy servers
+type AuthChallenge struct {
+	Path   string `yaml:"path"`
+	Scheme string `yaml:"scheme"`
+	Realm  string `yaml:"realm"`
+	Accept bool   `yaml:"accept"`
+}
+
+// AuthForm describes a fake login form of the dummy servers
+type AuthForm struct {
+	Path           string   `yaml:"path"`
+	Page           string   `yaml:"page"`
+	UsernameFields []string `yaml:"username_fields"`
+	PasswordFields []string `yaml:"password_fields"`
 }
 
 // NewConfig creates a default Config struct
@@ -316,6 +674,16 @@ func (cfg *Config) ParseConfigAt(filepath *string) error {
 		//os.Exit(1)
 	}
 
+	cfg.ArtifactsMaxSize, err = rawDatasizeToBytes(cfg.ArtifactsMaxSizeRaw)
+	if err != nil {
+		return fmt.Errorf("failed to parse the artifacts.max_size value ('%s')", cfg.ArtifactsMaxSizeRaw)
+	}
+
+	cfg.ArtifactsRetention, err = time.ParseDuration(cfg.ArtifactsRetentionRaw)
+	if err != nil || cfg.ArtifactsRetention < 0 {
+		return fmt.Errorf("failed to parse the artifacts.retention value ('%s')", cfg.ArtifactsRetentionRaw)
+	}
+
 	cfg.MaxPOSTDataSize, err = rawDatasizeToBytes(cfg.MaxPOSTDataSizeRaw)
 	if err != nil {
 		return fmt.Errorf("failed to parse the logs.http.post.max_size value ('%s')", cfg.MaxPOSTDataSizeRaw)
@@ -344,6 +712,16 @@ func (cfg *Config) ParseConfigAt(filepath *string) error {
 		//os.Exit(1)
 	}
 
+	cfg.MaxSMTPDataSize, err = rawDatasizeToBytes(cfg.MaxSMTPDataSizeRaw)
+	if err != nil {
+		return fmt.Errorf("failed to parse the logs.smtp.data.max_size value ('%s')", cfg.MaxSMTPDataSizeRaw)
+	}
+
+	cfg.MaxRedisDataSize, err = rawDatasizeToBytes(cfg.MaxRedisDataSizeRaw)
+	if err != nil {
+		return fmt.Errorf("failed to parse the logs.redis.data.max_size value ('%s')", cfg.MaxRedisDataSizeRaw)
+	}
+
 	cfg.MaxICMPv6DataSize, err = rawDatasizeToBytes(cfg.MaxICMPv6DataSizeRaw)
 	if err != nil {
 		return fmt.Errorf("failed to parse the logs.icmpv6.post.max_size value ('%s')", cfg.MaxICMPv6DataSizeRaw)
@@ -378,6 +756,10 @@ func (cfg *Config) ParseConfigAt(filepath *string) error {
 		cfg.DiscardProto6[proto] = struct{}{}
 	}
 
+	if cfg.ListenMode != ListenModeCapture && cfg.ListenMode != ListenModeServerOnly {
+		return fmt.Errorf("failed to parse the listen.mode value : '%s' is not one of %s, %s", cfg.ListenMode, ListenModeCapture, ListenModeServerOnly)
+	}
+
 	if http.StatusText(cfg.ServerHTTPMissingResponseStatus) == "" {
 		return fmt.Errorf("failed to parse the server.http.response.missing_status_code value : '%d' is not a valid HTTP code status", cfg.ServerHTTPMissingResponseStatus)
 		//os.Exit(1)
@@ -388,9 +770,545 @@ func (cfg *Config) ParseConfigAt(filepath *string) error {
 		//os.Exit(1)
 	}
 
+	if cfg.ServerHTTPSAutocertValidityDays <= 0 {
+		return fmt.Errorf("failed to parse the server.https.autocert.validity_days value : '%d' is not a positive number of days", cfg.ServerHTTPSAutocertValidityDays)
+	}
+
+	validKeyType := false
+	for _, keyType := range certs.KeyTypes {
+		if cfg.ServerHTTPSAutocertKeyType == keyType {
+			validKeyType = true
+			break
+		}
+	}
+
+	if !validKeyType {
+		return fmt.Errorf("failed to parse the server.https.autocert.key_type value : '%s' is not one of %s", cfg.ServerHTTPSAutocertKeyType, strings.Join(certs.KeyTypes, ", "))
+	}
+
+	for idx, cert := range cfg.ServerHTTPSCertificates {
+		if len(cert.Hosts) == 0 || cert.Cert == "" || cert.Key == "" {
+			return fmt.Errorf("failed to parse the server.https.certificates value : entry %d needs the 'hosts', 'crt' and 'key' keys", idx)
+		}
+	}
+
+	if err := parseVHosts("server.http.vhosts", cfg.ServerHTTPVHosts); err != nil {
+		return err
+	}
+
+	if err := parseVHosts("server.https.vhosts", cfg.ServerHTTPSVHosts); err != nil {
+		return err
+	}
+
+	for idx, listener := range cfg.ServerListeners {
+		if listener.Port <= 0 || listener.Port > 65535 {
+			return fmt.Errorf("failed to parse the server.listeners value : entry %d needs a valid 'port' key", idx)
+		}
+
+		if listener.Address != "" && net.ParseIP(listener.Address) == nil {
+			return fmt.Errorf("failed to parse the server.listeners value : '%s' is not a valid IP address", listener.Address)
+		}
+
+		if (listener.Cert == "") != (listener.Key == "") {
+			return fmt.Errorf("failed to parse the server.listeners value : entry %d needs both the 'crt' and 'key' keys", idx)
+		}
+
+		if listener.MissingStatusCode != 0 && http.StatusText(listener.MissingStatusCode) == "" {
+			return fmt.Errorf("failed to parse the server.listeners value : '%d' is not a valid HTTP code status", listener.MissingStatusCode)
+		}
+
+		if err := parseVHosts(fmt.Sprintf("server.listeners[%d].vhosts", idx), listener.VHosts); err != nil {
+			return err
+		}
+
+		if err := parseTarpitMode(fmt.Sprintf("server.listeners[%d].tarpit", idx), listener.Tarpit); err != nil {
+			return err
+		}
+	}
+
+	if err := parseTarpitMode("server.http.tarpit", cfg.ServerHTTPTarpit); err != nil {
+		return err
+	}
+
+	if err := parseTarpitMode("server.https.tarpit", cfg.ServerHTTPSTarpit); err != nil {
+		return err
+	}
+
+	if cfg.ServerSSHPort < 1 || cfg.ServerSSHPort > 65535 {
+		return fmt.Errorf("failed to parse the server.ssh.port value : %d is not a valid port", cfg.ServerSSHPort)
+	}
+
+	if !strings.HasPrefix(cfg.ServerSSHVersion, "SSH-2.0-") {
+		return fmt.Errorf("failed to parse the server.ssh.version value : '%s' does not start with 'SSH-2.0-'", cfg.ServerSSHVersion)
+	}
+
+	if cfg.ServerSSHEnable && len(cfg.ServerSSHHostKeys) == 0 {
+		return fmt.Errorf("failed to parse the server.ssh.host_keys value : at least one host key is needed")
+	}
+
+	for _, pair := range cfg.ServerSSHAccept {
+		if !strings.Contains(pair, ":") {
+			return fmt.Errorf("failed to parse the server.ssh.accept value : '%s' is not a 'username:password' pair", pair)
+		}
+	}
+
+	cfg.ServerSSHMaxDuration, err = time.ParseDuration(cfg.ServerSSHMaxDurationRaw)
+	if err != nil || cfg.ServerSSHMaxDuration < 0 {
+		return fmt.Errorf("failed to parse the server.ssh.max_duration value ('%s')", cfg.ServerSSHMaxDurationRaw)
+	}
+
+	if cfg.ServerTelnetAddress != "" && net.ParseIP(cfg.ServerTelnetAddress) == nil {
+		return fmt.Errorf("failed to parse the server.telnet.address value : '%s' is not a valid IP address", cfg.ServerTelnetAddress)
+	}
+
+	for _, port := range cfg.ServerTelnetPorts {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("failed to parse the server.telnet.ports value : '%d' is not a valid port", port)
+		}
+	}
+
+	for _, pair := range cfg.ServerTelnetAccept {
+		if !strings.Contains(pair, ":") {
+			return fmt.Errorf("failed to parse the server.telnet.accept value : '%s' is not a 'username:password' pair", pair)
+		}
+	}
+
+	if cfg.ServerTelnetMaxAttempts < 1 {
+		return fmt.Errorf("failed to parse the server.telnet.max_attempts value : %d is not a positive number", cfg.ServerTelnetMaxAttempts)
+	}
+
+	cfg.ServerTelnetTimeout, err = time.ParseDuration(cfg.ServerTelnetTimeoutRaw)
+	if err != nil || cfg.ServerTelnetTimeout <= 0 {
+		return fmt.Errorf("failed to parse the server.telnet.timeout value ('%s')", cfg.ServerTelnetTimeoutRaw)
+	}
+
+	cfg.ServerTelnetMaxDuration, err = time.ParseDuration(cfg.ServerTelnetMaxDurationRaw)
+	if err != nil || cfg.ServerTelnetMaxDuration < 0 {
+		return fmt.Errorf("failed to parse the server.telnet.max_duration value ('%s')", cfg.ServerTelnetMaxDurationRaw)
+	}
+
+	cfg.ServerTelnetMaxSize, err = rawDatasizeToBytes(cfg.ServerTelnetMaxSizeRaw)
+	if err != nil {
+		return fmt.Errorf("failed to parse the server.telnet.max_size value ('%s')", cfg.ServerTelnetMaxSizeRaw)
+	}
+
+	if cfg.ServerSMTPAddress != "" && net.ParseIP(cfg.ServerSMTPAddress) == nil {
+		return fmt.Errorf("failed to parse the server.smtp.address value : '%s' is not a valid IP address", cfg.ServerSMTPAddress)
+	}
+
+	for _, port := range cfg.ServerSMTPPorts {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("failed to parse the server.smtp.ports value : '%d' is not a valid port", port)
+		}
+	}
+
+	cfg.ServerSMTPMaxSize, err = rawDatasizeToBytes(cfg.ServerSMTPMaxSizeRaw)
+	if err != nil || cfg.ServerSMTPMaxSize == 0 {
+		return fmt.Errorf("failed to parse the server.smtp.max_size value ('%s')", cfg.ServerSMTPMaxSizeRaw)
+	}
+
+	if cfg.ServerSMTPMaxRecipients < 1 {
+		return fmt.Errorf("failed to parse the server.smtp.max_recipients value : %d is not a positive number", cfg.ServerSMTPMaxRecipients)
+	}
+
+	if cfg.ServerSMTPMaxMessages < 1 {
+		return fmt.Errorf("failed to parse the server.smtp.max_messages value : %d is not a positive number", cfg.ServerSMTPMaxMessages)
+	}
+
+	cfg.ServerSMTPTimeout, err = time.ParseDuration(cfg.ServerSMTPTimeoutRaw)
+	if err != nil || cfg.ServerSMTPTimeout <= 0 {
+		return fmt.Errorf("failed to parse the server.smtp.timeout value ('%s')", cfg.ServerSMTPTimeoutRaw)
+	}
+
+	cfg.ServerSMTPMaxDuration, err = time.ParseDuration(cfg.ServerSMTPMaxDurationRaw)
+	if err != nil || cfg.ServerSMTPMaxDuration < 0 {
+		return fmt.Errorf("failed to parse the server.smtp.max_duration value ('%s')", cfg.ServerSMTPMaxDurationRaw)
+	}
+
+	if cfg.ServerRedisAddress != "" && net.ParseIP(cfg.ServerRedisAddress) == nil {
+		return fmt.Errorf("failed to parse the server.redis.address value : '%s' is not a valid IP address", cfg.ServerRedisAddress)
+	}
+
+	for _, port := range cfg.ServerRedisPorts {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("failed to parse the server.redis.ports value : '%d' is not a valid port", port)
+		}
+	}
+
+	cfg.ServerRedisMaxSize, err = rawDatasizeToBytes(cfg.ServerRedisMaxSizeRaw)
+	if err != nil || cfg.ServerRedisMaxSize == 0 {
+		return fmt.Errorf("failed to parse the server.redis.max_size value ('%s')", cfg.ServerRedisMaxSizeRaw)
+	}
+
+	if cfg.ServerRedisMaxCommands < 1 {
+		return fmt.Errorf("failed to parse the server.redis.max_commands value : %d is not a positive number", cfg.ServerRedisMaxCommands)
+	}
+
+	cfg.ServerRedisTimeout, err = time.ParseDuration(cfg.ServerRedisTimeoutRaw)
+	if err != nil || cfg.ServerRedisTimeout <= 0 {
+		return fmt.Errorf("failed to parse the server.redis.timeout value ('%s')", cfg.ServerRedisTimeoutRaw)
+	}
+
+	cfg.ServerRedisMaxDuration, err = time.ParseDuration(cfg.ServerRedisMaxDurationRaw)
+	if err != nil || cfg.ServerRedisMaxDuration < 0 {
+		return fmt.Errorf("failed to parse the server.redis.max_duration value ('%s')", cfg.ServerRedisMaxDurationRaw)
+	}
+
+	cfg.ServerRedisReplicationTimeout, err = time.ParseDuration(cfg.ServerRedisReplicationTimeoutRaw)
+	if err != nil || cfg.ServerRedisReplicationTimeout <= 0 {
+		return fmt.Errorf("failed to parse the server.redis.replication.timeout value ('%s')", cfg.ServerRedisReplicationTimeoutRaw)
+	}
+
+	if cfg.ServerVNCAddress != "" && net.ParseIP(cfg.ServerVNCAddress) == nil {
+		return fmt.Errorf("failed to parse the server.vnc.address value : '%s' is not a valid IP address", cfg.ServerVNCAddress)
+	}
+
+	for _, port := range cfg.ServerVNCPorts {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("failed to parse the server.vnc.ports value : '%d' is not a valid port", port)
+		}
+	}
+
+	if !vncd.ValidVersion(cfg.ServerVNCVersion) {
+		return fmt.Errorf("failed to parse the server.vnc.version value : '%s' is not in the 'RFB xxx.yyy' format", cfg.ServerVNCVersion)
+	}
+
+	if len(cfg.ServerVNCSecurityTypes) == 0 || len(cfg.ServerVNCSecurityTypes) > 255 {
+		return fmt.Errorf("failed to parse the server.vnc.security_types value : between 1 and 255 security types are needed")
+	}
+
+	for _, secType := range cfg.ServerVNCSecurityTypes {
+		if secType < 1 || secType > 255 {
+			return fmt.Errorf("failed to parse the server.vnc.security_types value : '%d' is not a valid security type", secType)
+		}
+	}
+
+	cfg.ServerVNCTimeout, err = time.ParseDuration(cfg.ServerVNCTimeoutRaw)
+	if err != nil || cfg.ServerVNCTimeout <= 0 {
+		return fmt.Errorf("failed to parse the server.vnc.timeout value ('%s')", cfg.ServerVNCTimeoutRaw)
+	}
+
+	if !contains(credentials.Storages, cfg.ServerAuthPasswordStorage) {
+		return fmt.Errorf("failed to parse the server.auth.password_storage value : '%s' is not one of %s", cfg.ServerAuthPasswordStorage, strings.Join(credentials.Storages, ", "))
+	}
+
+	for idx, challenge := range cfg.ServerAuthChallenges {
+		if !strings.HasPrefix(challenge.Path, "/") {
+			return fmt.Errorf("failed to parse the server.auth.challenges value : entry %d needs a 'path' key starting with '/'", idx)
+		}
+
+		if !contains(credentials.Schemes, challenge.Scheme) {
+			return fmt.Errorf("failed to parse the server.auth.challenges value : '%s' is not one of %s", challenge.Scheme, strings.Join(credentials.Schemes, ", "))
+		}
+	}
+
+	for idx, form := range cfg.ServerAuthForms {
+		if !strings.HasPrefix(form.Path, "/") {
+			return fmt.Errorf("failed to parse the server.auth.forms value : entry %d needs a 'path' key starting with '/'", idx)
+		}
+	}
+
+	for _, pattern := range cfg.ServerWebSocketPaths {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("failed to parse the server.websocket.paths value : '%s' is not a valid pattern starting with '/'", pattern)
+		}
+	}
+
+	if cfg.ServerWebSocketMaxFrames < 1 {
+		return fmt.Errorf("failed to parse the server.websocket.max_frames value : %d is not a positive number", cfg.ServerWebSocketMaxFrames)
+	}
+
+	cfg.ServerWebSocketMaxPayload, err = rawDatasizeToBytes(cfg.ServerWebSocketMaxPayloadRaw)
+	if err != nil {
+		return fmt.Errorf("failed to parse the server.websocket.max_payload_size value ('%s')", cfg.ServerWebSocketMaxPayloadRaw)
+	}
+
+	cfg.ServerWebSocketTimeout, err = time.ParseDuration(cfg.ServerWebSocketTimeoutRaw)
+	if err != nil || cfg.ServerWebSocketTimeout <= 0 {
+		return fmt.Errorf("failed to parse the server.websocket.timeout value ('%s')", cfg.ServerWebSocketTimeoutRaw)
+	}
+
+	cfg.ServerWebSocketMaxDuration, err = time.ParseDuration(cfg.ServerWebSocketMaxDurationRaw)
+	if err != nil || cfg.ServerWebSocketMaxDuration < 0 {
+		return fmt.Errorf("failed to parse the server.websocket.max_duration value ('%s')", cfg.ServerWebSocketMaxDurationRaw)
+	}
+
+	cfg.RespondersTimeout, err = time.ParseDuration(cfg.RespondersTimeoutRaw)
+	if err != nil || cfg.RespondersTimeout <= 0 {
+		return fmt.Errorf("failed to parse the responders.timeout value ('%s')", cfg.RespondersTimeoutRaw)
+	}
+
+	cfg.RespondersMaxSize, err = rawDatasizeToBytes(cfg.RespondersMaxSizeRaw)
+	if err != nil {
+		return fmt.Errorf("failed to parse the responders.max_size value ('%s')", cfg.RespondersMaxSizeRaw)
+	}
+
+	if cfg.RespondersUDPEnable && cfg.IsServerOnly() {
+		return fmt.Errorf("failed to parse the responders.udp.enable value : the UDP responders answer the captured packets and cannot be used with the %s listen mode", ListenModeServerOnly)
+	}
+
+	if cfg.RespondersUDPAddress != "" && net.ParseIP(cfg.RespondersUDPAddress) == nil {
+		return fmt.Errorf("failed to parse the responders.udp.address value : '%s' is not a valid IP address", cfg.RespondersUDPAddress)
+	}
+
+	for service, ports := range cfg.RespondersUDPServices {
+		if !contains(udpresponders.Services, service) {
+			return fmt.Errorf("failed to parse the responders.udp.services value : '%s' is not one of %s", service, strings.Join(udpresponders.Services, ", "))
+		}
+
+		for _, port := range ports {
+			if port <= 0 || port > 65535 {
+				return fmt.Errorf("failed to parse the responders.udp.services value : '%d' is not a valid port", port)
+			}
+		}
+	}
+
+	if cfg.RespondersUDPMaxReplySize < 1 {
+		return fmt.Errorf("failed to parse the responders.udp.max_reply_size value : %d is not a positive number", cfg.RespondersUDPMaxReplySize)
+	}
+
+	if cfg.RespondersUDPPerSourceRateLimit < 1 {
+		return fmt.Errorf("failed to parse the responders.udp.rate_limit.per_source value : %d is not a positive number", cfg.RespondersUDPPerSourceRateLimit)
+	}
+
+	if cfg.RespondersUDPGlobalRateLimit < 1 {
+		return fmt.Errorf("failed to parse the responders.udp.rate_limit.global value : %d is not a positive number", cfg.RespondersUDPGlobalRateLimit)
+	}
+
+	cfg.ServerTarpitInterval, err = time.ParseDuration(cfg.ServerTarpitIntervalRaw)
+	if err != nil || cfg.ServerTarpitInterval <= 0 {
+		return fmt.Errorf("failed to parse the server.tarpit.interval value : '%s' is not a positive duration", cfg.ServerTarpitIntervalRaw)
+	}
+
+	cfg.ServerTarpitMaxDuration, err = time.ParseDuration(cfg.ServerTarpitMaxDurationRaw)
+	if err != nil || cfg.ServerTarpitMaxDuration < 0 {
+		return fmt.Errorf("failed to parse the server.tarpit.max_duration value : '%s' is not a valid duration", cfg.ServerTarpitMaxDurationRaw)
+	}
+
+	if cfg.ServerTarpitTCPAddress != "" && net.ParseIP(cfg.ServerTarpitTCPAddress) == nil {
+		return fmt.Errorf("failed to parse the server.tarpit.tcp.address value : '%s' is not a valid IP address", cfg.ServerTarpitTCPAddress)
+	}
+
+	for _, port := range cfg.ServerTarpitTCPPorts {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("failed to parse the server.tarpit.tcp.ports value : '%d' is not a valid port", port)
+		}
+	}
+
+	cfg.ServerProxyProtocolTrustedCIDRs = nil
+	for _, rawCIDR := range cfg.ServerProxyProtocolTrustedCIDRsRaw {
+		_, network, err := net.ParseCIDR(rawCIDR)
+		if err != nil {
+			return fmt.Errorf("failed to parse the server.proxy_protocol.trusted_cidrs value : '%s' is not a valid CIDR", rawCIDR)
+		}
+		cfg.ServerProxyProtocolTrustedCIDRs = append(cfg.ServerProxyProtocolTrustedCIDRs, network)
+	}
+
 	return nil
 }
 
+// parseVHosts checks the virtual hosts of a dummy server and normalizes their hosts. The name of a virtual host
+// defaults to its first host
+func parseVHosts(key string, vhosts []VHost) error {
+	names := make(map[string]bool)
+
+	for idx := range vhosts {
+		vhost := &vhosts[idx]
+		if len(vhost.Hosts) == 0 || vhost.Dir == "" {
+			return fmt.Errorf("failed to parse the %s value : entry %d needs the 'hosts' and 'dir' keys", key, idx)
+		}
+
+		if vhost.MissingStatusCode != 0 && http.StatusText(vhost.MissingStatusCode) == "" {
+			return fmt.Errorf("failed to parse the %s value : '%d' is not a valid HTTP code status", key, vhost.MissingStatusCode)
+		}
+
+		for hostIdx, host := range vhost.Hosts {
+			vhost.Hosts[hostIdx] = strings.TrimSuffix(strings.ToLower(host), ".")
+		}
+
+		if vhost.Name == "" {
+			vhost.Name = vhost.Hosts[0]
+		}
+
+		if names[vhost.Name] {
+			return fmt.Errorf("failed to parse the %s value : duplicate virtual host name '%s'", key, vhost.Name)
+		}
+		names[vhost.Name] = true
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, val := range values {
+		if val == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTarpitMode checks that the tarpit mode of a dummy server is empty or one of tarpit.HTTPModes
+func parseTarpitMode(key string, mode string) error {
+	if mode == "" || contains(tarpit.HTTPModes, mode) {
+		return nil
+	}
+
+	return fmt.Errorf("failed to parse the %s value : '%s' is not one of %s", key, mode, strings.Join(tarpit.HTTPModes, ", "))
+}
+
+// MatchVHost returns the first virtual host matching the given Host header, or nil if none does
+func MatchVHost(vhosts []VHost, rawHost string) *VHost {
+	if len(vhosts) == 0 {
+		return nil
+	}
+
+	host := rawHost
+	if h, _, err := net.SplitHostPort(rawHost); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+
+	for idx := range vhosts {
+		for _, pattern := range vhosts[idx].Hosts {
+			if certs.MatchHost(pattern, host) {
+				return &vhosts[idx]
+			}
+		}
+	}
+
+	return nil
+}
+
+// HTTPListener returns the listener of the main dummy HTTP server
+func (cfg *Config) HTTPListener() Listener {
+	return Listener{
+		Port:              cfg.ServerHTTPPort,
+		Dir:               cfg.ServerHTTPDir,
+		MissingStatusCode: cfg.ServerHTTPMissingResponseStatus,
+		Headers:           cfg.ServerHTTPHeaders,
+		Persona:           cfg.ServerHTTPPersona,
+		ProxyProtocol:     cfg.ServerHTTPProxyProtocol,
+		VHosts:            cfg.ServerHTTPVHosts,
+		Tarpit:            cfg.ServerHTTPTarpit,
+	}
+}
+
+// HTTPSListener returns the listener of the main dummy HTTPS server
+func (cfg *Config) HTTPSListener() Listener {
+	return Listener{
+		Port:              cfg.ServerHTTPSPort,
+		TLS:               true,
+		Cert:              cfg.ServerHTTPSCert,
+		Key:               cfg.ServerHTTPSKey,
+		HTTP2:             cfg.ServerHTTPSEnableHTTP2,
+		Dir:               cfg.ServerHTTPSDir,
+		MissingStatusCode: cfg.ServerHTTPSMissingResponseStatus,
+		Headers:           cfg.ServerHTTPSHeaders,
+		Persona:           cfg.ServerHTTPSPersona,
+		ProxyProtocol:     cfg.ServerHTTPSProxyProtocol,
+		VHosts:            cfg.ServerHTTPSVHosts,
+		Tarpit:            cfg.ServerHTTPSTarpit,
+	}
+}
+
+// Listeners returns the additional listeners of server.listeners, with the unset values inherited from the main
+// dummy HTTP or HTTPS server
+func (cfg *Config) Listeners() []Listener {
+	var listeners []Listener
+
+	for _, listener := range cfg.ServerListeners {
+		server := cfg.HTTPListener()
+		if listener.TLS {
+			server = cfg.HTTPSListener()
+		}
+
+		if listener.Cert == "" {
+			listener.Cert, listener.Key = server.Cert, server.Key
+		}
+
+		if listener.Dir == "" {
+			listener.Dir = server.Dir
+		}
+
+		if listener.MissingStatusCode == 0 {
+			listener.MissingStatusCode = server.MissingStatusCode
+		}
+
+		if listener.Headers == nil {
+			listener.Headers = server.Headers
+		}
+
+		if listener.Persona == "" {
+			listener.Persona = server.Persona
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners
+}
+
+// VHostsFor returns the virtual hosts of the dummy server receiving the requests of the given kind on the given port
+func (cfg *Config) VHostsFor(kind string, port uint16) []VHost {
+	for _, listener := range cfg.ServerListeners {
+		if listener.Port == int(port) && listener.TLS == (kind == HTTPSKind) {
+			return listener.VHosts
+		}
+	}
+
+	if kind == HTTPSKind {
+		return cfg.ServerHTTPSVHosts
+	}
+
+	return cfg.ServerHTTPVHosts
+}
+
+// MatchAuthChallenge returns the first protected path the given path belongs to, or nil if none does
+func (cfg *Config) MatchAuthChallenge(path string) *AuthChallenge {
+	for idx := range cfg.ServerAuthChallenges {
+		if strings.HasPrefix(path, cfg.ServerAuthChallenges[idx].Path) {
+			return &cfg.ServerAuthChallenges[idx]
+		}
+	}
+
+	return nil
+}
+
+// MatchAuthForm returns the fake login form served at the given path, or nil if none is
+func (cfg *Config) MatchAuthForm(path string) *AuthForm {
+	for idx := range cfg.ServerAuthForms {
+		if path == cfg.ServerAuthForms[idx].Path {
+			return &cfg.ServerAuthForms[idx]
+		}
+	}
+
+	return nil
+}
+
+// MatchWebSocketPath checks if the given path accepts the WebSocket upgrades
+func (cfg *Config) MatchWebSocketPath(p string) bool {
+	for _, pattern := range cfg.ServerWebSocketPaths {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsServerOnly checks if the packet capture is disabled, in which case the HTTP events are generated by the dummy HTTP
+// server
+func (cfg *Config) IsServerOnly() bool {
+	return cfg.ListenMode == ListenModeServerOnly
+}
+
+// LogHTTPRequests checks if the dummy HTTP server has to generate events for the requests it receives
+func (cfg *Config) LogHTTPRequests() bool {
+	return cfg.ServerHTTPLogRequests || cfg.IsServerOnly()
+}
+
 func (cfg *Config) loadCLIConfigEnv() {
 	if *Cli.HomeDirPath != "" {
 		cfg.HomeDirPath = *Cli.HomeDirPath
@@ -0,0 +1,195 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+)
+
+const (
+	// SchemeBasic is the scheme of the credentials sent using the Basic HTTP authentication
+	SchemeBasic = "basic"
+
+	// SchemeDigest is the scheme of the credentials sent using the Digest HTTP authentication
+	SchemeDigest = "digest"
+
+	// SchemeNTLM is the scheme of the credentials sent using the NTLM HTTP authentication
+	SchemeNTLM = "ntlm"
+
+	// SchemeForm is the scheme of the credentials posted to a login form
+	SchemeForm = "form"
+
+	// StorageClear keeps the passwords in clear
+	StorageClear = "clear"
+
+	// StorageHashed replaces the passwords by their SHA-256 hash
+	StorageHashed = "hashed"
+
+	// StorageNone drops the passwords
+	StorageNone = "none"
+
+	// maxFormSize is the maximum size of the multipart forms kept in memory while looking for credentials
+	maxFormSize = 1 << 20
+)
+
+var (
+	// Schemes lists the HTTP authentication schemes that can be used to challenge the clients
+	Schemes = []string{SchemeBasic, SchemeDigest, SchemeNTLM}
+
+	// Storages lists the ways the captured passwords can be stored
+	Storages = []string{StorageClear, StorageHashed, StorageNone}
+
+	// DefaultUsernameFields lists the form fields looked up for a username if none are configured
+	DefaultUsernameFields = []string{"username", "user", "login", "email", "log", "uname", "user_name", "usr"}
+
+	// DefaultPasswordFields lists the form fields looked up for a password if none are configured
+	DefaultPasswordFields = []string{"password", "pass", "passwd", "pwd", "pw"}
+)
+
+// Credentials describes the credentials sent by a client. The Hash field holds the challenge response of the Digest
+// and NTLM schemes, in a format suitable for offline cracking
+type Credentials struct {
+	Scheme   string
+	Username string
+	Password string
+	Domain   string
+	Hash     string
+}
+
+// Protect applies the given storage to the password. The challenge response is dropped along with the password with
+// the "none" storage, as it can be cracked to recover it. It is kept with the "hashed" storage
+func (c *Credentials) Protect(storage string) {
+	switch storage {
+	case StorageHashed:
+		if c.Password != "" {
+			sum := sha256.Sum256([]byte(c.Password))
+			c.Password = "sha256:" + hex.EncodeToString(sum[:])
+		}
+	case StorageNone:
+		c.Password = ""
+		c.Hash = ""
+	}
+}
+
+// BasicChallenge returns the value of the WWW-Authenticate header asking for Basic credentials in the given realm
+func BasicChallenge(realm string) string {
+	return `Basic realm="` + quoteEscape(realm) + `"`
+}
+
+// FromAuthorization extracts the credentials from the value of the Authorization header of a request using the given
+// method. It returns nil if the header does not hold any, such as the first message of the NTLM handshake
+func FromAuthorization(header string, method string) *Credentials {
+	idx := strings.Index(header, " ")
+	if idx < 0 {
+		return nil
+	}
+
+	scheme, value := strings.ToLower(header[:idx]), strings.TrimSpace(header[idx+1:])
+
+	switch scheme {
+	case SchemeBasic:
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil
+		}
+
+		creds := &Credentials{Scheme: SchemeBasic, Username: string(raw)}
+		if sep := bytes.IndexByte(raw, ':'); sep >= 0 {
+			creds.Username, creds.Password = string(raw[:sep]), string(raw[sep+1:])
+		}
+
+		return creds
+	case SchemeDigest:
+		return parseDigest(value, method)
+	case SchemeNTLM, "negotiate":
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil
+		}
+
+		return parseNTLMAuthenticate(raw)
+	}
+
+	return nil
+}
+
+// FromForm extracts the credentials from the body of a form posted with the given content type. The fields are
+// matched case-insensitively, and the default ones are used if none are given. It returns nil if neither a username
+// nor a password has been found
+func FromForm(contentType string, body []byte, usernameFields []string, passwordFields []string) *Credentials {
+	if len(usernameFields) == 0 {
+		usernameFields = DefaultUsernameFields
+	}
+
+	if len(passwordFields) == 0 {
+		passwordFields = DefaultPasswordFields
+	}
+
+	values := formValues(contentType, body)
+	if len(values) == 0 {
+		return nil
+	}
+
+	creds := &Credentials{
+		Scheme:   SchemeForm,
+		Username: lookupField(values, usernameFields),
+		Password: lookupField(values, passwordFields),
+	}
+
+	if creds.Username == "" && creds.Password == "" {
+		return nil
+	}
+
+	return creds
+}
+
+// formValues parses an urlencoded, multipart or flat JSON body
+func formValues(contentType string, body []byte) url.Values {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+
+	switch {
+	case mediaType == "multipart/form-data":
+		form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(maxFormSize)
+		if err != nil {
+			return nil
+		}
+		defer form.RemoveAll()
+
+		return form.Value
+	case strings.HasSuffix(mediaType, "json"):
+		var obj map[string]interface{}
+		if err := json.Unmarshal(body, &obj); err != nil {
+			return nil
+		}
+
+		values := make(url.Values)
+		for key, val := range obj {
+			if str, ok := val.(string); ok {
+				values.Set(key, str)
+			}
+		}
+
+		return values
+	default:
+		values, _ := url.ParseQuery(string(body))
+		return values
+	}
+}
+
+func lookupField(values url.Values, fields []string) string {
+	for _, field := range fields {
+		for key, vals := range values {
+			if strings.EqualFold(key, field) && len(vals) > 0 {
+				return vals[0]
+			}
+		}
+	}
+
+	return ""
+}
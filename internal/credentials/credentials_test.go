@@ -0,0 +1,133 @@
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestFromAuthorizationBasic(t *testing.T) {
+	creds := FromAuthorization("Basic "+base64.StdEncoding.EncodeToString([]byte("admin:pass:word")), "GET")
+	if creds == nil || creds.Scheme != SchemeBasic || creds.Username != "admin" || creds.Password != "pass:word" {
+		t.Fatalf("unexpected credentials %+v", creds)
+	}
+
+	for _, header := range []string{"", "Basic", "Basic !!!", "Bearer abc"} {
+		if creds := FromAuthorization(header, "GET"); creds != nil {
+			t.Errorf("%q : unexpected credentials %+v", header, creds)
+		}
+	}
+
+	if challenge := BasicChallenge(`Admin "zone"`); challenge != `Basic realm="Admin \"zone\""` {
+		t.Errorf("unexpected challenge %s", challenge)
+	}
+}
+
+func TestFromAuthorizationDigest(t *testing.T) {
+	header := `Digest username="admin", realm="Restricted", nonce="abc", uri="/admin", qop=auth, nc=00000001, ` +
+		`cnonce="def", response="0123456789abcdef", opaque="xyz"`
+
+	creds := FromAuthorization(header, "GET")
+	if creds == nil || creds.Scheme != SchemeDigest || creds.Username != "admin" || creds.Domain != "Restricted" {
+		t.Fatalf("unexpected credentials %+v", creds)
+	}
+
+	expected := "$response$0123456789abcdef$admin$Restricted$GET$/admin$abc$00000001$def$auth"
+	if creds.Hash != expected {
+		t.Errorf("unexpected hash %s, expected %s", creds.Hash, expected)
+	}
+
+	if !strings.HasPrefix(DigestChallenge(`a"b`), `Digest realm="a\"b", qop="auth", nonce="`) {
+		t.Errorf("unexpected challenge %s", DigestChallenge(`a"b`))
+	}
+}
+
+func TestFromAuthorizationNTLM(t *testing.T) {
+	domain, user := encodeUTF16("CORP"), encodeUTF16("bob")
+	nt := make([]byte, 40)
+	for idx := range nt {
+		nt[idx] = byte(idx)
+	}
+
+	msg := make([]byte, 64)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], ntlmAuthenticate)
+	binary.LittleEndian.PutUint32(msg[60:], ntlmFlagUnicode)
+
+	offset := len(msg)
+	for _, field := range []struct {
+		pos   int
+		value []byte
+	}{{20, nt}, {28, domain}, {36, user}} {
+		putSecurityBuffer(msg[field.pos:], len(field.value), offset)
+		offset += len(field.value)
+	}
+	msg = append(msg, nt...)
+	msg = append(msg, domain...)
+	msg = append(msg, user...)
+
+	creds := FromAuthorization("NTLM "+base64.StdEncoding.EncodeToString(msg), "GET")
+	if creds == nil || creds.Scheme != SchemeNTLM || creds.Username != "bob" || creds.Domain != "CORP" {
+		t.Fatalf("unexpected credentials %+v", creds)
+	}
+
+	expected := "bob::CORP:" + hex.EncodeToString(ServerChallenge) + ":" + hex.EncodeToString(nt[:16]) + ":" + hex.EncodeToString(nt[16:])
+	if creds.Hash != expected {
+		t.Errorf("unexpected hash %s, expected %s", creds.Hash, expected)
+	}
+
+	challenge := NTLMChallenge("CORP", "WEB01")
+	if ntlmMessageType(challenge) != ntlmChallenge || string(challenge[24:32]) != string(ServerChallenge) {
+		t.Errorf("unexpected challenge %x", challenge)
+	}
+}
+
+func TestFromForm(t *testing.T) {
+	for _, test := range []struct {
+		contentType string
+		body        string
+	}{
+		{"application/x-www-form-urlencoded", "Username=admin&Password=secret&submit=1"},
+		{"application/json", `{"username": "admin", "password": "secret", "remember": true}`},
+		{"multipart/form-data; boundary=xx", "--xx\r\nContent-Disposition: form-data; name=\"username\"\r\n\r\nadmin\r\n" +
+			"--xx\r\nContent-Disposition: form-data; name=\"password\"\r\n\r\nsecret\r\n--xx--\r\n"},
+	} {
+		creds := FromForm(test.contentType, []byte(test.body), nil, nil)
+		if creds == nil || creds.Scheme != SchemeForm || creds.Username != "admin" || creds.Password != "secret" {
+			t.Errorf("%s : unexpected credentials %+v", test.contentType, creds)
+		}
+	}
+
+	creds := FromForm("application/x-www-form-urlencoded", []byte("id=admin&secret=pw"), []string{"id"}, []string{"secret"})
+	if creds == nil || creds.Username != "admin" || creds.Password != "pw" {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+
+	if creds := FromForm("application/x-www-form-urlencoded", []byte("q=search"), nil, nil); creds != nil {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+}
+
+func TestProtect(t *testing.T) {
+	for storage, expected := range map[string]string{
+		StorageClear:  "secret",
+		StorageHashed: "sha256:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b",
+		StorageNone:   "",
+	} {
+		creds := &Credentials{Password: "secret"}
+		creds.Protect(storage)
+
+		if creds.Password != expected {
+			t.Errorf("%s : unexpected password %q", storage, creds.Password)
+		}
+
+		creds = &Credentials{Hash: "user::DOMAIN:challenge:response"}
+		creds.Protect(storage)
+
+		if (creds.Hash == "") != (storage == StorageNone) || creds.Password != "" {
+			t.Errorf("%s : unexpected credentials %+v", storage, creds)
+		}
+	}
+}
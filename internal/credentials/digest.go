@@ -0,0 +1,88 @@
+package credentials
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// DigestChallenge returns the value of the WWW-Authenticate header asking for Digest credentials in the given realm
+func DigestChallenge(realm string) string {
+	return fmt.Sprintf(`Digest realm="%s", qop="auth", nonce="%s", opaque="%s", algorithm=MD5`,
+		quoteEscape(realm), randomHex(16), randomHex(16))
+}
+
+// parseDigest extracts the credentials from the parameters of a Digest Authorization header. The hash uses the
+// John the Ripper HDAA format
+func parseDigest(value string, method string) *Credentials {
+	params := parseParams(value)
+	if params["username"] == "" || params["response"] == "" {
+		return nil
+	}
+
+	return &Credentials{
+		Scheme:   SchemeDigest,
+		Username: params["username"],
+		Domain:   params["realm"],
+		Hash: strings.Join([]string{"", "response", params["response"], params["username"], params["realm"], method,
+			params["uri"], params["nonce"], params["nc"], params["cnonce"], params["qop"]}, "$"),
+	}
+}
+
+// parseParams parses a comma-separated list of key=value pairs, with optionally quoted values
+func parseParams(value string) map[string]string {
+	params := make(map[string]string)
+
+	for len(value) > 0 {
+		value = strings.TrimLeft(value, " ,")
+		eq := strings.Index(value, "=")
+		if eq < 0 {
+			break
+		}
+
+		key := strings.ToLower(strings.TrimSpace(value[:eq]))
+		value = strings.TrimLeft(value[eq+1:], " ")
+
+		var val string
+		if strings.HasPrefix(value, `"`) {
+			var sb strings.Builder
+			idx := 1
+			for ; idx < len(value) && value[idx] != '"'; idx++ {
+				if value[idx] == '\\' && idx+1 < len(value) {
+					idx++
+				}
+				sb.WriteByte(value[idx])
+			}
+
+			val = sb.String()
+			if idx < len(value) {
+				idx++
+			}
+			value = value[idx:]
+		} else {
+			end := strings.Index(value, ",")
+			if end < 0 {
+				end = len(value)
+			}
+
+			val = strings.TrimSpace(value[:end])
+			value = value[end:]
+		}
+
+		params[key] = val
+	}
+
+	return params
+}
+
+func quoteEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func randomHex(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
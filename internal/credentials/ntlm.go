@@ -0,0 +1,173 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	ntlmNegotiate    = 1
+	ntlmChallenge    = 2
+	ntlmAuthenticate = 3
+
+	ntlmFlagUnicode = 0x00000001
+
+	// ntlmChallengeFlags are the flags sent by Windows servers : unicode, request target, NTLM, always sign, domain
+	// target, extended session security, target info, version, 128 and 56 bits encryption
+	ntlmChallengeFlags = 0xa2898205
+
+	// filetimeEpochOffset is the offset between the Windows FILETIME epoch and the Unix one, in 100ns units
+	filetimeEpochOffset = 116444736000000000
+)
+
+var (
+	ntlmSignature = []byte("NTLMSSP\x00")
+
+	// ntlmVersion is the Windows version announced in the challenges (10.0 build 17763)
+	ntlmVersion = []byte{10, 0, 0x63, 0x45, 0, 0, 0, 15}
+
+	// ServerChallenge is the challenge sent to all the NTLM clients, so that their responses can be cracked offline
+	ServerChallenge = newServerChallenge()
+)
+
+func newServerChallenge() []byte {
+	challenge := make([]byte, 8)
+	_, _ = rand.Read(challenge)
+
+	return challenge
+}
+
+// IsNTLMNegotiate checks if the value of an Authorization header is the first message of the NTLM handshake
+func IsNTLMNegotiate(raw []byte) bool {
+	return ntlmMessageType(raw) == ntlmNegotiate
+}
+
+func ntlmMessageType(raw []byte) uint32 {
+	if len(raw) < 12 || !bytes.Equal(raw[:8], ntlmSignature) {
+		return 0
+	}
+
+	return binary.LittleEndian.Uint32(raw[8:12])
+}
+
+// NTLMChallenge builds the challenge message answering the NTLM negotiation, announcing a Windows server with the
+// given NetBIOS domain and computer names
+func NTLMChallenge(domain string, computer string) []byte {
+	const headerLen = 56
+
+	target := encodeUTF16(domain)
+
+	var info bytes.Buffer
+	writeAVPair(&info, 2, encodeUTF16(domain))
+	writeAVPair(&info, 1, encodeUTF16(computer))
+	writeAVPair(&info, 4, encodeUTF16(strings.ToLower(domain)+".local"))
+	writeAVPair(&info, 3, encodeUTF16(strings.ToLower(computer)+"."+strings.ToLower(domain)+".local"))
+
+	timestamp := make([]byte, 8)
+	binary.LittleEndian.PutUint64(timestamp, uint64(time.Now().UnixNano()/100+filetimeEpochOffset))
+	writeAVPair(&info, 7, timestamp)
+	writeAVPair(&info, 0, nil)
+
+	msg := make([]byte, headerLen)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], ntlmChallenge)
+	putSecurityBuffer(msg[12:], len(target), headerLen)
+	binary.LittleEndian.PutUint32(msg[20:], ntlmChallengeFlags)
+	copy(msg[24:], ServerChallenge)
+	putSecurityBuffer(msg[40:], info.Len(), headerLen+len(target))
+	copy(msg[48:], ntlmVersion)
+
+	msg = append(msg, target...)
+	return append(msg, info.Bytes()...)
+}
+
+func writeAVPair(buf *bytes.Buffer, id uint16, value []byte) {
+	_ = binary.Write(buf, binary.LittleEndian, id)
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(value)))
+	buf.Write(value)
+}
+
+func putSecurityBuffer(b []byte, length int, offset int) {
+	binary.LittleEndian.PutUint16(b, uint16(length))
+	binary.LittleEndian.PutUint16(b[2:], uint16(length))
+	binary.LittleEndian.PutUint32(b[4:], uint32(offset))
+}
+
+func securityBuffer(msg []byte, offset int) []byte {
+	if len(msg) < offset+8 {
+		return nil
+	}
+
+	length := int(binary.LittleEndian.Uint16(msg[offset:]))
+	start := int(binary.LittleEndian.Uint32(msg[offset+4:]))
+	if start+length > len(msg) {
+		return nil
+	}
+
+	return msg[start : start+length]
+}
+
+// parseNTLMAuthenticate extracts the credentials from the last message of the NTLM handshake. The hash uses the
+// NetNTLMv1 or NetNTLMv2 format of hashcat, computed with ServerChallenge
+func parseNTLMAuthenticate(raw []byte) *Credentials {
+	if ntlmMessageType(raw) != ntlmAuthenticate || len(raw) < 64 {
+		return nil
+	}
+
+	unicode := binary.LittleEndian.Uint32(raw[60:])&ntlmFlagUnicode != 0
+	decode := func(b []byte) string {
+		if unicode {
+			return decodeUTF16(b)
+		}
+		return string(b)
+	}
+
+	lm := securityBuffer(raw, 12)
+	nt := securityBuffer(raw, 20)
+	creds := &Credentials{
+		Scheme:   SchemeNTLM,
+		Domain:   decode(securityBuffer(raw, 28)),
+		Username: decode(securityBuffer(raw, 36)),
+	}
+
+	// Anonymous authentication
+	if creds.Username == "" {
+		return nil
+	}
+
+	challenge := hex.EncodeToString(ServerChallenge)
+	prefix := creds.Username + "::" + creds.Domain + ":"
+
+	switch {
+	case len(nt) > 24:
+		creds.Hash = prefix + challenge + ":" + hex.EncodeToString(nt[:16]) + ":" + hex.EncodeToString(nt[16:])
+	case len(nt) == 24:
+		creds.Hash = prefix + hex.EncodeToString(lm) + ":" + hex.EncodeToString(nt) + ":" + challenge
+	}
+
+	return creds
+}
+
+func encodeUTF16(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for idx, unit := range units {
+		binary.LittleEndian.PutUint16(b[2*idx:], unit)
+	}
+
+	return b
+}
+
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for idx := range units {
+		units[idx] = binary.LittleEndian.Uint16(b[2*idx:])
+	}
+
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,77 @@
+package dedup
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// Window is the time a request logged by the dummy HTTP server waits for its sniffed counterpart before being sent
+	Window = 2 * time.Second
+)
+
+var (
+	// SniffedHTTPRequests records the HTTP requests reassembled from the captured packets, so that the dummy HTTP server
+	// can drop its own events for the same requests when both are active
+	SniffedHTTPRequests = NewCache()
+)
+
+// Cache is a set of recently seen requests
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+// NewCache creates an empty Cache
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]time.Time)}
+}
+
+// HTTPKey identifies an HTTP request by its source and request line, which are the same whether it has been sniffed or
+// received by the dummy server
+func HTTPKey(srcIP string, srcPort uint16, verb string, requestURI string) string {
+	return fmt.Sprintf("%s:%d %s %s", srcIP, srcPort, verb, requestURI)
+}
+
+// Add records a request
+func (c *Cache) Add(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = time.Now()
+}
+
+// Seen checks if a request has been recorded, and forgets it
+func (c *Cache) Seen(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		return false
+	}
+
+	delete(c.entries, key)
+	return true
+}
+
+// FlushOlderThan forgets the requests recorded before the given deadline, such as the ones sent to ports the dummy
+// server does not listen on
+func (c *Cache) FlushOlderThan(deadline time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, seen := range c.entries {
+		if seen.Before(deadline) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// FlushAll forgets all the recorded requests
+func (c *Cache) FlushAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]time.Time)
+}
@@ -0,0 +1,30 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	c := NewCache()
+	key := HTTPKey("10.0.0.1", 4242, "GET", "/index.html")
+
+	if c.Seen(key) {
+		t.Fatal("unexpected unknown request seen")
+	}
+
+	c.Add(key)
+	if !c.Seen(key) {
+		t.Fatal("expected the request to be seen")
+	}
+
+	if c.Seen(key) {
+		t.Error("a request should only be seen once")
+	}
+
+	c.Add(key)
+	c.FlushOlderThan(time.Now().Add(time.Second))
+	if c.Seen(key) {
+		t.Error("expected the request to be flushed")
+	}
+}
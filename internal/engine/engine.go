@@ -1,11 +1,21 @@
 package engine
 
 import (
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/artifacts"
 	"github.com/bonjourmalware/melody/internal/config"
 	"github.com/bonjourmalware/melody/internal/events"
 	"github.com/bonjourmalware/melody/internal/logging"
+	"github.com/bonjourmalware/melody/internal/responders"
 	"github.com/bonjourmalware/melody/internal/router"
 	"github.com/bonjourmalware/melody/internal/rules"
+	"github.com/bonjourmalware/melody/internal/udpresponders"
+)
+
+const (
+	// artifactsPruneInterval is the time between two removals of the expired artifacts
+	artifactsPruneInterval = time.Hour
 )
 
 var (
@@ -19,13 +29,95 @@ func Start(quitErrChan chan error, shutdownChan chan bool, engineStoppedChan cha
 
 	if config.Cfg.ServerHTTPEnable {
 		logging.Std.Println("Starting HTTP server")
-		go router.StartHTTP(quitErrChan)
+		go router.StartHTTP(quitErrChan, EventChan)
 	}
 
 	if config.Cfg.ServerHTTPSEnable {
 		logging.Std.Println("Starting HTTPS server")
 		go router.StartHTTPS(quitErrChan, EventChan)
 	}
+
+	if config.Cfg.ServerSSHEnable {
+		logging.Std.Println("Starting SSH server")
+		go router.StartSSH(quitErrChan, EventChan)
+	}
+
+	if config.Cfg.ServerTelnetEnable {
+		for _, port := range config.Cfg.ServerTelnetPorts {
+			logging.Std.Println("Starting Telnet server on port", port)
+			go router.StartTelnet(quitErrChan, EventChan, port)
+		}
+	}
+
+	if config.Cfg.ServerSMTPEnable {
+		for _, port := range config.Cfg.ServerSMTPPorts {
+			logging.Std.Println("Starting SMTP server on port", port)
+			go router.StartSMTP(quitErrChan, EventChan, port)
+		}
+	}
+
+	if udpresponders.Default != nil {
+		for _, port := range udpresponders.Default.Ports() {
+			logging.Std.Println("Starting UDP responder on port", port)
+			go router.StartUDPResponder(quitErrChan, port)
+		}
+	}
+
+	if config.Cfg.ServerRedisEnable {
+		for _, port := range config.Cfg.ServerRedisPorts {
+			logging.Std.Println("Starting Redis server on port", port)
+			go router.StartRedis(quitErrChan, EventChan, port)
+		}
+	}
+
+	if config.Cfg.ServerVNCEnable {
+		for _, port := range config.Cfg.ServerVNCPorts {
+			logging.Std.Println("Starting VNC server on port", port)
+			go router.StartVNC(quitErrChan, EventChan, port)
+		}
+	}
+
+	for _, listener := range config.Cfg.Listeners() {
+		logging.Std.Println("Starting additional server on port", listener.Port)
+		go router.StartListener(quitErrChan, EventChan, listener)
+	}
+
+	if artifacts.Default != nil {
+		go pruneArtifacts(shutdownChan)
+	}
+
+	for _, responder := range responders.Loaded {
+		for _, port := range responder.Ports {
+			logging.Std.Printf("Starting responder '%s' on port %d\n", responder.Name, port)
+			go router.StartResponder(quitErrChan, EventChan, responder, port)
+		}
+	}
+
+	for _, port := range config.Cfg.ServerTarpitTCPPorts {
+		logging.Std.Println("Starting TCP tarpit on port", port)
+		go router.StartTCPTarpit(quitErrChan, EventChan, port)
+	}
+}
+
+// pruneArtifacts removes the expired artifacts from the store at startup, then periodically
+func pruneArtifacts(shutdownChan chan bool) {
+	ticker := time.NewTicker(artifactsPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := artifacts.Default.Prune()
+		if err != nil {
+			logging.Errors.Println("failed to prune the artifacts :", err)
+		} else if removed > 0 {
+			logging.Std.Printf("Removed %d expired artifacts\n", removed)
+		}
+
+		select {
+		case <-shutdownChan:
+			return
+		case <-ticker.C:
+		}
+	}
 }
 
 func startEventQualifier(quitErrChan chan error, shutdownChan chan bool, engineStoppedChan chan bool) {
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/bonjourmalware/melody/internal/artifacts"
+	"github.com/bonjourmalware/melody/internal/httpparser"
+	"github.com/c2h5oh/datasize"
+)
+
+// Upload describes a file uploaded with a multipart request
+type Upload struct {
+	Field       string
+	Filename    string
+	ContentType string
+	Artifact    *artifacts.Artifact
+}
+
+// storeArtifacts writes the full body of the request, as well as the files it uploads, to the artifacts store. It
+// returns the errors met instead of failing, so that the event is logged anyway
+func storeArtifacts(r *http.Request) (*artifacts.Artifact, []Upload, []string) {
+	if artifacts.Default == nil {
+		return nil, nil, nil
+	}
+
+	var errs []string
+	maxSize := artifacts.Default.MaxSize()
+
+	data, ok, err := httpparser.ReadBody(r, maxSize)
+	if err != nil {
+		return nil, nil, []string{err.Error()}
+	}
+
+	if !ok {
+		return nil, nil, []string{fmt.Sprintf("request body not stored (over %s)", datasize.ByteSize(maxSize).HumanReadable())}
+	}
+
+	if len(data) == 0 {
+		return nil, nil, nil
+	}
+
+	body, err := artifacts.Default.Save(data)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	uploads, err := storeUploads(r.Header.Get("Content-Type"), data)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	return body, uploads, errs
+}
+
+// storeUploads writes the files of a multipart body to the artifacts store
+func storeUploads(contentType string, data []byte) ([]Upload, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, nil
+	}
+
+	var uploads []Upload
+	reader := multipart.NewReader(bytes.NewReader(data), params["boundary"])
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return uploads, nil
+		} else if err != nil {
+			return uploads, fmt.Errorf("failed to parse the multipart body [%s]", err)
+		}
+
+		if part.FileName() == "" {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(part)
+		if err != nil {
+			return uploads, fmt.Errorf("failed to parse the multipart body [%s]", err)
+		}
+
+		artifact, err := artifacts.Default.Save(content)
+		if err != nil {
+			return uploads, err
+		}
+
+		uploads = append(uploads, Upload{
+			Field:       part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Artifact:    artifact,
+		})
+	}
+}
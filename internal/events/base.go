@@ -1,8 +1,13 @@
 package events
 
 import (
-	"github.com/bonjourmalware/melody/internal/loggable"
 	"time"
+
+	"github.com/bonjourmalware/melody/internal/loggable"
+	"github.com/bonjourmalware/melody/internal/protoid"
+	"github.com/bonjourmalware/melody/internal/sessions"
+
+	"github.com/google/gopacket"
 )
 
 // BaseEvent described the common structure to all the events generated by the received packets
@@ -13,6 +18,7 @@ type BaseEvent struct {
 	SourceIP   string
 	DestPort   uint16
 	Session    string
+	AppProto   string
 	Timestamp  time.Time
 	Additional map[string]string
 	Event
@@ -43,6 +49,11 @@ func (ev BaseEvent) GetSession() string {
 	return ev.Session
 }
 
+// GetAppProto fetches the application protocol identified for the event's flow
+func (ev BaseEvent) GetAppProto() string {
+	return ev.AppProto
+}
+
 // GetTags fetches the Tags of an event
 func (ev BaseEvent) GetTags() map[string][]string {
 	return ev.Tags
@@ -93,3 +104,22 @@ func (ev *BaseEvent) AddTags(tags map[string]string) {
 //
 //	return inlineTags
 //}
+
+// identifyAppProto returns the application protocol of the packet's flow. The first payload seen on a flow is used to
+// identify it, and the result is kept in the session for the next packets. The flow is keyed by both its addresses and
+// its ports, so that clients using the same ports don't share their protocol
+func identifyAppProto(packet gopacket.Packet, payload []byte, transport string) string {
+	flow := packet.NetworkLayer().NetworkFlow().String() + "/" + packet.TransportLayer().TransportFlow().String()
+	if appProto := sessions.SessionMap.GetAppProto(flow); appProto != "" {
+		return appProto
+	}
+
+	if len(payload) == 0 {
+		return ""
+	}
+
+	appProto := protoid.Identify(payload, transport)
+	sessions.SessionMap.SetAppProto(flow, appProto)
+
+	return appProto
+}
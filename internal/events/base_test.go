@@ -0,0 +1,62 @@
+package events
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func makeTCPPacket(t *testing.T, srcIP string, payload []byte) gopacket.Packet {
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.ParseIP(srcIP).To4(),
+		DstIP:    net.ParseIP("192.0.2.254").To4(),
+	}
+	tcp := &layers.TCP{SrcPort: 40000, DstPort: 8080, PSH: true, ACK: true}
+	if err := tcp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, ip, tcp, gopacket.Payload(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	return gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv4, gopacket.Default)
+}
+
+func TestIdentifyAppProtoByFlow(t *testing.T) {
+	first, err := NewTCPEvent(makeTCPPacket(t, "192.0.2.1", []byte("GET / HTTP/1.1\r\n\r\n")), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same ports, other client
+	second, err := NewTCPEvent(makeTCPPacket(t, "192.0.2.2", []byte("SSH-2.0-OpenSSH_8.2\r\n")), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.AppProto != "http" {
+		t.Error("first client FAILED : got", first.AppProto)
+	}
+
+	if second.AppProto != "ssh" {
+		t.Error("second client FAILED : got", second.AppProto)
+	}
+
+	// The protocol is kept for the next packets of the flow
+	next, err := NewTCPEvent(makeTCPPacket(t, "192.0.2.1", []byte("garbage")), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if next.AppProto != "http" {
+		t.Error("next packet FAILED : got", next.AppProto)
+	}
+}
@@ -15,6 +15,10 @@ type Event interface {
 	GetUDPHeader() *layers.UDP
 	GetTCPHeader() *layers.TCP
 	GetHTTPData() HTTPEvent
+	GetQUICData() QUICEvent
+	GetSNMPData() SNMPEvent
+	GetSSHData() SSHEvent
+	GetVNCData() VNCEvent
 
 	AddTags(tags map[string]string)
 	AddAdditional(add map[string]string)
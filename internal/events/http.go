@@ -1,12 +1,16 @@
 package events
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/bonjourmalware/melody/internal/artifacts"
+	"github.com/bonjourmalware/melody/internal/credentials"
 	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/proxyproto"
 
 	"github.com/rs/xid"
 
@@ -28,6 +32,7 @@ type HTTPEvent struct {
 	SourcePort    uint16            `json:"src_port"`
 	DestHost      string            `json:"dst_host"`
 	DestPort      uint16            `json:"dst_port"`
+	VHost         string            `json:"vhost"`
 	Headers       map[string]string `json:"headers"`
 	HeadersKeys   []string          `json:"headers_keys"`
 	HeadersValues []string          `json:"headers_values"`
@@ -35,6 +40,11 @@ type HTTPEvent struct {
 	Errors        []string        `json:"errors"`
 	Body          logdata.Payload `json:"body"`
 	IsTLS         bool            `json:"is_tls"`
+	TLS           *tls.ConnectionState
+	Proxy         *proxyproto.Addr
+	Credentials   []*credentials.Credentials
+	BodyArtifact  *artifacts.Artifact
+	Uploads       []Upload
 	Req           *http.Request
 	LogData       logdata.HTTPEventLog
 	BaseEvent
@@ -75,11 +85,49 @@ func (ev HTTPEvent) ToLog() EventLog {
 	ev.LogData.HTTP.RequestURI = ev.RequestURI
 	ev.LogData.HTTP.SourcePort = ev.SourcePort
 	ev.LogData.HTTP.DestHost = ev.DestHost
+	ev.LogData.HTTP.VHost = ev.VHost
 	ev.LogData.DestPort = ev.DestPort
 	ev.LogData.SourceIP = ev.SourceIP
 	ev.LogData.HTTP.Headers = ev.Headers
 	ev.LogData.HTTP.Body = ev.Body
+	if ev.BodyArtifact != nil {
+		ev.LogData.HTTP.Body = logdata.NewArtifactPayloadLogData(ev.BodyArtifact.SHA256, ev.BodyArtifact.Size)
+	}
+	for _, upload := range ev.Uploads {
+		ev.LogData.HTTP.Uploads = append(ev.LogData.HTTP.Uploads, logdata.UploadLogData{
+			Field:       upload.Field,
+			Filename:    upload.Filename,
+			ContentType: upload.ContentType,
+			SHA256:      upload.Artifact.SHA256,
+			Size:        upload.Artifact.Size,
+		})
+	}
 	ev.LogData.HTTP.IsTLS = ev.IsTLS
+	if ev.TLS != nil {
+		ev.LogData.HTTP.TLSVersion = httpparser.TLSVersionName(ev.TLS.Version)
+		ev.LogData.HTTP.TLSCipherSuite = httpparser.CipherSuiteName(ev.TLS.CipherSuite)
+		ev.LogData.HTTP.TLSServerName = ev.TLS.ServerName
+		ev.LogData.HTTP.TLSNegotiatedProtocol = ev.TLS.NegotiatedProtocol
+	}
+	if ev.Proxy != nil {
+		proxyIP, _, _ := net.SplitHostPort(ev.Proxy.Proxy.String())
+		ev.LogData.HTTP.Proxy = &logdata.ProxyLogData{
+			Version:  ev.Proxy.Header.Version,
+			SourceIP: proxyIP,
+			DestIP:   ev.Proxy.Header.Dest.IP.String(),
+			DestPort: uint16(ev.Proxy.Header.Dest.Port),
+			TLVs:     ev.Proxy.Header.TLVMap(),
+		}
+	}
+	for _, creds := range ev.Credentials {
+		ev.LogData.HTTP.Credentials = append(ev.LogData.HTTP.Credentials, logdata.CredentialsLogData{
+			Scheme:   creds.Scheme,
+			Username: creds.Username,
+			Password: creds.Password,
+			Domain:   creds.Domain,
+			Hash:     creds.Hash,
+		})
+	}
 	ev.LogData.Additional = ev.Additional
 
 	if val, ok := ev.Headers["User-Agent"]; ok {
@@ -123,6 +171,9 @@ func NewHTTPEvent(r *http.Request, network gopacket.Flow, transport gopacket.Flo
 		errs = append(errs, err.Error())
 	}
 
+	bodyArtifact, uploads, artifactsErrs := storeArtifacts(r)
+	errs = append(errs, artifactsErrs...)
+
 	ev := &HTTPEvent{
 		Verb:          r.Method,
 		Proto:         r.Proto,
@@ -132,7 +183,11 @@ func NewHTTPEvent(r *http.Request, network gopacket.Flow, transport gopacket.Flo
 		DestHost:      network.Dst().String(),
 		Body:          logdata.NewPayloadLogData(params, config.Cfg.MaxPOSTDataSize),
 		IsTLS:         r.TLS != nil,
+		TLS:           r.TLS,
 		Headers:       headers,
+		Credentials:   extractCredentials(r, params),
+		BodyArtifact:  bodyArtifact,
+		Uploads:       uploads,
 		InlineHeaders: inlineHeaders,
 		Errors:        errs,
 	}
@@ -148,12 +203,15 @@ func NewHTTPEvent(r *http.Request, network gopacket.Flow, transport gopacket.Flo
 	} else {
 		ev.Kind = config.HTTPKind
 	}
+	ev.AppProto = ev.Kind
+	ev.VHost = matchVHost(ev.Kind, ev.DestPort, r.Host)
 
 	return ev, nil
 }
 
 // NewHTTPEventFromRequest creates an HTTPEvent from an http.Request if flow information is not available. It is used
-// for HTTPS events, as they're generated from the dummy webserver and not reassembled by Melody
+// for HTTPS events, as they're generated from the dummy webserver and not reassembled by Melody. The source is the
+// client address sent by the trusted proxy the request went through, if any
 func NewHTTPEventFromRequest(r *http.Request) (*HTTPEvent, error) {
 	headers := make(map[string]string)
 	var inlineHeaders []string
@@ -161,7 +219,6 @@ func NewHTTPEventFromRequest(r *http.Request) (*HTTPEvent, error) {
 	var params []byte
 	var srcIP string
 	var dstHost string
-	var rawDstPort string
 	var rawSrcPort string
 	var err error
 
@@ -170,9 +227,11 @@ func NewHTTPEventFromRequest(r *http.Request) (*HTTPEvent, error) {
 		inlineHeaders = append(inlineHeaders, header+": "+r.Header.Get(header))
 	}
 
-	dstHost, rawDstPort, err = net.SplitHostPort(r.Host)
+	// The destination port is the one the request was received on, as the Host header is set by the client
+	dstHost, _, err = net.SplitHostPort(r.Host)
 	if err != nil {
-		errs = append(errs, err.Error())
+		// Clients omit the default port of the scheme
+		dstHost = r.Host
 	}
 
 	srcIP, rawSrcPort, err = net.SplitHostPort(r.RemoteAddr)
@@ -185,19 +244,26 @@ func NewHTTPEventFromRequest(r *http.Request) (*HTTPEvent, error) {
 		errs = append(errs, err.Error())
 	}
 
+	bodyArtifact, uploads, artifactsErrs := storeArtifacts(r)
+	errs = append(errs, artifactsErrs...)
+
 	srcPort, _ := strconv.ParseUint(rawSrcPort, 10, 16)
-	dstPort, _ := strconv.ParseUint(rawDstPort, 10, 16)
 
 	ev := &HTTPEvent{
 		Verb:          r.Method,
 		Proto:         r.Proto,
 		RequestURI:    r.URL.RequestURI(),
 		SourcePort:    uint16(srcPort),
-		DestPort:      uint16(dstPort),
+		DestPort:      localPort(r),
 		DestHost:      dstHost,
 		Body:          logdata.NewPayloadLogData(params, config.Cfg.MaxPOSTDataSize),
 		IsTLS:         r.TLS != nil,
+		TLS:           r.TLS,
+		Proxy:         proxyproto.ClientAddr(r),
 		Headers:       headers,
+		Credentials:   extractCredentials(r, params),
+		BodyArtifact:  bodyArtifact,
+		Uploads:       uploads,
 		InlineHeaders: inlineHeaders,
 		Errors:        errs,
 	}
@@ -213,6 +279,51 @@ func NewHTTPEventFromRequest(r *http.Request) (*HTTPEvent, error) {
 	} else {
 		ev.Kind = config.HTTPKind
 	}
+	ev.AppProto = ev.Kind
+	ev.VHost = matchVHost(ev.Kind, ev.DestPort, r.Host)
 
 	return ev, nil
 }
+
+// extractCredentials returns the credentials sent in the authorization headers of the request, and posted to the
+// fake login forms. The passwords are stored as configured
+func extractCredentials(r *http.Request, body []byte) []*credentials.Credentials {
+	var found []*credentials.Credentials
+
+	for _, header := range []string{"Authorization", "Proxy-Authorization"} {
+		if creds := credentials.FromAuthorization(r.Header.Get(header), r.Method); creds != nil {
+			found = append(found, creds)
+		}
+	}
+
+	if form := config.Cfg.MatchAuthForm(r.URL.Path); form != nil && r.Method == http.MethodPost {
+		if creds := credentials.FromForm(r.Header.Get("Content-Type"), body, form.UsernameFields, form.PasswordFields); creds != nil {
+			found = append(found, creds)
+		}
+	}
+
+	for _, creds := range found {
+		creds.Protect(config.Cfg.ServerAuthPasswordStorage)
+	}
+
+	return found
+}
+
+// matchVHost returns the name of the virtual host of the dummy server listening on the given port answering the
+// request, or an empty string if none does
+func matchVHost(kind string, port uint16, host string) string {
+	if vhost := config.MatchVHost(config.Cfg.VHostsFor(kind, port), host); vhost != nil {
+		return vhost.Name
+	}
+
+	return ""
+}
+
+// localPort returns the port of the dummy server that received the request
+func localPort(r *http.Request) uint16 {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(*net.TCPAddr); ok {
+		return uint16(addr.Port)
+	}
+
+	return 0
+}
@@ -0,0 +1,121 @@
+package events
+
+import (
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/events/helpers"
+	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/quicparser"
+
+	"github.com/bonjourmalware/melody/internal/config"
+
+	"github.com/bonjourmalware/melody/internal/sessions"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// QUICEvent describes the structure of an event generated by the ClientHello of a QUIC Initial packet
+type QUICEvent struct {
+	Version           uint32
+	DCID              string
+	SCID              string
+	SourcePort        uint16
+	SNI               string
+	ALPN              []string
+	SupportedVersions []uint16
+	CipherSuites      []uint16
+	LogData           logdata.QUICEventLog
+	BaseEvent
+	helpers.IPv4Layer
+	helpers.IPv6Layer
+}
+
+// NewQUICEvent creates a new QUICEvent from an UDP packet carrying a QUIC Initial packet. As a ClientHello can span
+// multiple Initial packets, it returns a nil event until the ClientHello has been fully received
+func NewQUICEvent(packet gopacket.Packet, IPVersion uint) (*QUICEvent, error) {
+	var ev = &QUICEvent{}
+	ev.Kind = config.QUICKind
+	ev.IPVersion = IPVersion
+
+	UDPHeader, _ := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+
+	initial, err := quicparser.ParseInitial(UDPHeader.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ev.Timestamp = packet.Metadata().Timestamp
+	ev.Session = sessions.SessionMap.GetUID(packet.TransportLayer().TransportFlow().String())
+	ev.AppProto = config.QUICKind
+
+	switch IPVersion {
+	case 4:
+		IPHeader, _ := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+		ev.IPv4Layer = helpers.IPv4Layer{Header: IPHeader}
+		ev.SourceIP = IPHeader.SrcIP.String()
+	case 6:
+		IPHeader, _ := packet.Layer(layers.LayerTypeIPv6).(*layers.IPv6)
+		ev.IPv6Layer = helpers.IPv6Layer{Header: IPHeader}
+		ev.SourceIP = IPHeader.SrcIP.String()
+	}
+
+	hello, err := quicparser.PendingHandshakes.Reassemble(packet.NetworkLayer().NetworkFlow().Src().String(), initial)
+	if err != nil {
+		return nil, err
+	}
+
+	if hello == nil {
+		return nil, nil
+	}
+
+	ev.DestPort = uint16(UDPHeader.DstPort)
+	ev.SourcePort = uint16(UDPHeader.SrcPort)
+	ev.Version = initial.Version
+	ev.DCID = initial.DCIDString()
+	ev.SCID = initial.SCIDString()
+	ev.SNI = hello.SNI
+	ev.ALPN = hello.ALPN
+	ev.SupportedVersions = hello.SupportedVersions
+	ev.CipherSuites = hello.CipherSuites
+
+	ev.Additional = make(map[string]string)
+	ev.Tags = make(Tags)
+
+	return ev, nil
+}
+
+// GetQUICData returns the event's data
+func (ev QUICEvent) GetQUICData() QUICEvent {
+	return ev
+}
+
+// ToLog parses the event structure and generate an EventLog almost ready to be sent to the logging file
+func (ev QUICEvent) ToLog() EventLog {
+	ev.LogData = logdata.QUICEventLog{}
+	ev.LogData.Timestamp = ev.Timestamp.Format(time.RFC3339Nano)
+	ev.LogData.Init(ev.BaseEvent)
+
+	switch ev.IPVersion {
+	case 4:
+		ev.LogData.IP = logdata.NewIPv4LogData(ev.IPv4Layer)
+	case 6:
+		ev.LogData.IP = logdata.NewIPv6LogData(ev.IPv6Layer)
+	}
+
+	ev.LogData.QUIC = logdata.QUICLogData{
+		Version:           ev.Version,
+		VersionName:       quicparser.VersionName(ev.Version),
+		DCID:              ev.DCID,
+		SCID:              ev.SCID,
+		SourcePort:        ev.SourcePort,
+		SNI:               ev.SNI,
+		ALPN:              ev.ALPN,
+		SupportedVersions: ev.SupportedVersions,
+		CipherSuites:      ev.CipherSuites,
+	}
+
+	ev.LogData.Additional = ev.Additional
+
+	return ev.LogData
+}
@@ -0,0 +1,140 @@
+package events
+
+import (
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/artifacts"
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/credentials"
+	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/redisd"
+
+	"github.com/google/gopacket/layers"
+	"github.com/rs/xid"
+)
+
+// RedisEvent describes the structure of the event generated at the end of a session of the Redis server. The
+// artifacts are listed in the same order as the writes and the replications, and are nil if they were not stored
+type RedisEvent struct {
+	Summary              redisd.Session
+	WriteArtifacts       []*artifacts.Artifact
+	ReplicationArtifacts []*artifacts.Artifact
+	Errors               []string
+	LogData              logdata.RedisEventLog
+	BaseEvent
+}
+
+// NewRedisEvent creates a RedisEvent from a session of the Redis server. The written values and the payloads sent by
+// the masters are written to the artifacts store if it is enabled
+func NewRedisEvent(session redisd.Session) *RedisEvent {
+	ev := &RedisEvent{
+		Summary: session,
+	}
+
+	for _, write := range session.Writes {
+		ev.WriteArtifacts = append(ev.WriteArtifacts, ev.save(write.Data))
+	}
+
+	for _, replication := range session.Replications {
+		ev.ReplicationArtifacts = append(ev.ReplicationArtifacts, ev.save(replication.Data))
+	}
+
+	// Cannot use promoted (inherited) fields in struct literal
+	ev.Kind = config.RedisKind
+	ev.AppProto = config.RedisKind
+	ev.SourceIP = session.SourceIP
+	ev.DestPort = session.DestPort
+	ev.Timestamp = time.Now()
+	ev.Session = xid.New().String()
+
+	ev.Tags = make(Tags)
+	ev.Additional = make(map[string]string)
+
+	return ev
+}
+
+// save writes data to the artifacts store if it is enabled and the data is not empty
+func (ev *RedisEvent) save(data []byte) *artifacts.Artifact {
+	if artifacts.Default == nil || len(data) == 0 {
+		return nil
+	}
+
+	artifact, err := artifacts.Default.Save(data)
+	if err != nil {
+		ev.Errors = append(ev.Errors, err.Error())
+		return nil
+	}
+
+	return artifact
+}
+
+// GetIPHeader satisfies the Event interface by returning nil, as the Redis events are not generated from a packet
+func (ev RedisEvent) GetIPHeader() *layers.IPv4 {
+	return nil
+}
+
+// ToLog parses the event structure and generate an EventLog almost ready to be sent to the logging file. The passwords
+// sent with AUTH are stored as configured
+func (ev RedisEvent) ToLog() EventLog {
+	ev.LogData = logdata.RedisEventLog{}
+	ev.LogData.Timestamp = ev.Timestamp.Format(time.RFC3339Nano)
+	ev.LogData.Init(ev.BaseEvent)
+
+	ev.LogData.Redis = logdata.RedisLogData{
+		SourcePort:   ev.Summary.SourcePort,
+		Start:        ev.Summary.Start.Format(time.RFC3339Nano),
+		Duration:     ev.Summary.Duration.Seconds(),
+		ClosedBy:     ev.Summary.ClosedBy,
+		Commands:     []logdata.RedisCommandLogData{},
+		Writes:       []logdata.RedisWriteLogData{},
+		Replications: []logdata.RedisReplicationLogData{},
+		Errors:       ev.Errors,
+	}
+
+	for _, cmd := range ev.Summary.Commands {
+		args := cmd.Args
+		// The password is the last argument, after the username since Redis 6
+		if cmd.Name == "auth" && len(args) > 0 {
+			args = append([]string(nil), args...)
+			creds := &credentials.Credentials{Password: args[len(args)-1]}
+			creds.Protect(config.Cfg.ServerAuthPasswordStorage)
+			args[len(args)-1] = creds.Password
+		}
+
+		ev.LogData.Redis.Commands = append(ev.LogData.Redis.Commands, logdata.RedisCommandLogData{
+			Offset:    cmd.Time.Sub(ev.Summary.Start).Seconds(),
+			Name:      cmd.Name,
+			Args:      args,
+			Truncated: cmd.Truncated,
+		})
+	}
+
+	for idx, write := range ev.Summary.Writes {
+		ev.LogData.Redis.Writes = append(ev.LogData.Redis.Writes, logdata.RedisWriteLogData{
+			Key:  write.Key,
+			Data: ev.payload(write.Data, ev.WriteArtifacts[idx]),
+		})
+	}
+
+	for idx, replication := range ev.Summary.Replications {
+		ev.LogData.Redis.Replications = append(ev.LogData.Redis.Replications, logdata.RedisReplicationLogData{
+			Host:  replication.Host,
+			Port:  replication.Port,
+			Error: replication.Error,
+			Data:  ev.payload(replication.Data, ev.ReplicationArtifacts[idx]),
+		})
+	}
+
+	ev.LogData.Additional = ev.Additional
+
+	return ev.LogData
+}
+
+// payload references the artifact of data if it has been stored, or logs the beginning of data otherwise
+func (ev RedisEvent) payload(data []byte, artifact *artifacts.Artifact) logdata.Payload {
+	if artifact != nil {
+		return logdata.NewArtifactPayloadLogData(artifact.SHA256, artifact.Size)
+	}
+
+	return logdata.NewPayloadLogData(data, config.Cfg.MaxRedisDataSize)
+}
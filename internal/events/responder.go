@@ -0,0 +1,78 @@
+package events
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/responders"
+
+	"github.com/google/gopacket/layers"
+	"github.com/rs/xid"
+)
+
+// ResponderEvent describes the structure of the event generated at the end of the session of a responder
+type ResponderEvent struct {
+	Name    string
+	Summary responders.Session
+	LogData logdata.ResponderEventLog
+	BaseEvent
+}
+
+// NewResponderEvent creates a ResponderEvent from the session of the given responder. The app_proto field holds the
+// name of the responder
+func NewResponderEvent(name string, session responders.Session) *ResponderEvent {
+	ev := &ResponderEvent{
+		Name:    name,
+		Summary: session,
+	}
+
+	// Cannot use promoted (inherited) fields in struct literal
+	ev.Kind = config.ResponderKind
+	ev.AppProto = name
+	ev.SourceIP = session.SourceIP
+	ev.DestPort = session.DestPort
+	ev.Timestamp = time.Now()
+	ev.Session = xid.New().String()
+
+	ev.Tags = make(Tags)
+	ev.Additional = make(map[string]string)
+
+	return ev
+}
+
+// GetIPHeader satisfies the Event interface by returning nil, as the responder events are not generated from a packet
+func (ev ResponderEvent) GetIPHeader() *layers.IPv4 {
+	return nil
+}
+
+// ToLog parses the event structure and generate an EventLog almost ready to be sent to the logging file
+func (ev ResponderEvent) ToLog() EventLog {
+	ev.LogData = logdata.ResponderEventLog{}
+	ev.LogData.Timestamp = ev.Timestamp.Format(time.RFC3339Nano)
+	ev.LogData.Init(ev.BaseEvent)
+
+	ev.LogData.Responder = logdata.ResponderLogData{
+		Name:       ev.Name,
+		SourcePort: ev.Summary.SourcePort,
+		Start:      ev.Summary.Start.Format(time.RFC3339Nano),
+		Duration:   ev.Summary.Duration.Seconds(),
+		ClosedBy:   ev.Summary.ClosedBy,
+		Truncated:  ev.Summary.Truncated,
+		Dialogue:   []logdata.DialogueLogData{},
+	}
+
+	for _, msg := range ev.Summary.Dialogue {
+		ev.LogData.Responder.Dialogue = append(ev.LogData.Responder.Dialogue, logdata.DialogueLogData{
+			From:    msg.From,
+			Offset:  msg.Time.Sub(ev.Summary.Start).Seconds(),
+			Content: string(msg.Data),
+			Base64:  base64.StdEncoding.EncodeToString(msg.Data),
+		})
+	}
+
+	ev.LogData.Additional = ev.Additional
+
+	return ev.LogData
+}
@@ -0,0 +1,186 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/artifacts"
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/credentials"
+	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/smtpd"
+
+	"github.com/google/gopacket/layers"
+	"github.com/rs/xid"
+)
+
+// SMTPEvent describes the structure of the event generated at the end of a session of the SMTP server
+type SMTPEvent struct {
+	Summary  smtpd.Session
+	Messages []SMTPMessage
+	Errors   []string
+	LogData  logdata.SMTPEventLog
+	BaseEvent
+}
+
+// SMTPMessage describes a message sent during a session, along with its parsed content
+type SMTPMessage struct {
+	smtpd.Message
+	Content     smtpd.Content
+	Artifact    *artifacts.Artifact
+	Attachments []SMTPAttachment
+}
+
+// SMTPAttachment describes a file attached to a message. The artifact is not stored if the artifacts storage is
+// disabled, but its hash is still computed
+type SMTPAttachment struct {
+	Filename    string
+	ContentType string
+	Artifact    *artifacts.Artifact
+}
+
+// NewSMTPEvent creates an SMTPEvent from a session of the SMTP server. The messages and their attachments are written
+// to the artifacts store if it is enabled
+func NewSMTPEvent(session smtpd.Session) *SMTPEvent {
+	ev := &SMTPEvent{
+		Summary: session,
+	}
+
+	for _, message := range session.Messages {
+		msg := SMTPMessage{Message: message}
+		if len(message.Data) == 0 {
+			ev.Messages = append(ev.Messages, msg)
+			continue
+		}
+
+		content, err := smtpd.ParseContent(message.Data)
+		if err != nil {
+			ev.Errors = append(ev.Errors, fmt.Sprintf("failed to parse message [%s]", err))
+		}
+		msg.Content = content
+
+		if artifacts.Default != nil {
+			msg.Artifact, err = artifacts.Default.Save(message.Data)
+			if err != nil {
+				ev.Errors = append(ev.Errors, err.Error())
+			}
+		}
+
+		for _, attachment := range content.Attachments {
+			artifact, err := saveAttachment(attachment.Data)
+			if err != nil {
+				ev.Errors = append(ev.Errors, err.Error())
+			}
+
+			msg.Attachments = append(msg.Attachments, SMTPAttachment{
+				Filename:    attachment.Filename,
+				ContentType: attachment.ContentType,
+				Artifact:    artifact,
+			})
+		}
+
+		ev.Messages = append(ev.Messages, msg)
+	}
+
+	// Cannot use promoted (inherited) fields in struct literal
+	ev.Kind = config.SMTPKind
+	ev.AppProto = config.SMTPKind
+	ev.SourceIP = session.SourceIP
+	ev.DestPort = session.DestPort
+	ev.Timestamp = time.Now()
+	ev.Session = xid.New().String()
+
+	ev.Tags = make(Tags)
+	ev.Additional = make(map[string]string)
+
+	return ev
+}
+
+// saveAttachment writes an attachment to the artifacts store, or only hashes it if the store is disabled. The hash is
+// returned even if the attachment could not be stored
+func saveAttachment(data []byte) (*artifacts.Artifact, error) {
+	sum := sha256.Sum256(data)
+	artifact := &artifacts.Artifact{SHA256: hex.EncodeToString(sum[:]), Size: len(data)}
+
+	if artifacts.Default == nil {
+		return artifact, nil
+	}
+
+	if _, err := artifacts.Default.Save(data); err != nil {
+		return artifact, err
+	}
+
+	return artifact, nil
+}
+
+// GetIPHeader satisfies the Event interface by returning nil, as the SMTP events are not generated from a packet
+func (ev SMTPEvent) GetIPHeader() *layers.IPv4 {
+	return nil
+}
+
+// ToLog parses the event structure and generate an EventLog almost ready to be sent to the logging file
+func (ev SMTPEvent) ToLog() EventLog {
+	ev.LogData = logdata.SMTPEventLog{}
+	ev.LogData.Timestamp = ev.Timestamp.Format(time.RFC3339Nano)
+	ev.LogData.Init(ev.BaseEvent)
+
+	ev.LogData.SMTP = logdata.SMTPLogData{
+		SourcePort: ev.Summary.SourcePort,
+		Start:      ev.Summary.Start.Format(time.RFC3339Nano),
+		Duration:   ev.Summary.Duration.Seconds(),
+		ClosedBy:   ev.Summary.ClosedBy,
+		Helo:       ev.Summary.Helo,
+		TLS:        ev.Summary.TLS,
+		Auth:       []logdata.CredentialsLogData{},
+		Messages:   []logdata.SMTPMessageLogData{},
+		Errors:     ev.Errors,
+	}
+
+	for _, auth := range ev.Summary.Auths {
+		creds := &credentials.Credentials{Scheme: auth.Mechanism, Username: auth.Username, Password: auth.Password}
+		creds.Protect(config.Cfg.ServerAuthPasswordStorage)
+
+		ev.LogData.SMTP.Auth = append(ev.LogData.SMTP.Auth, logdata.CredentialsLogData{
+			Scheme:   creds.Scheme,
+			Username: creds.Username,
+			Password: creds.Password,
+		})
+	}
+
+	for _, msg := range ev.Messages {
+		msgLog := logdata.SMTPMessageLogData{
+			MailFrom:    msg.MailFrom,
+			RcptTo:      msg.RcptTo,
+			Size:        msg.Size,
+			Rejected:    msg.Truncated,
+			QueueID:     msg.QueueID,
+			Subject:     msg.Content.Subject,
+			From:        msg.Content.From,
+			To:          msg.Content.To,
+			MessageID:   msg.Content.MessageID,
+			Data:        logdata.NewPayloadLogData(msg.Data, config.Cfg.MaxSMTPDataSize),
+			Attachments: []logdata.AttachmentLogData{},
+		}
+
+		if msg.Artifact != nil {
+			msgLog.Data = logdata.NewArtifactPayloadLogData(msg.Artifact.SHA256, msg.Artifact.Size)
+		}
+
+		for _, attachment := range msg.Attachments {
+			msgLog.Attachments = append(msgLog.Attachments, logdata.AttachmentLogData{
+				Filename:    attachment.Filename,
+				ContentType: attachment.ContentType,
+				SHA256:      attachment.Artifact.SHA256,
+				Size:        attachment.Artifact.Size,
+			})
+		}
+
+		ev.LogData.SMTP.Messages = append(ev.LogData.SMTP.Messages, msgLog)
+	}
+
+	ev.LogData.Additional = ev.Additional
+
+	return ev.LogData
+}
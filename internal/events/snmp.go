@@ -0,0 +1,108 @@
+package events
+
+import (
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/events/helpers"
+	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/snmpparser"
+
+	"github.com/bonjourmalware/melody/internal/config"
+
+	"github.com/bonjourmalware/melody/internal/sessions"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// SNMPEvent describes the structure of an event generated by an SNMP message carried by an UDP packet
+type SNMPEvent struct {
+	Version    string
+	Community  string
+	User       string
+	PDUType    string
+	RequestID  int64
+	OIDs       []string
+	SourcePort uint16
+	LogData    logdata.SNMPEventLog
+	BaseEvent
+	helpers.IPv4Layer
+	helpers.IPv6Layer
+}
+
+// NewSNMPEvent creates a new SNMPEvent from an UDP packet. It returns snmpparser.ErrNotSNMP if the payload is not a
+// valid SNMP message
+func NewSNMPEvent(packet gopacket.Packet, IPVersion uint) (*SNMPEvent, error) {
+	var ev = &SNMPEvent{}
+	ev.Kind = config.SNMPKind
+	ev.IPVersion = IPVersion
+
+	UDPHeader, _ := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+
+	msg, err := snmpparser.Parse(UDPHeader.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ev.Timestamp = packet.Metadata().Timestamp
+	ev.Session = sessions.SessionMap.GetUID(packet.TransportLayer().TransportFlow().String())
+	ev.AppProto = config.SNMPKind
+
+	switch IPVersion {
+	case 4:
+		IPHeader, _ := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+		ev.IPv4Layer = helpers.IPv4Layer{Header: IPHeader}
+		ev.SourceIP = IPHeader.SrcIP.String()
+	case 6:
+		IPHeader, _ := packet.Layer(layers.LayerTypeIPv6).(*layers.IPv6)
+		ev.IPv6Layer = helpers.IPv6Layer{Header: IPHeader}
+		ev.SourceIP = IPHeader.SrcIP.String()
+	}
+
+	ev.DestPort = uint16(UDPHeader.DstPort)
+	ev.SourcePort = uint16(UDPHeader.SrcPort)
+	ev.Version = msg.Version
+	ev.Community = msg.Community
+	ev.User = msg.User
+	ev.PDUType = msg.PDUType
+	ev.RequestID = msg.RequestID
+	ev.OIDs = msg.OIDs
+
+	ev.Additional = make(map[string]string)
+	ev.Tags = make(Tags)
+
+	return ev, nil
+}
+
+// GetSNMPData returns the event's data
+func (ev SNMPEvent) GetSNMPData() SNMPEvent {
+	return ev
+}
+
+// ToLog parses the event structure and generate an EventLog almost ready to be sent to the logging file
+func (ev SNMPEvent) ToLog() EventLog {
+	ev.LogData = logdata.SNMPEventLog{}
+	ev.LogData.Timestamp = ev.Timestamp.Format(time.RFC3339Nano)
+	ev.LogData.Init(ev.BaseEvent)
+
+	switch ev.IPVersion {
+	case 4:
+		ev.LogData.IP = logdata.NewIPv4LogData(ev.IPv4Layer)
+	case 6:
+		ev.LogData.IP = logdata.NewIPv6LogData(ev.IPv6Layer)
+	}
+
+	ev.LogData.SNMP = logdata.SNMPLogData{
+		Version:    ev.Version,
+		Community:  ev.Community,
+		User:       ev.User,
+		PDUType:    ev.PDUType,
+		RequestID:  ev.RequestID,
+		OIDs:       ev.OIDs,
+		SourcePort: ev.SourcePort,
+	}
+
+	ev.LogData.Additional = ev.Additional
+
+	return ev.LogData
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/credentials"
+	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/sshd"
+
+	"github.com/google/gopacket/layers"
+)
+
+// SSHEvent describes the structure of an event generated by an action of a client of the SSH server
+type SSHEvent struct {
+	SourcePort     uint16
+	ClientVersion  string
+	Action         string
+	Username       string
+	AuthMethod     string
+	Password       string
+	KeyType        string
+	KeyFingerprint string
+	Accepted       bool
+	Command        string
+	LogData        logdata.SSHEventLog
+	BaseEvent
+}
+
+// NewSSHEvent creates an SSHEvent from an activity of a client. The session is shared by all the events of the same
+// connection. The passwords are stored as configured
+func NewSSHEvent(activity sshd.Activity, session string) *SSHEvent {
+	creds := &credentials.Credentials{Password: activity.Password}
+	creds.Protect(config.Cfg.ServerAuthPasswordStorage)
+
+	ev := &SSHEvent{
+		SourcePort:     activity.SourcePort,
+		ClientVersion:  activity.ClientVersion,
+		Action:         activity.Action,
+		Username:       activity.Username,
+		AuthMethod:     activity.Method,
+		Password:       creds.Password,
+		KeyType:        activity.KeyType,
+		KeyFingerprint: activity.KeyFingerprint,
+		Accepted:       activity.Accepted,
+		Command:        activity.Command,
+	}
+
+	// Cannot use promoted (inherited) fields in struct literal
+	ev.Kind = config.SSHKind
+	ev.AppProto = config.SSHKind
+	ev.SourceIP = activity.SourceIP
+	ev.DestPort = activity.DestPort
+	ev.Timestamp = time.Now()
+	ev.Session = session
+
+	ev.Tags = make(Tags)
+	ev.Additional = make(map[string]string)
+
+	return ev
+}
+
+// GetIPHeader satisfies the Event interface by returning nil, as the SSH events are not generated from a packet
+func (ev SSHEvent) GetIPHeader() *layers.IPv4 {
+	return nil
+}
+
+// GetSSHData returns the event's data
+func (ev SSHEvent) GetSSHData() SSHEvent {
+	return ev
+}
+
+// ToLog parses the event structure and generate an EventLog almost ready to be sent to the logging file
+func (ev SSHEvent) ToLog() EventLog {
+	ev.LogData = logdata.SSHEventLog{}
+	ev.LogData.Timestamp = ev.Timestamp.Format(time.RFC3339Nano)
+	ev.LogData.Init(ev.BaseEvent)
+
+	ev.LogData.SSH = logdata.SSHLogData{
+		SourcePort:     ev.SourcePort,
+		ClientVersion:  ev.ClientVersion,
+		Action:         ev.Action,
+		Username:       ev.Username,
+		AuthMethod:     ev.AuthMethod,
+		Password:       ev.Password,
+		KeyType:        ev.KeyType,
+		KeyFingerprint: ev.KeyFingerprint,
+		Accepted:       ev.Accepted,
+		Command:        ev.Command,
+	}
+
+	ev.LogData.Additional = ev.Additional
+
+	return ev.LogData
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/tarpit"
+
+	"github.com/google/gopacket/layers"
+	"github.com/rs/xid"
+)
+
+// TarpitEvent describes the structure of the summary event generated when a connection leaves the tarpit
+type TarpitEvent struct {
+	SourcePort    uint16
+	Mode          string
+	Start         time.Time
+	Duration      time.Duration
+	BytesSent     int64
+	BytesReceived int64
+	ReleasedBy    string
+	LogData       logdata.TarpitEventLog
+	BaseEvent
+}
+
+// NewTarpitEvent creates a TarpitEvent from the summary of a connection. The session links the event to the HTTP
+// event of the trapped request if not empty, and is generated otherwise
+func NewTarpitEvent(summary tarpit.Summary, appProto string, session string) *TarpitEvent {
+	ev := &TarpitEvent{
+		SourcePort:    summary.SourcePort,
+		Mode:          summary.Mode,
+		Start:         summary.Start,
+		Duration:      summary.Duration,
+		BytesSent:     summary.BytesSent,
+		BytesReceived: summary.BytesReceived,
+		ReleasedBy:    summary.ReleasedBy,
+	}
+
+	// Cannot use promoted (inherited) fields in struct literal
+	ev.Kind = config.TarpitKind
+	ev.AppProto = appProto
+	ev.SourceIP = summary.SourceIP
+	ev.DestPort = summary.DestPort
+	ev.Timestamp = time.Now()
+	ev.Session = session
+	if ev.Session == "" {
+		ev.Session = xid.New().String()
+	}
+
+	ev.Tags = make(Tags)
+	ev.Additional = make(map[string]string)
+
+	return ev
+}
+
+// GetIPHeader satisfies the Event interface by returning nil, as the tarpit events are not generated from a packet
+func (ev TarpitEvent) GetIPHeader() *layers.IPv4 {
+	return nil
+}
+
+// ToLog parses the event structure and generate an EventLog almost ready to be sent to the logging file
+func (ev TarpitEvent) ToLog() EventLog {
+	ev.LogData = logdata.TarpitEventLog{}
+	ev.LogData.Timestamp = ev.Timestamp.Format(time.RFC3339Nano)
+	ev.LogData.Init(ev.BaseEvent)
+
+	ev.LogData.Tarpit = logdata.TarpitLogData{
+		SourcePort:    ev.SourcePort,
+		Mode:          ev.Mode,
+		Start:         ev.Start.Format(time.RFC3339Nano),
+		Duration:      ev.Duration.Seconds(),
+		BytesSent:     ev.BytesSent,
+		BytesReceived: ev.BytesReceived,
+		ReleasedBy:    ev.ReleasedBy,
+	}
+
+	ev.LogData.Additional = ev.Additional
+
+	return ev.LogData
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/bonjourmalware/melody/internal/events/helpers"
 
 	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/protoid"
 
 	"github.com/bonjourmalware/melody/internal/sessions"
 
@@ -50,6 +51,7 @@ func NewTCPEvent(packet gopacket.Packet, IPVersion uint) (*TCPEvent, error) {
 	ev.Timestamp = packet.Metadata().Timestamp
 	ev.TCPLayer = helpers.TCPLayer{Header: TCPHeader}
 	ev.DestPort = uint16(TCPHeader.DstPort)
+	ev.AppProto = identifyAppProto(packet, TCPHeader.Payload, protoid.TCP)
 
 	ev.Additional = make(map[string]string)
 	ev.Tags = make(Tags)
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/credentials"
+	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/telnetd"
+
+	"github.com/google/gopacket/layers"
+	"github.com/rs/xid"
+)
+
+// TelnetEvent describes the structure of the event generated at the end of a session of the Telnet server
+type TelnetEvent struct {
+	Summary telnetd.Session
+	LogData logdata.TelnetEventLog
+	BaseEvent
+}
+
+// NewTelnetEvent creates a TelnetEvent from a session of the Telnet server
+func NewTelnetEvent(session telnetd.Session) *TelnetEvent {
+	ev := &TelnetEvent{
+		Summary: session,
+	}
+
+	// Cannot use promoted (inherited) fields in struct literal
+	ev.Kind = config.TelnetKind
+	ev.AppProto = config.TelnetKind
+	ev.SourceIP = session.SourceIP
+	ev.DestPort = session.DestPort
+	ev.Timestamp = time.Now()
+	ev.Session = xid.New().String()
+
+	ev.Tags = make(Tags)
+	ev.Additional = make(map[string]string)
+
+	return ev
+}
+
+// GetIPHeader satisfies the Event interface by returning nil, as the Telnet events are not generated from a packet
+func (ev TelnetEvent) GetIPHeader() *layers.IPv4 {
+	return nil
+}
+
+// ToLog parses the event structure and generate an EventLog almost ready to be sent to the logging file. The
+// passwords, including the ones typed in the dialogue, are stored as configured
+func (ev TelnetEvent) ToLog() EventLog {
+	ev.LogData = logdata.TelnetEventLog{}
+	ev.LogData.Timestamp = ev.Timestamp.Format(time.RFC3339Nano)
+	ev.LogData.Init(ev.BaseEvent)
+
+	ev.LogData.Telnet = logdata.TelnetLogData{
+		SourcePort: ev.Summary.SourcePort,
+		Start:      ev.Summary.Start.Format(time.RFC3339Nano),
+		Duration:   ev.Summary.Duration.Seconds(),
+		ClosedBy:   ev.Summary.ClosedBy,
+		Truncated:  ev.Summary.Truncated,
+		Logins:     []logdata.LoginLogData{},
+		Commands:   []string{},
+		Dialogue:   []logdata.DialogueLogData{},
+	}
+
+	for _, login := range ev.Summary.Logins {
+		creds := &credentials.Credentials{Password: login.Password}
+		creds.Protect(config.Cfg.ServerAuthPasswordStorage)
+
+		ev.LogData.Telnet.Logins = append(ev.LogData.Telnet.Logins, logdata.LoginLogData{
+			Username: login.Username,
+			Password: creds.Password,
+			Accepted: login.Accepted,
+		})
+	}
+
+	ev.LogData.Telnet.Commands = append(ev.LogData.Telnet.Commands, ev.Summary.Commands...)
+
+	for _, msg := range ev.Summary.Dialogue {
+		data := msg.Data
+		if msg.Secret {
+			creds := &credentials.Credentials{Password: string(data)}
+			creds.Protect(config.Cfg.ServerAuthPasswordStorage)
+			data = []byte(creds.Password)
+		}
+
+		ev.LogData.Telnet.Dialogue = append(ev.LogData.Telnet.Dialogue, logdata.DialogueLogData{
+			From:    msg.From,
+			Offset:  msg.Time.Sub(ev.Summary.Start).Seconds(),
+			Content: string(data),
+			Base64:  base64.StdEncoding.EncodeToString(data),
+		})
+	}
+
+	ev.LogData.Additional = ev.Additional
+
+	return ev.LogData
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/bonjourmalware/melody/internal/events/helpers"
 	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/protoid"
+	"github.com/bonjourmalware/melody/internal/udpresponders"
 
 	"github.com/bonjourmalware/melody/internal/config"
 
@@ -17,6 +19,7 @@ import (
 
 // UDPEvent describes the structure of an event generated by an ICPMv4 packet
 type UDPEvent struct {
+	Reply   *udpresponders.Reply
 	LogData logdata.UDPEventLog
 	BaseEvent
 	helpers.UDPLayer
@@ -47,6 +50,7 @@ func NewUDPEvent(packet gopacket.Packet, IPVersion uint) (*UDPEvent, error) {
 	UDPHeader, _ := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
 	ev.UDPLayer = helpers.UDPLayer{Header: UDPHeader}
 	ev.DestPort = uint16(UDPHeader.DstPort)
+	ev.AppProto = identifyAppProto(packet, UDPHeader.Payload, protoid.UDP)
 
 	ev.Additional = make(map[string]string)
 	ev.Tags = make(Tags)
@@ -115,6 +119,15 @@ func (ev UDPEvent) ToLog() EventLog {
 		Checksum: ev.UDPLayer.Header.Checksum,
 	}
 
+	if ev.Reply != nil {
+		ev.LogData.UDP.Reply = &logdata.UDPReplyLogData{
+			Service:     ev.Reply.Service,
+			Payload:     logdata.NewPayloadLogData(ev.Reply.Payload, config.Cfg.MaxUDPDataSize),
+			RateLimited: ev.Reply.RateLimited,
+			Oversized:   ev.Reply.Oversized,
+		}
+	}
+
 	ev.LogData.Additional = ev.Additional
 
 	return ev.LogData
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/vncd"
+
+	"github.com/google/gopacket/layers"
+	"github.com/rs/xid"
+)
+
+// VNCEvent describes the structure of the event generated at the end of a session of the VNC server
+type VNCEvent struct {
+	Summary       vncd.Session
+	ClientVersion string
+	LogData       logdata.VNCEventLog
+	BaseEvent
+}
+
+// NewVNCEvent creates a VNCEvent from a session of the VNC server
+func NewVNCEvent(session vncd.Session) *VNCEvent {
+	ev := &VNCEvent{
+		Summary:       session,
+		ClientVersion: session.ClientVersion,
+	}
+
+	// Cannot use promoted (inherited) fields in struct literal
+	ev.Kind = config.VNCKind
+	ev.AppProto = config.VNCKind
+	ev.SourceIP = session.SourceIP
+	ev.DestPort = session.DestPort
+	ev.Timestamp = time.Now()
+	ev.Session = xid.New().String()
+
+	ev.Tags = make(Tags)
+	ev.Additional = make(map[string]string)
+
+	return ev
+}
+
+// GetIPHeader satisfies the Event interface by returning nil, as the VNC events are not generated from a packet
+func (ev VNCEvent) GetIPHeader() *layers.IPv4 {
+	return nil
+}
+
+// GetVNCData returns the event's data
+func (ev VNCEvent) GetVNCData() VNCEvent {
+	return ev
+}
+
+// ToLog parses the event structure and generate an EventLog almost ready to be sent to the logging file. The
+// challenge and the response of the VNC authentication are kept as is, so that the password can be recovered offline
+func (ev VNCEvent) ToLog() EventLog {
+	ev.LogData = logdata.VNCEventLog{}
+	ev.LogData.Timestamp = ev.Timestamp.Format(time.RFC3339Nano)
+	ev.LogData.Init(ev.BaseEvent)
+
+	ev.LogData.VNC = logdata.VNCLogData{
+		SourcePort:    ev.Summary.SourcePort,
+		Start:         ev.Summary.Start.Format(time.RFC3339Nano),
+		Duration:      ev.Summary.Duration.Seconds(),
+		ClosedBy:      ev.Summary.ClosedBy,
+		ServerVersion: ev.Summary.ServerVersion,
+		ClientVersion: ev.ClientVersion,
+		SecurityType:  ev.Summary.SecurityType,
+		Challenge:     hex.EncodeToString(ev.Summary.Challenge),
+		Response:      hex.EncodeToString(ev.Summary.Response),
+		Hash:          vncd.Hash(ev.Summary.Challenge, ev.Summary.Response),
+	}
+
+	ev.LogData.Additional = ev.Additional
+
+	return ev.LogData
+}
@@ -0,0 +1,89 @@
+package events
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/logdata"
+	"github.com/bonjourmalware/melody/internal/websocket"
+
+	"github.com/google/gopacket/layers"
+)
+
+// WebSocketEvent describes the structure of the event generated for each frame sent by a WebSocket client
+type WebSocketEvent struct {
+	SourcePort uint16
+	Path       string
+	Protocol   string
+	Frame      websocket.Frame
+	LogData    logdata.WebSocketEventLog
+	BaseEvent
+}
+
+// NewWebSocketEvent creates a WebSocketEvent from a frame sent after the upgrade requested by r. The session is shared
+// by all the frames of a connection
+func NewWebSocketEvent(r *http.Request, frame websocket.Frame, session string) *WebSocketEvent {
+	ev := &WebSocketEvent{
+		Path:     r.URL.Path,
+		Protocol: strings.TrimSpace(strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",")[0]),
+		Frame:    frame,
+	}
+
+	if host, port, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		srcPort, _ := strconv.ParseUint(port, 10, 16)
+		ev.SourceIP = host
+		ev.SourcePort = uint16(srcPort)
+	}
+
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(*net.TCPAddr); ok {
+		ev.DestPort = uint16(addr.Port)
+	}
+
+	// Cannot use promoted (inherited) fields in struct literal
+	ev.Kind = config.WebSocketKind
+	ev.AppProto = config.HTTPKind
+	if r.TLS != nil {
+		ev.AppProto = config.HTTPSKind
+	}
+	ev.Timestamp = frame.Time
+	ev.Session = session
+
+	ev.Tags = make(Tags)
+	ev.Additional = make(map[string]string)
+
+	return ev
+}
+
+// GetIPHeader satisfies the Event interface by returning nil, as the WebSocket events are not generated from a packet
+func (ev WebSocketEvent) GetIPHeader() *layers.IPv4 {
+	return nil
+}
+
+// ToLog parses the event structure and generate an EventLog almost ready to be sent to the logging file
+func (ev WebSocketEvent) ToLog() EventLog {
+	ev.LogData = logdata.WebSocketEventLog{}
+	ev.LogData.Timestamp = ev.Timestamp.Format(time.RFC3339Nano)
+	ev.LogData.Init(ev.BaseEvent)
+
+	ev.LogData.WebSocket = logdata.WebSocketLogData{
+		SourcePort: ev.SourcePort,
+		Path:       ev.Path,
+		Protocol:   ev.Protocol,
+		Index:      ev.Frame.Index,
+		Fin:        ev.Frame.Fin,
+		Opcode:     ev.Frame.Opcode,
+		OpcodeName: websocket.OpcodeName(ev.Frame.Opcode),
+		Masked:     ev.Frame.Masked,
+		Length:     ev.Frame.Length,
+		Payload:    logdata.NewPayloadLogData(ev.Frame.Payload, config.Cfg.ServerWebSocketMaxPayload),
+	}
+	ev.LogData.WebSocket.Payload.Truncated = ev.LogData.WebSocket.Payload.Truncated || ev.Frame.Truncated
+
+	ev.LogData.Additional = ev.Additional
+
+	return ev.LogData
+}
@@ -13,6 +13,30 @@ import (
 	"github.com/c2h5oh/datasize"
 )
 
+// ReadBody reads the decoded body of an http.Request up to maxSize bytes, and restores it for the next readers. It
+// returns false if the body is larger than maxSize
+func ReadBody(r *http.Request, maxSize uint64) ([]byte, bool, error) {
+	if r.Body == nil {
+		return nil, true, nil
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(maxSize)+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read request body [%s]", err)
+	}
+
+	if uint64(len(data)) > maxSize {
+		return nil, false, nil
+	}
+
+	return data, true, nil
+}
+
 // GetBodyPayload extract the body of an http.Request without striping it
 func GetBodyPayload(r *http.Request) ([]byte, error) {
 	var buf bytes.Buffer
@@ -20,13 +44,19 @@ func GetBodyPayload(r *http.Request) ([]byte, error) {
 	var dest io.Writer = &b
 
 	if r.Body != nil {
-		if r.Header.Get("Content-Length") == "" {
-			return []byte{}, nil
-		}
-		iContentLength, err := strconv.ParseUint(r.Header.Get("Content-Length"), 10, 64)
+		var iContentLength uint64
+		var err error
 
-		if err != nil {
-			return []byte{}, fmt.Errorf("request data not logged (failed to parse Content-Length as uint64 : %s)", err)
+		if r.Header.Get("Content-Length") != "" {
+			iContentLength, err = strconv.ParseUint(r.Header.Get("Content-Length"), 10, 64)
+			if err != nil {
+				return []byte{}, fmt.Errorf("request data not logged (failed to parse Content-Length as uint64 : %s)", err)
+			}
+		} else if r.ProtoMajor == 2 && r.ContentLength > 0 {
+			// The Content-Length header is optional in HTTP/2, as the end of the body is signaled by the END_STREAM flag
+			iContentLength = uint64(r.ContentLength)
+		} else {
+			return []byte{}, nil
 		}
 
 		if iContentLength > config.Cfg.MaxPOSTDataSize {
@@ -43,6 +73,8 @@ func GetBodyPayload(r *http.Request) ([]byte, error) {
 		}
 
 		bodyReader := ioutil.NopCloser(bytes.NewReader(buf.Bytes()))
+		// Restore the body for the next readers, such as the dummy webserver's handlers
+		r.Body = ioutil.NopCloser(bytes.NewReader(buf.Bytes()))
 
 		chunked := len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked"
 
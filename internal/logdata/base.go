@@ -16,6 +16,7 @@ type BaseLogData struct {
 	Type       string              `json:"type"`
 	SourceIP   string              `json:"src_ip"`
 	DestPort   uint16              `json:"dst_port"`
+	AppProto   string              `json:"app_proto"`
 	Tags       map[string][]string `json:"matches"`
 	InlineTags []string            `json:"inline_matches"`
 	Additional map[string]string   `json:"embedded"`
@@ -27,6 +28,7 @@ func (l *BaseLogData) Init(ev loggable.Loggable) {
 	l.SourceIP = ev.GetSourceIP()
 	l.DestPort = ev.GetDestPort()
 	l.Session = ev.GetSession()
+	l.AppProto = ev.GetAppProto()
 	l.InlineTags = []string{}
 
 	if len(ev.GetTags()) == 0 {
@@ -9,6 +9,7 @@ type HTTPLogData struct {
 	RequestURI    string            `json:"uri"`
 	SourcePort    uint16            `json:"src_port"`
 	DestHost      string            `json:"dst_host"`
+	VHost         string            `json:"vhost"`
 	UserAgent     string            `json:"user_agent"`
 	Headers       map[string]string `json:"headers"`
 	HeadersKeys   []string          `json:"headers_keys"`
@@ -16,6 +17,42 @@ type HTTPLogData struct {
 	Errors        []string          `json:"errors"`
 	Body          Payload           `json:"body"`
 	IsTLS         bool              `json:"is_tls"`
+
+	TLSVersion            string `json:"tls_version"`
+	TLSCipherSuite        string `json:"tls_cipher_suite"`
+	TLSServerName         string `json:"tls_sni"`
+	TLSNegotiatedProtocol string `json:"tls_alpn"`
+
+	Proxy       *ProxyLogData        `json:"proxy,omitempty"`
+	Credentials []CredentialsLogData `json:"credentials,omitempty"`
+	Uploads     []UploadLogData      `json:"uploads,omitempty"`
+}
+
+// UploadLogData is the struct describing a file uploaded with a multipart request and written to the artifacts store
+type UploadLogData struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	SHA256      string `json:"sha256"`
+	Size        int    `json:"size"`
+}
+
+// CredentialsLogData is the struct describing the credentials sent along an HTTP request
+type CredentialsLogData struct {
+	Scheme   string `json:"scheme"`
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// ProxyLogData is the struct describing the PROXY protocol header sent by the trusted proxy a request went through
+type ProxyLogData struct {
+	Version  int               `json:"version"`
+	SourceIP string            `json:"src_ip"`
+	DestIP   string            `json:"dst_ip"`
+	DestPort uint16            `json:"dst_port"`
+	TLVs     map[string]string `json:"tlvs,omitempty"`
 }
 
 // HTTPEventLog is the event log struct for reassembled HTTP packets
@@ -7,6 +7,14 @@ type Payload struct {
 	Content   string `json:"content"`
 	Base64    string `json:"base64"`
 	Truncated bool   `json:"truncated"`
+
+	Artifact *ArtifactLogData `json:"artifact,omitempty"`
+}
+
+// ArtifactLogData is the struct describing a file written to the artifacts store instead of being logged
+type ArtifactLogData struct {
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
 }
 
 // NewPayloadLogData is used to create a new Payload struct
@@ -21,3 +29,8 @@ func NewPayloadLogData(data []byte, maxLength uint64) Payload {
 	pl.Base64 = base64.StdEncoding.EncodeToString(data)
 	return pl
 }
+
+// NewArtifactPayloadLogData is used to create a Payload struct referencing a stored artifact
+func NewArtifactPayloadLogData(sha256 string, size int) Payload {
+	return Payload{Artifact: &ArtifactLogData{SHA256: sha256, Size: size}}
+}
@@ -0,0 +1,31 @@
+package logdata
+
+import "encoding/json"
+
+// QUICLogData is the struct describing the logged data for QUIC Initial packets
+type QUICLogData struct {
+	Version           uint32   `json:"version"`
+	VersionName       string   `json:"version_name"`
+	DCID              string   `json:"dcid"`
+	SCID              string   `json:"scid"`
+	SourcePort        uint16   `json:"src_port"`
+	SNI               string   `json:"sni"`
+	ALPN              []string `json:"alpn"`
+	SupportedVersions []uint16 `json:"supported_versions"`
+	CipherSuites      []uint16 `json:"cipher_suites"`
+}
+
+// QUICEventLog is the event log struct for QUIC Initial packets
+type QUICEventLog struct {
+	QUIC QUICLogData `json:"quic"`
+	IP   IPLogData   `json:"ip"`
+	BaseLogData
+}
+
+func (eventLog QUICEventLog) String() (string, error) {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,51 @@
+package logdata
+
+import "encoding/json"
+
+// RedisLogData is the struct describing the logged data for the sessions of the Redis server
+type RedisLogData struct {
+	SourcePort   uint16                    `json:"src_port"`
+	Start        string                    `json:"start"`
+	Duration     float64                   `json:"duration"`
+	ClosedBy     string                    `json:"closed_by"`
+	Commands     []RedisCommandLogData     `json:"commands"`
+	Writes       []RedisWriteLogData       `json:"writes"`
+	Replications []RedisReplicationLogData `json:"replications"`
+	Errors       []string                  `json:"errors"`
+}
+
+// RedisCommandLogData is the struct describing a command sent to the Redis server
+type RedisCommandLogData struct {
+	Offset    float64  `json:"offset"`
+	Name      string   `json:"name"`
+	Args      []string `json:"args"`
+	Truncated bool     `json:"truncated"`
+}
+
+// RedisWriteLogData is the struct describing a value written to the Redis server
+type RedisWriteLogData struct {
+	Key  string  `json:"key"`
+	Data Payload `json:"data"`
+}
+
+// RedisReplicationLogData is the struct describing a replication attempt, along with the payload sent by the master
+type RedisReplicationLogData struct {
+	Host  string  `json:"host"`
+	Port  string  `json:"port"`
+	Error string  `json:"error"`
+	Data  Payload `json:"data"`
+}
+
+// RedisEventLog is the event log struct for the sessions of the Redis server
+type RedisEventLog struct {
+	Redis RedisLogData `json:"redis"`
+	BaseLogData
+}
+
+func (eventLog RedisEventLog) String() (string, error) {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
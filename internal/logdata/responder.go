@@ -0,0 +1,37 @@
+package logdata
+
+import "encoding/json"
+
+// ResponderLogData is the struct describing the logged data for the sessions of the responders
+type ResponderLogData struct {
+	Name       string            `json:"name"`
+	SourcePort uint16            `json:"src_port"`
+	Start      string            `json:"start"`
+	Duration   float64           `json:"duration"`
+	ClosedBy   string            `json:"closed_by"`
+	Truncated  bool              `json:"truncated"`
+	Dialogue   []DialogueLogData `json:"dialogue"`
+}
+
+// DialogueLogData is the struct describing a message of the dialogue between a client and a responder. The offset is
+// the time elapsed since the start of the session, in seconds
+type DialogueLogData struct {
+	From    string  `json:"from"`
+	Offset  float64 `json:"offset"`
+	Content string  `json:"content"`
+	Base64  string  `json:"base64"`
+}
+
+// ResponderEventLog is the event log struct for the sessions of the responders
+type ResponderEventLog struct {
+	Responder ResponderLogData `json:"responder"`
+	BaseLogData
+}
+
+func (eventLog ResponderEventLog) String() (string, error) {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,54 @@
+package logdata
+
+import "encoding/json"
+
+// SMTPLogData is the struct describing the logged data for the sessions of the SMTP server
+type SMTPLogData struct {
+	SourcePort uint16               `json:"src_port"`
+	Start      string               `json:"start"`
+	Duration   float64              `json:"duration"`
+	ClosedBy   string               `json:"closed_by"`
+	Helo       string               `json:"helo"`
+	TLS        bool                 `json:"tls"`
+	Auth       []CredentialsLogData `json:"auth"`
+	Messages   []SMTPMessageLogData `json:"messages"`
+	Errors     []string             `json:"errors,omitempty"`
+}
+
+// SMTPMessageLogData is the struct describing a message sent to the SMTP server. The rejected messages went over the
+// maximum size, or were interrupted
+type SMTPMessageLogData struct {
+	MailFrom    string              `json:"mail_from"`
+	RcptTo      []string            `json:"rcpt_to"`
+	Size        int                 `json:"size"`
+	Rejected    bool                `json:"rejected"`
+	QueueID     string              `json:"queue_id,omitempty"`
+	Subject     string              `json:"subject"`
+	From        string              `json:"from"`
+	To          string              `json:"to"`
+	MessageID   string              `json:"message_id"`
+	Data        Payload             `json:"data"`
+	Attachments []AttachmentLogData `json:"attachments"`
+}
+
+// AttachmentLogData is the struct describing a file attached to a message
+type AttachmentLogData struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	SHA256      string `json:"sha256"`
+	Size        int    `json:"size"`
+}
+
+// SMTPEventLog is the event log struct for the sessions of the SMTP server
+type SMTPEventLog struct {
+	SMTP SMTPLogData `json:"smtp"`
+	BaseLogData
+}
+
+func (eventLog SMTPEventLog) String() (string, error) {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
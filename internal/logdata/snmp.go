@@ -0,0 +1,29 @@
+package logdata
+
+import "encoding/json"
+
+// SNMPLogData is the struct describing the logged data for SNMP messages
+type SNMPLogData struct {
+	Version    string   `json:"version"`
+	Community  string   `json:"community"`
+	User       string   `json:"user"`
+	PDUType    string   `json:"pdu_type"`
+	RequestID  int64    `json:"request_id"`
+	OIDs       []string `json:"oids"`
+	SourcePort uint16   `json:"src_port"`
+}
+
+// SNMPEventLog is the event log struct for SNMP messages
+type SNMPEventLog struct {
+	SNMP SNMPLogData `json:"snmp"`
+	IP   IPLogData   `json:"ip"`
+	BaseLogData
+}
+
+func (eventLog SNMPEventLog) String() (string, error) {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,31 @@
+package logdata
+
+import "encoding/json"
+
+// SSHLogData is the struct describing the logged data for the actions of the SSH clients
+type SSHLogData struct {
+	SourcePort     uint16 `json:"src_port"`
+	ClientVersion  string `json:"client_version"`
+	Action         string `json:"action"`
+	Username       string `json:"username"`
+	AuthMethod     string `json:"auth_method,omitempty"`
+	Password       string `json:"password,omitempty"`
+	KeyType        string `json:"key_type,omitempty"`
+	KeyFingerprint string `json:"key_fingerprint,omitempty"`
+	Accepted       bool   `json:"accepted"`
+	Command        string `json:"command,omitempty"`
+}
+
+// SSHEventLog is the event log struct for the actions of the SSH clients
+type SSHEventLog struct {
+	SSH SSHLogData `json:"ssh"`
+	BaseLogData
+}
+
+func (eventLog SSHEventLog) String() (string, error) {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,28 @@
+package logdata
+
+import "encoding/json"
+
+// TarpitLogData is the struct describing the logged data for the connections held by the tarpit
+type TarpitLogData struct {
+	SourcePort    uint16  `json:"src_port"`
+	Mode          string  `json:"mode"`
+	Start         string  `json:"start"`
+	Duration      float64 `json:"duration"`
+	BytesSent     int64   `json:"bytes_sent"`
+	BytesReceived int64   `json:"bytes_received"`
+	ReleasedBy    string  `json:"released_by"`
+}
+
+// TarpitEventLog is the event log struct for the connections held by the tarpit
+type TarpitEventLog struct {
+	Tarpit TarpitLogData `json:"tarpit"`
+	BaseLogData
+}
+
+func (eventLog TarpitEventLog) String() (string, error) {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
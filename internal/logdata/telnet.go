@@ -0,0 +1,36 @@
+package logdata
+
+import "encoding/json"
+
+// TelnetLogData is the struct describing the logged data for the sessions of the Telnet server
+type TelnetLogData struct {
+	SourcePort uint16            `json:"src_port"`
+	Start      string            `json:"start"`
+	Duration   float64           `json:"duration"`
+	ClosedBy   string            `json:"closed_by"`
+	Truncated  bool              `json:"truncated"`
+	Logins     []LoginLogData    `json:"logins"`
+	Commands   []string          `json:"commands"`
+	Dialogue   []DialogueLogData `json:"dialogue"`
+}
+
+// LoginLogData is the struct describing a login attempt
+type LoginLogData struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Accepted bool   `json:"accepted"`
+}
+
+// TelnetEventLog is the event log struct for the sessions of the Telnet server
+type TelnetEventLog struct {
+	Telnet TelnetLogData `json:"telnet"`
+	BaseLogData
+}
+
+func (eventLog TelnetEventLog) String() (string, error) {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -4,9 +4,18 @@ import "encoding/json"
 
 // UDPLogData is the struct describing the logged data for UDP packets
 type UDPLogData struct {
-	Payload  Payload `json:"payload"`
-	Length   uint16  `json:"length"`
-	Checksum uint16  `json:"checksum"`
+	Payload  Payload          `json:"payload"`
+	Length   uint16           `json:"length"`
+	Checksum uint16           `json:"checksum"`
+	Reply    *UDPReplyLogData `json:"reply,omitempty"`
+}
+
+// UDPReplyLogData is the struct describing the reply sent by an UDP responder
+type UDPReplyLogData struct {
+	Service     string  `json:"service"`
+	Payload     Payload `json:"payload"`
+	RateLimited bool    `json:"rate_limited"`
+	Oversized   bool    `json:"oversized"`
 }
 
 // UDPEventLog is the event log struct for UDP packets
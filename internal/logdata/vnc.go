@@ -0,0 +1,31 @@
+package logdata
+
+import "encoding/json"
+
+// VNCLogData is the struct describing the logged data for the sessions of the VNC server
+type VNCLogData struct {
+	SourcePort    uint16  `json:"src_port"`
+	Start         string  `json:"start"`
+	Duration      float64 `json:"duration"`
+	ClosedBy      string  `json:"closed_by"`
+	ServerVersion string  `json:"server_version"`
+	ClientVersion string  `json:"client_version"`
+	SecurityType  uint8   `json:"security_type"`
+	Challenge     string  `json:"challenge,omitempty"`
+	Response      string  `json:"response,omitempty"`
+	Hash          string  `json:"hash,omitempty"`
+}
+
+// VNCEventLog is the event log struct for the sessions of the VNC server
+type VNCEventLog struct {
+	VNC VNCLogData `json:"vnc"`
+	BaseLogData
+}
+
+func (eventLog VNCEventLog) String() (string, error) {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,31 @@
+package logdata
+
+import "encoding/json"
+
+// WebSocketLogData is the struct describing the logged data for the frames sent by the WebSocket clients
+type WebSocketLogData struct {
+	SourcePort uint16  `json:"src_port"`
+	Path       string  `json:"path"`
+	Protocol   string  `json:"protocol"`
+	Index      int     `json:"index"`
+	Fin        bool    `json:"fin"`
+	Opcode     byte    `json:"opcode"`
+	OpcodeName string  `json:"opcode_name"`
+	Masked     bool    `json:"masked"`
+	Length     uint64  `json:"length"`
+	Payload    Payload `json:"payload"`
+}
+
+// WebSocketEventLog is the event log struct for the frames sent by the WebSocket clients
+type WebSocketEventLog struct {
+	WebSocket WebSocketLogData `json:"websocket"`
+	BaseLogData
+}
+
+func (eventLog WebSocketEventLog) String() (string, error) {
+	data, err := json.Marshal(eventLog)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
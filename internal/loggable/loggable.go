@@ -7,4 +7,5 @@ type Loggable interface {
 	GetKind() string
 	GetSourceIP() string
 	GetDestPort() uint16
+	GetAppProto() string
 }
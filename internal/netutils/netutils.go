@@ -0,0 +1,19 @@
+package netutils
+
+import (
+	"net"
+)
+
+// Endpoints returns the source IP and port of a TCP connection, along with the port it has been received on. They are
+// left empty for the other kinds of connections
+func Endpoints(c net.Conn) (srcIP string, srcPort uint16, dstPort uint16) {
+	if addr, ok := c.RemoteAddr().(*net.TCPAddr); ok {
+		srcIP, srcPort = addr.IP.String(), uint16(addr.Port)
+	}
+
+	if addr, ok := c.LocalAddr().(*net.TCPAddr); ok {
+		dstPort = uint16(addr.Port)
+	}
+
+	return srcIP, srcPort, dstPort
+}
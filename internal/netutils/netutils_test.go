@@ -0,0 +1,45 @@
+package netutils
+
+import (
+	"net"
+	"testing"
+)
+
+func TestEndpoints(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	srcIP, srcPort, dstPort := Endpoints(conn)
+	if srcIP != "127.0.0.1" {
+		t.Errorf("expected source IP 127.0.0.1, got %s", srcIP)
+	}
+	if want := uint16(client.LocalAddr().(*net.TCPAddr).Port); srcPort != want {
+		t.Errorf("expected source port %d, got %d", want, srcPort)
+	}
+	if want := uint16(ln.Addr().(*net.TCPAddr).Port); dstPort != want {
+		t.Errorf("expected destination port %d, got %d", want, dstPort)
+	}
+
+	pipe, other := net.Pipe()
+	defer pipe.Close()
+	defer other.Close()
+
+	if srcIP, srcPort, dstPort := Endpoints(pipe); srcIP != "" || srcPort != 0 || dstPort != 0 {
+		t.Errorf("expected empty endpoints for a pipe, got %s:%d -> %d", srcIP, srcPort, dstPort)
+	}
+}
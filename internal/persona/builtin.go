@@ -0,0 +1,201 @@
+package persona
+
+import (
+	"net/http"
+)
+
+var (
+	// builtins are the personas available without any persona file, mimicking the default install of common web
+	// servers
+	builtins = []RawPersona{
+		{
+			Name:   "apache",
+			Server: "Apache/2.4.41 (Ubuntu)",
+			KeepAlive: map[string]string{
+				"Keep-Alive": "timeout=5, max=100",
+				"Connection": "Keep-Alive",
+			},
+			HeaderOrder: []string{
+				"Date", "Server", "Location", "Allow", "Last-Modified", "ETag", "Accept-Ranges", "Content-Length",
+				"Vary", "Keep-Alive", "Connection", "Content-Type",
+			},
+			DateFormat:        http.TimeFormat,
+			AbsoluteRedirects: true,
+			AllowedMethods:    []string{"GET", "HEAD", "POST", "OPTIONS"},
+			Allow:             "GET,POST,OPTIONS,HEAD",
+			Options:           MethodResponse{Status: http.StatusOK},
+			// TraceEnable is disabled by the default Ubuntu configuration
+			Trace:            MethodResponse{Status: http.StatusMethodNotAllowed},
+			ErrorContentType: "text/html; charset=iso-8859-1",
+			ErrorPages: map[string]string{
+				"301": apachePage(`The document has moved <a href="{{.Location}}">here</a>.`),
+				"302": apachePage(`The document has moved <a href="{{.Location}}">here</a>.`),
+				"400": apachePage("Your browser sent a request that this server could not understand.<br />\n"),
+				"401": apachePage("This server could not verify that you\nare authorized to access the document\n" +
+					"requested.  Either you supplied the wrong\ncredentials (e.g., bad password), or your\n" +
+					"browser doesn't understand how to supply\nthe credentials required."),
+				"403": apachePage("You don't have permission to access this resource."),
+				"404": apachePage("The requested URL was not found on this server."),
+				"405": apachePage("The requested method {{.Method}} is not allowed for this URL."),
+				"500": apachePage("The server encountered an internal error or\nmisconfiguration and was unable to complete\n" +
+					"your request.</p>\n<p>Please contact the server administrator at \n webmaster@localhost to inform them " +
+					"of the time this error occurred,\n and the actions you performed just before this error.</p>\n" +
+					"<p>More information about this error may be available\nin the server error log."),
+				"501":          apachePage("{{.Method}} not supported for current URL.<br />\n"),
+				DefaultPageKey: apachePage("{{.Reason}}"),
+			},
+		},
+		{
+			Name:   "nginx",
+			Server: "nginx/1.18.0 (Ubuntu)",
+			KeepAlive: map[string]string{
+				"Connection": "keep-alive",
+			},
+			HeaderOrder: []string{
+				"Server", "Date", "Content-Type", "Content-Length", "Last-Modified", "Location", "Connection", "ETag",
+				"Allow", "Accept-Ranges",
+			},
+			DateFormat:        http.TimeFormat,
+			Reasons:           map[int]string{http.StatusMethodNotAllowed: "Not Allowed"},
+			AbsoluteRedirects: true,
+			// The static module only accepts GET and HEAD
+			AllowedMethods:   []string{"GET", "HEAD"},
+			ErrorContentType: "text/html",
+			ErrorPages: map[string]string{
+				"301":          nginxPage,
+				"302":          nginxPage,
+				DefaultPageKey: nginxPage,
+			},
+		},
+		{
+			Name:    "iis",
+			Server:  "Microsoft-IIS/10.0",
+			Headers: map[string]string{"X-Powered-By": "ASP.NET"},
+			HeaderOrder: []string{
+				"Allow", "Content-Type", "Location", "Last-Modified", "Accept-Ranges", "ETag", "Server", "Public",
+				"X-Powered-By", "Date", "Connection", "Content-Length",
+			},
+			DateFormat:     http.TimeFormat,
+			AllowedMethods: []string{"GET", "HEAD", "POST", "OPTIONS", "TRACE"},
+			Allow:          "OPTIONS, TRACE, GET, HEAD, POST",
+			Options: MethodResponse{
+				Status:  http.StatusOK,
+				Headers: map[string]string{"Public": "OPTIONS, TRACE, GET, HEAD, POST"},
+			},
+			Trace:            MethodResponse{Status: http.StatusNotImplemented},
+			ErrorContentType: "text/html",
+			ErrorPages: map[string]string{
+				"301": iisMovedPage,
+				"302": iisMovedPage,
+				"400": `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN""http://www.w3.org/TR/html4/strict.dtd">
+<HTML><HEAD><TITLE>Bad Request</TITLE>
+<META HTTP-EQUIV="Content-Type" Content="text/html; charset=us-ascii"></HEAD>
+<BODY><h2>Bad Request</h2>
+<hr><p>HTTP Error 400. The request is badly formed.</p>
+</BODY></HTML>
+`,
+				"403": iisPage("403 - Forbidden: Access is denied.",
+					"You do not have permission to view this directory or page using the credentials that you supplied."),
+				"404": iisPage("404 - File or directory not found.",
+					"The resource you are looking for might have been removed, had its name changed, or is temporarily unavailable."),
+				"405": iisPage("405 - HTTP verb used to access this page is not allowed.",
+					"The page you are looking for cannot be displayed because an invalid method (HTTP verb) was used to attempt access."),
+				"500": iisPage("500 - Internal server error.",
+					"There is a problem with the resource you are looking for, and it cannot be displayed."),
+				DefaultPageKey: iisPage("{{.Status}} - {{.Reason}}.",
+					"The page you are looking for cannot be displayed."),
+			},
+		},
+		{
+			Name:   "lighttpd",
+			Server: "lighttpd/1.4.55",
+			HeaderOrder: []string{
+				"Location", "Allow", "Content-Type", "Accept-Ranges", "ETag", "Last-Modified", "Content-Length",
+				"Connection", "Date", "Server",
+			},
+			DateFormat:        http.TimeFormat,
+			AbsoluteRedirects: true,
+			AllowedMethods:    []string{"GET", "HEAD", "POST", "OPTIONS"},
+			Allow:             "OPTIONS, GET, HEAD, POST",
+			// Unsupported methods are answered with 501 Not Implemented
+			DisallowedMethodStatus: http.StatusNotImplemented,
+			Options:                MethodResponse{Status: http.StatusOK},
+			Trace:                  MethodResponse{Status: http.StatusNotImplemented},
+			ErrorContentType:       "text/html",
+			ErrorPages: map[string]string{
+				DefaultPageKey: `<?xml version="1.0" encoding="iso-8859-1"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN"
+         "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">
+ <head>
+  <title>{{.Status}} {{.Reason}}</title>
+ </head>
+ <body>
+  <h1>{{.Status}} {{.Reason}}</h1>
+ </body>
+</html>
+`,
+			},
+		},
+	}
+
+	nginxPage = `<html>
+<head><title>{{.Status}} {{.Reason}}</title></head>
+<body>
+<center><h1>{{.Status}} {{.Reason}}</h1></center>
+<hr><center>{{.Server}}</center>
+</body>
+</html>
+`
+
+	iisMovedPage = `<head><title>Document Moved</title></head>
+<body><h1>Object Moved</h1>This document may be found <a HREF="{{.Location}}">here</a></body>`
+)
+
+// apachePage returns the layout of the Apache error pages with the given message
+func apachePage(message string) string {
+	return `<!DOCTYPE HTML PUBLIC "-//IETF//DTD HTML 2.0//EN">
+<html><head>
+<title>{{.Status}} {{.Reason}}</title>
+</head><body>
+<h1>{{.Reason}}</h1>
+<p>` + message + `</p>
+<hr>
+<address>{{.Server}} Server at {{.Host}} Port {{.Port}}</address>
+</body></html>
+`
+}
+
+// iisPage returns the layout of the IIS error pages with the given title and description
+func iisPage(title string, description string) string {
+	return `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=iso-8859-1"/>
+<title>` + title + `</title>
+<style type="text/css">
+<!--
+body{margin:0;font-size:.7em;font-family:Verdana, Arial, Helvetica, sans-serif;background:#EEEEEE;}
+fieldset{padding:0 15px 10px 15px;}
+h1{font-size:2.4em;margin:0;color:#FFF;}
+h2{font-size:1.7em;margin:0;color:#CC0000;}
+h3{font-size:1.2em;margin:10px 0 0 0;color:#000000;}
+#header{width:96%;margin:0 0 0 0;padding:6px 2% 6px 2%;font-family:"trebuchet MS", Verdana, sans-serif;color:#FFF;
+background-color:#555555;}
+#content{margin:0 0 0 2%;position:relative;}
+.content-container{background:#FFF;width:96%;margin-top:8px;padding:10px;position:relative;}
+-->
+</style>
+</head>
+<body>
+<div id="header"><h1>Server Error</h1></div>
+<div id="content">
+ <div class="content-container"><fieldset>
+  <h2>` + title + `</h2>
+  <h3>` + description + `</h3>
+ </fieldset></div>
+</div>
+</body>
+</html>
+`
+}
@@ -0,0 +1,234 @@
+package persona
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+
+	"github.com/bonjourmalware/melody/internal/logging"
+)
+
+const (
+	// internalErrorHeaders are the headers of the error responses written by net/http when it fails to read a request,
+	// before any handler is called
+	internalErrorHeaders = "\r\nContent-Type: text/plain; charset=utf-8\r\nConnection: close\r\n\r\n"
+)
+
+// maxDrainedBody is the largest unread request body discarded to keep the connection alive, as net/http does
+const maxDrainedBody = 256 << 10
+
+// Handler sends the response of the wrapped handler as the persona would. HTTP/1.x responses are written on the
+// hijacked connection in order to control the headers' order and casing. The connection is then handed back to the
+// server if it is kept alive and was accepted by a KeepAliveListener, and closed otherwise
+func (p *Persona) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := newRecorder()
+		h.ServeHTTP(rec, r) // pass request
+
+		if !rec.wroteHeader {
+			rec.WriteHeader(http.StatusOK)
+		}
+
+		keepAliveListener, _ := r.Context().Value(keepAliveContextKey{}).(*KeepAliveListener)
+		keepAlive := keepAliveListener != nil && !r.Close && drainBody(r)
+
+		if keepAlive {
+			for name, val := range p.KeepAlive {
+				if rec.header.Get(name) == "" {
+					rec.header.Set(name, val)
+				}
+			}
+
+			// The HTTP/1.0 clients close the connection unless told otherwise
+			if r.ProtoMajor == 1 && r.ProtoMinor == 0 && rec.header.Get("Connection") == "" {
+				rec.header.Set("Connection", "keep-alive")
+			}
+		} else {
+			rec.header.Set("Connection", "close")
+		}
+
+		localAddr, _ := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+		body := p.finalize(r, localAddr, rec.status, rec.header, rec.body.Bytes())
+
+		hijacker, ok := w.(http.Hijacker)
+		if !ok || r.ProtoMajor != 1 {
+			// The headers' order and casing and the connection are handled by the protocol
+			rec.header.Del("Connection")
+			rec.header.Del("Keep-Alive")
+			writeStandard(w, rec.status, rec.header, body)
+			return
+		}
+
+		conn, brw, err := hijacker.Hijack()
+		if err != nil {
+			logging.Errors.Println(err)
+			writeStandard(w, rec.status, rec.header, body)
+			return
+		}
+
+		// The next request has already been sent by a pipelining client, and is lost with the buffer
+		if keepAlive && brw.Reader.Buffered() > 0 {
+			keepAlive = false
+			rec.header.Del("Keep-Alive")
+			rec.header.Set("Connection", "close")
+		}
+
+		// Bypass the rewriting of net/http's own error responses
+		out := conn
+		if pc, ok := conn.(*personaConn); ok {
+			out = pc.Conn
+		}
+
+		if err := p.write(out, rec.status, rec.header, body); err != nil {
+			logging.Errors.Println(err)
+			keepAlive = false
+		}
+
+		if !keepAlive {
+			_ = conn.Close()
+			return
+		}
+
+		keepAliveListener.release(conn)
+	})
+}
+
+// drainBody discards the unread part of the request body, and returns false if it is too large to be discarded
+func drainBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return true
+	}
+
+	n, err := io.Copy(ioutil.Discard, io.LimitReader(r.Body, maxDrainedBody+1))
+	return err == nil && n <= maxDrainedBody
+}
+
+// writeStandard sends the response through the http.ResponseWriter
+func writeStandard(w http.ResponseWriter, status int, header http.Header, body []byte) {
+	for key, vals := range header {
+		w.Header()[key] = vals
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// MethodsHandler answers the OPTIONS and TRACE requests, along with the ones using a method the persona does not allow,
+// without calling the wrapped handler. It should wrap the handler serving the files, so that the responses defined by
+// the rules take precedence
+func (p *Persona) MethodsHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodOptions && p.Options.Status != 0:
+			p.answerMethod(w, r, p.Options)
+			return
+		case r.Method == http.MethodTrace && p.Trace.Status != 0:
+			p.answerMethod(w, r, p.Trace)
+			return
+		}
+
+		if len(p.AllowedMethods) > 0 && !p.AllowedMethods[r.Method] {
+			if p.Allow != "" {
+				w.Header().Set("Allow", p.Allow)
+			}
+			w.WriteHeader(p.DisallowedMethodStatus)
+			return
+		}
+
+		h.ServeHTTP(w, r) // pass request
+	})
+}
+
+func (p *Persona) answerMethod(w http.ResponseWriter, r *http.Request, resp MethodResponse) {
+	if p.Allow != "" && (resp.Status == http.StatusOK || resp.Status == http.StatusMethodNotAllowed) {
+		w.Header().Set("Allow", p.Allow)
+	}
+
+	for header, val := range resp.Headers {
+		w.Header().Set(header, val)
+	}
+
+	if !resp.Echo || resp.Status != http.StatusOK {
+		w.WriteHeader(resp.Status)
+		return
+	}
+
+	dump, err := httputil.DumpRequest(r, true)
+	if err != nil {
+		logging.Errors.Println(err)
+	}
+
+	w.Header().Set("Content-Type", "message/http")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(dump)
+}
+
+// Listener wraps a net.Listener so that the error responses written by net/http itself, such as the ones sent back
+// to malformed requests, are replaced by the persona's. It can't be used below a TLS listener
+func (p *Persona) Listener(l net.Listener) net.Listener {
+	return &personaListener{Listener: l, persona: p}
+}
+
+type personaListener struct {
+	net.Listener
+	persona *Persona
+}
+
+func (l *personaListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &personaConn{Conn: conn, persona: l.persona}, nil
+}
+
+type personaConn struct {
+	net.Conn
+	persona *Persona
+}
+
+func (c *personaConn) Write(b []byte) (int, error) {
+	status, ok := internalErrorStatus(b)
+	if !ok {
+		return c.Conn.Write(b)
+	}
+
+	header := make(http.Header)
+	header.Set("Connection", "close")
+
+	body := c.persona.finalize(nil, c.LocalAddr(), status, header, nil)
+	if err := c.persona.write(c.Conn, status, header, body); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// internalErrorStatus returns the status code of an error response written by net/http itself
+func internalErrorStatus(b []byte) (int, bool) {
+	const prefix = "HTTP/1.1 "
+
+	if !bytes.HasPrefix(b, []byte(prefix)) {
+		return 0, false
+	}
+
+	// The headers directly follow the status line
+	idx := bytes.Index(b, []byte(internalErrorHeaders))
+	if idx < 0 || bytes.Contains(b[:idx], []byte("\r\n")) || idx < len(prefix)+3 {
+		return 0, false
+	}
+
+	status := 0
+	for _, c := range b[len(prefix) : len(prefix)+3] {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		status = status*10 + int(c-'0')
+	}
+
+	return status, true
+}
@@ -0,0 +1,97 @@
+package persona
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// errListenerClosed is returned by the Accept calls of a closed KeepAliveListener
+var errListenerClosed = errors.New("use of closed network connection")
+
+// keepAliveContextKey is used to pass the KeepAliveListener of a connection to the Handler
+type keepAliveContextKey struct{}
+
+// KeepAliveListener wraps a net.Listener so that the connections hijacked by the Handler to write its responses are
+// served again by the HTTP server, as the real web servers keep them alive. It must be the outermost listener, and the
+// server must use its ConnContext method
+type KeepAliveListener struct {
+	net.Listener
+
+	released chan net.Conn
+	accepted chan acceptResult
+	done     chan struct{}
+	start    sync.Once
+	stop     sync.Once
+}
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+// NewKeepAliveListener creates a KeepAliveListener
+func NewKeepAliveListener(l net.Listener) *KeepAliveListener {
+	return &KeepAliveListener{
+		Listener: l,
+		released: make(chan net.Conn),
+		accepted: make(chan acceptResult),
+		done:     make(chan struct{}),
+	}
+}
+
+// Accept returns the next connection, either a new one or one released by the Handler. It fails once the listener is
+// closed
+func (l *KeepAliveListener) Accept() (net.Conn, error) {
+	l.start.Do(func() {
+		go l.acceptLoop()
+	})
+
+	select {
+	case conn := <-l.released:
+		return conn, nil
+	case res := <-l.accepted:
+		return res.conn, res.err
+	case <-l.done:
+		return nil, &net.OpError{Op: "accept", Net: l.Addr().Network(), Addr: l.Addr(), Err: errListenerClosed}
+	}
+}
+
+func (l *KeepAliveListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+
+		select {
+		case l.accepted <- acceptResult{conn, err}:
+		case <-l.done:
+			if conn != nil {
+				_ = conn.Close()
+			}
+			return
+		}
+	}
+}
+
+// Close closes the wrapped listener. The connections released afterwards are closed
+func (l *KeepAliveListener) Close() error {
+	l.stop.Do(func() {
+		close(l.done)
+	})
+
+	return l.Listener.Close()
+}
+
+// ConnContext attaches the listener to the context of its connections, to be used as the http.Server's ConnContext
+func (l *KeepAliveListener) ConnContext(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, keepAliveContextKey{}, l)
+}
+
+// release hands a hijacked connection back to the server, or closes it if the listener is closed
+func (l *KeepAliveListener) release(conn net.Conn) {
+	select {
+	case l.released <- conn:
+	case <-l.done:
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,279 @@
+package persona
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bonjourmalware/melody/internal/replay"
+	"github.com/bonjourmalware/melody/internal/templating"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultPageKey is the key of the error page used for the error status codes without a dedicated page
+	DefaultPageKey = "default"
+)
+
+// RawPersona describes a persona, as written in the persona files
+type RawPersona struct {
+	Name                   string            `yaml:"name,omitempty"`
+	Server                 string            `yaml:"server,omitempty"`
+	HeaderOrder            []string          `yaml:"header_order,omitempty"`
+	Headers                map[string]string `yaml:"headers,omitempty"`
+	KeepAlive              map[string]string `yaml:"keep_alive,omitempty"`
+	DateFormat             string            `yaml:"date_format,omitempty"`
+	Reasons                map[int]string    `yaml:"reasons,omitempty"`
+	AbsoluteRedirects      bool              `yaml:"absolute_redirects,omitempty"`
+	AllowedMethods         []string          `yaml:"allowed_methods,omitempty"`
+	Allow                  string            `yaml:"allow,omitempty"`
+	DisallowedMethodStatus int               `yaml:"disallowed_method_status,omitempty"`
+	Options                MethodResponse    `yaml:"options,omitempty"`
+	Trace                  MethodResponse    `yaml:"trace,omitempty"`
+	ErrorContentType       string            `yaml:"error_content_type,omitempty"`
+	ErrorPages             map[string]string `yaml:"error_pages,omitempty"`
+	Replay                 string            `yaml:"replay,omitempty"`
+	ReplayFallback         string            `yaml:"replay_fallback,omitempty"`
+}
+
+// MethodResponse describes how a persona answers the OPTIONS or TRACE requests. A zero status lets the request go
+// through the usual handlers
+type MethodResponse struct {
+	Status  int               `yaml:"status,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Echo sends the request back as a message/http body, as a server accepting TRACE does
+	Echo bool `yaml:"echo,omitempty"`
+}
+
+// Persona describes how the dummy HTTP/S servers mimic a real web server : its headers' order and casing, date format,
+// error pages, redirections and handling of the OPTIONS and TRACE methods. The KeepAlive headers are added to the
+// responses sent on the connections kept alive
+type Persona struct {
+	Name                   string
+	Server                 string
+	HeaderOrder            []string
+	Headers                map[string]string
+	KeepAlive              map[string]string
+	DateFormat             string
+	Reasons                map[int]string
+	AbsoluteRedirects      bool
+	AllowedMethods         map[string]bool
+	Allow                  string
+	DisallowedMethodStatus int
+	Options                MethodResponse
+	Trace                  MethodResponse
+	ErrorContentType       string
+
+	// Replay holds the exchanges recorded from a real application, served instead of the files of the server's
+	// directory. The requests without a matching exchange are answered with the one of ReplayFallback if set
+	Replay         *replay.Recording
+	ReplayFallback string
+
+	errorPages  map[int]*template.Template
+	defaultPage *template.Template
+}
+
+// Parse creates a Persona from a RawPersona
+func (raw RawPersona) Parse() (*Persona, error) {
+	if raw.Name == "" {
+		return nil, fmt.Errorf("missing 'name' key")
+	}
+
+	p := &Persona{
+		Name:                   strings.ToLower(raw.Name),
+		Server:                 raw.Server,
+		HeaderOrder:            raw.HeaderOrder,
+		Headers:                raw.Headers,
+		KeepAlive:              raw.KeepAlive,
+		DateFormat:             raw.DateFormat,
+		Reasons:                raw.Reasons,
+		AbsoluteRedirects:      raw.AbsoluteRedirects,
+		AllowedMethods:         make(map[string]bool),
+		Allow:                  raw.Allow,
+		DisallowedMethodStatus: raw.DisallowedMethodStatus,
+		Options:                raw.Options,
+		Trace:                  raw.Trace,
+		ErrorContentType:       raw.ErrorContentType,
+		ReplayFallback:         raw.ReplayFallback,
+		errorPages:             make(map[int]*template.Template),
+	}
+
+	if p.DateFormat == "" {
+		p.DateFormat = http.TimeFormat
+	}
+
+	if p.DisallowedMethodStatus == 0 {
+		p.DisallowedMethodStatus = http.StatusMethodNotAllowed
+	}
+
+	if p.ErrorContentType == "" {
+		p.ErrorContentType = "text/html"
+	}
+
+	for _, method := range raw.AllowedMethods {
+		p.AllowedMethods[strings.ToUpper(method)] = true
+	}
+
+	for code := range raw.Reasons {
+		if http.StatusText(code) == "" {
+			return nil, fmt.Errorf("'%d' is not a valid HTTP code status", code)
+		}
+	}
+
+	for _, status := range []int{p.DisallowedMethodStatus, p.Options.Status, p.Trace.Status} {
+		if status != 0 && http.StatusText(status) == "" {
+			return nil, fmt.Errorf("'%d' is not a valid HTTP code status", status)
+		}
+	}
+
+	for key, text := range raw.ErrorPages {
+		tmpl, err := templating.Parse(key, text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid error page '%s' : %s", key, err)
+		}
+
+		if key == DefaultPageKey {
+			p.defaultPage = tmpl
+			continue
+		}
+
+		code, err := strconv.Atoi(key)
+		if err != nil || http.StatusText(code) == "" {
+			return nil, fmt.Errorf("invalid error page '%s' : the key must be a HTTP code status or '%s'", key, DefaultPageKey)
+		}
+
+		p.errorPages[code] = tmpl
+	}
+
+	return p, nil
+}
+
+// Load returns the built-in personas along with the custom ones defined in the YAML files of the given directory. A
+// custom persona overrides the built-in one with the same name. A missing directory is not an error
+func Load(dir string) (map[string]*Persona, error) {
+	personas := make(map[string]*Persona)
+
+	for _, raw := range builtins {
+		p, err := raw.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse built-in persona '%s' : %s", raw.Name, err)
+		}
+		personas[p.Name] = p
+	}
+
+	if dir == "" {
+		return personas, nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return personas, nil
+		}
+		return nil, err
+	}
+
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if file.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		p, err := LoadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		personas[p.Name] = p
+	}
+
+	return personas, nil
+}
+
+// LoadFile parses the persona defined in the given YAML file. The path of its recording is relative to the file's
+// directory
+func LoadFile(path string) (*Persona, error) {
+	var raw RawPersona
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse persona file '%s' : %s", path, err)
+	}
+
+	p, err := raw.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse persona file '%s' : %s", path, err)
+	}
+
+	if raw.Replay != "" {
+		replayDir := raw.Replay
+		if !filepath.IsAbs(replayDir) {
+			replayDir = filepath.Join(filepath.Dir(path), replayDir)
+		}
+
+		p.Replay, err = replay.Load(replayDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse persona file '%s' : %s", path, err)
+		}
+	}
+
+	return p, nil
+}
+
+// Get returns the persona with the given name, either built-in or loaded from the given directory
+func Get(name string, dir string) (*Persona, error) {
+	personas, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := personas[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown persona '%s'", name)
+	}
+
+	return p, nil
+}
+
+// Builtin returns the definition of the built-in persona with the given name
+func Builtin(name string) (RawPersona, bool) {
+	for _, raw := range builtins {
+		if raw.Name == strings.ToLower(name) {
+			return raw, true
+		}
+	}
+
+	return RawPersona{}, false
+}
+
+// reason returns the reason phrase sent along the given status code
+func (p *Persona) reason(status int) string {
+	if reason, ok := p.Reasons[status]; ok {
+		return reason
+	}
+
+	return http.StatusText(status)
+}
+
+// errorPage returns the template of the page sent for the given status code, or nil if the persona has none. The
+// default page is only used for the 4xx and 5xx codes
+func (p *Persona) errorPage(status int) *template.Template {
+	if tmpl, ok := p.errorPages[status]; ok {
+		return tmpl
+	}
+
+	if status >= http.StatusBadRequest {
+		return p.defaultPage
+	}
+
+	return nil
+}
@@ -0,0 +1,307 @@
+package persona
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/replay"
+)
+
+// rawRequest sends the raw request to the server and returns the raw response
+func rawRequest(t *testing.T, srv *httptest.Server, request string) string {
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprint(conn, request); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := ioutil.ReadAll(bufio.NewReader(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(resp)
+}
+
+func newTestServer(t *testing.T, name string) *httptest.Server {
+	p, err := Get(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/found", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Etag", `"abc"`)
+		_, _ = w.Write([]byte("found"))
+	})
+	mux.HandleFunc("/dir", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "dir/")
+		w.WriteHeader(http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	srv := httptest.NewUnstartedServer(p.Handler(p.MethodsHandler(mux)))
+	srv.Listener = p.Listener(srv.Listener)
+	srv.Start()
+
+	return srv
+}
+
+func TestBuiltins(t *testing.T) {
+	personas, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"apache", "nginx", "iis", "lighttpd"} {
+		if _, ok := personas[name]; !ok {
+			t.Errorf("missing built-in persona '%s'", name)
+		}
+	}
+
+	if _, err := Get("unknown", ""); err == nil {
+		t.Error("expected an error for an unknown persona")
+	}
+}
+
+func TestHeaderOrder(t *testing.T) {
+	srv := newTestServer(t, "apache")
+	defer srv.Close()
+
+	resp := rawRequest(t, srv, "GET /found HTTP/1.1\r\nHost: www.example.com\r\n\r\n")
+
+	if !strings.HasPrefix(resp, "HTTP/1.1 200 OK\r\nDate: ") {
+		t.Fatalf("unexpected response start : %q", resp)
+	}
+
+	server := strings.Index(resp, "\r\nServer: Apache/2.4.41 (Ubuntu)\r\n")
+	etag := strings.Index(resp, "\r\nETag: \"abc\"\r\n")
+	length := strings.Index(resp, "\r\nContent-Length: 5\r\n")
+	ctype := strings.Index(resp, "\r\nContent-Type: text/plain\r\n")
+
+	if server < 0 || etag < 0 || length < 0 || ctype < 0 {
+		t.Fatalf("missing headers : %q", resp)
+	}
+
+	if !(server < etag && etag < length && length < ctype) {
+		t.Errorf("unexpected header order : %q", resp)
+	}
+
+	if !strings.HasSuffix(resp, "\r\n\r\nfound") {
+		t.Errorf("unexpected body : %q", resp)
+	}
+}
+
+func TestErrorPages(t *testing.T) {
+	srv := newTestServer(t, "apache")
+	defer srv.Close()
+
+	resp := rawRequest(t, srv, "GET /missing HTTP/1.1\r\nHost: www.example.com\r\n\r\n")
+	if !strings.HasPrefix(resp, "HTTP/1.1 404 Not Found\r\n") ||
+		!strings.Contains(resp, "Content-Type: text/html; charset=iso-8859-1\r\n") ||
+		!strings.Contains(resp, "<address>Apache/2.4.41 (Ubuntu) Server at www.example.com Port 80</address>") {
+		t.Errorf("unexpected 404 response : %q", resp)
+	}
+
+	if strings.Contains(resp, "404 page not found") {
+		t.Errorf("the net/http error message was not replaced : %q", resp)
+	}
+
+	nginx := newTestServer(t, "nginx")
+	defer nginx.Close()
+
+	resp = rawRequest(t, nginx, "DELETE /found HTTP/1.1\r\nHost: www.example.com\r\n\r\n")
+	if !strings.HasPrefix(resp, "HTTP/1.1 405 Not Allowed\r\nServer: nginx/1.18.0 (Ubuntu)\r\nDate: ") ||
+		!strings.Contains(resp, "<center><h1>405 Not Allowed</h1></center>") {
+		t.Errorf("unexpected 405 response : %q", resp)
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	srv := newTestServer(t, "apache")
+	defer srv.Close()
+
+	resp := rawRequest(t, srv, "GET /dir HTTP/1.1\r\nHost: www.example.com\r\n\r\n")
+	if !strings.HasPrefix(resp, "HTTP/1.1 301 Moved Permanently\r\n") ||
+		!strings.Contains(resp, "\r\nLocation: http://www.example.com/dir/\r\n") ||
+		!strings.Contains(resp, `The document has moved <a href="http://www.example.com/dir/">here</a>.`) {
+		t.Errorf("unexpected redirection : %q", resp)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	srv := newTestServer(t, "iis")
+	defer srv.Close()
+
+	resp := rawRequest(t, srv, "OPTIONS / HTTP/1.1\r\nHost: www.example.com\r\n\r\n")
+	if !strings.HasPrefix(resp, "HTTP/1.1 200 OK\r\nAllow: OPTIONS, TRACE, GET, HEAD, POST\r\nServer: Microsoft-IIS/10.0\r\nPublic: ") {
+		t.Errorf("unexpected OPTIONS response : %q", resp)
+	}
+
+	resp = rawRequest(t, srv, "TRACE / HTTP/1.1\r\nHost: www.example.com\r\n\r\n")
+	if !strings.HasPrefix(resp, "HTTP/1.1 501 Not Implemented\r\n") {
+		t.Errorf("unexpected TRACE response : %q", resp)
+	}
+}
+
+func TestBadRequest(t *testing.T) {
+	srv := newTestServer(t, "apache")
+	defer srv.Close()
+
+	resp := rawRequest(t, srv, "GET / HTTP/1.1\r\nHost: www.example.com\r\nBroken header\r\n\r\n")
+	if !strings.HasPrefix(resp, "HTTP/1.1 400 Bad Request\r\nDate: ") ||
+		!strings.Contains(resp, "Your browser sent a request that this server could not understand.") {
+		t.Errorf("unexpected bad request response : %q", resp)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	p, err := Get("apache", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(p.Handler(http.NotFoundHandler()))
+	kl := NewKeepAliveListener(p.Listener(srv.Listener))
+	srv.Listener = kl
+	srv.Config.ConnContext = kl.ConnContext
+	srv.Start()
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	br := bufio.NewReader(conn)
+	for idx, connection := range []string{"keep-alive", "close"} {
+		if _, err := fmt.Fprintf(conn, "POST / HTTP/1.1\r\nHost: www.example.com\r\nContent-Length: 4\r\nConnection: %s\r\n\r\ndata", connection); err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("failed to read the response %d : %v", idx, err)
+		}
+		_, _ = ioutil.ReadAll(resp.Body)
+
+		if connection == "keep-alive" && (resp.Close || resp.Header.Get("Keep-Alive") != "timeout=5, max=100") {
+			t.Errorf("unexpected headers of a kept alive connection : %v", resp.Header)
+		}
+
+		if connection == "close" && (!resp.Close || resp.Header.Get("Keep-Alive") != "") {
+			t.Errorf("unexpected headers of a closed connection : %v", resp.Header)
+		}
+	}
+
+	if _, err := br.ReadByte(); err == nil {
+		t.Error("expected the connection to be closed")
+	}
+}
+
+func TestKeepAliveListenerClose(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kl := NewKeepAliveListener(ln)
+	accepted := make(chan error, 1)
+	go func() {
+		_, err := kl.Accept()
+		accepted <- err
+	}()
+
+	_ = kl.Close()
+
+	select {
+	case err := <-accepted:
+		if err == nil {
+			t.Error("expected an error once the listener is closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Accept is still blocked after Close")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "melody-personas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	custom := `name: Custom
+server: "CustomServer/1.0"
+header_order: ["server", "date"]
+error_pages:
+  default: "{{.Status}} from {{.Server}}"
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "custom.yml"), []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Get("custom", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Server != "CustomServer/1.0" || p.errorPage(http.StatusNotFound) == nil {
+		t.Errorf("unexpected persona %+v", p)
+	}
+
+	rec := &replay.Recording{}
+	ex, err := replay.NewExchange("GET", "/login.html", http.StatusOK, [][2]string{{"Content-Type", "text/html"}}, []byte("login"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Add(ex)
+
+	if err := rec.Save(filepath.Join(dir, "router")); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := "name: router\nreplay: router\nreplay_fallback: /login.html\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "router.yml"), []byte(recorded), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = Get("router", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Replay == nil {
+		t.Fatal("the recording was not loaded")
+	}
+
+	if match := p.Replay.Match(httptest.NewRequest("GET", "/missing", nil), p.ReplayFallback); match == nil || string(match.Body()) != "login" {
+		t.Error("expected the recorded login page to be served for the missing pages")
+	}
+
+	invalid := "name: invalid\nerror_pages:\n  notacode: \"\"\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "invalid.yml"), []byte(invalid), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(dir); err == nil {
+		t.Error("expected an error for an invalid error page key")
+	}
+}
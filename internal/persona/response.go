@@ -0,0 +1,254 @@
+package persona
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/logging"
+)
+
+// headerNewlineToSpace prevents the header values from splitting the response, as net/http does
+var headerNewlineToSpace = strings.NewReplacer("\n", " ", "\r", " ")
+
+// PageData is the data made available to the error pages. The values coming from the request are HTML escaped
+type PageData struct {
+	Status   int
+	Reason   string
+	Method   string
+	Path     string
+	Location string
+	Server   string
+	Host     string
+	Port     string
+}
+
+// recorder buffers the response of the wrapped handlers so that the persona can rewrite it
+type recorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: make(http.Header)}
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+
+	rec.status = status
+	rec.wroteHeader = true
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+
+	return rec.body.Write(b)
+}
+
+// isGenerated checks if the body is empty or is one of the plain text error messages written by net/http, that the
+// persona should replace by its own page
+func isGenerated(header http.Header, body []byte) bool {
+	if len(body) == 0 {
+		return true
+	}
+
+	return header.Get("X-Content-Type-Options") == "nosniff" &&
+		strings.HasPrefix(header.Get("Content-Type"), "text/plain")
+}
+
+// newPageData creates the error page data from the request. The host and port come from the Host header, as the
+// listening port might not be the one the client connected to. The local address is used as a fallback for the host
+func newPageData(status int, reason string, r *http.Request, localAddr net.Addr) PageData {
+	data := PageData{
+		Status: status,
+		Reason: reason,
+		Port:   "80",
+	}
+
+	if localAddr != nil {
+		data.Host, _, _ = net.SplitHostPort(localAddr.String())
+	}
+
+	if r == nil {
+		return data
+	}
+
+	data.Method = html.EscapeString(r.Method)
+	data.Path = html.EscapeString(r.URL.Path)
+
+	if r.TLS != nil {
+		data.Port = "443"
+	}
+
+	if r.Host != "" {
+		host, port, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		} else {
+			data.Port = html.EscapeString(port)
+		}
+		data.Host = html.EscapeString(host)
+	}
+
+	return data
+}
+
+// renderPage renders the error page of the given status code, if the persona has one
+func (p *Persona) renderPage(data PageData) ([]byte, bool) {
+	tmpl := p.errorPage(data.Status)
+	if tmpl == nil {
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		logging.Errors.Printf("failed to render the %d page of persona '%s' : %s", data.Status, p.Name, err)
+	}
+
+	return buf.Bytes(), true
+}
+
+// finalize rewrites the response as the persona would send it : error pages, absolute redirections and default
+// headers. The request is nil if net/http failed to read it. It returns the body to send
+func (p *Persona) finalize(r *http.Request, localAddr net.Addr, status int, header http.Header, body []byte) []byte {
+	if location := header.Get("Location"); location != "" && p.AbsoluteRedirects && r != nil {
+		header.Set("Location", absoluteLocation(r, location))
+	}
+
+	if status >= http.StatusMultipleChoices && isGenerated(header, body) {
+		data := newPageData(status, p.reason(status), r, localAddr)
+		data.Location = html.EscapeString(header.Get("Location"))
+		data.Server = html.EscapeString(header.Get("Server"))
+		if data.Server == "" {
+			data.Server = html.EscapeString(p.Server)
+		}
+
+		if page, ok := p.renderPage(data); ok {
+			body = page
+			header.Del("X-Content-Type-Options")
+			header.Set("Content-Type", p.ErrorContentType)
+		}
+	}
+
+	for name, val := range p.Headers {
+		if header.Get(name) == "" {
+			header.Set(name, val)
+		}
+	}
+
+	if header.Get("Server") == "" && p.Server != "" {
+		header.Set("Server", p.Server)
+	}
+
+	if header.Get("Date") == "" {
+		header.Set("Date", time.Now().UTC().Format(p.DateFormat))
+	}
+
+	if !bodyAllowed(status) {
+		header.Del("Content-Length")
+		header.Del("Content-Type")
+		return nil
+	}
+
+	if len(body) > 0 && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", http.DetectContentType(body))
+	}
+
+	if r != nil && r.Method == http.MethodHead {
+		if header.Get("Content-Length") == "" {
+			header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		return nil
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return body
+}
+
+// bodyAllowed checks if a response with the given status code can have a body
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// absoluteLocation resolves the location of a redirection against the requested URL, as most web servers send
+// absolute URLs
+func absoluteLocation(r *http.Request, location string) string {
+	u, err := r.URL.Parse(location)
+	if err != nil {
+		return location
+	}
+
+	if u.Host == "" {
+		u.Host = r.Host
+	}
+
+	if u.Scheme == "" {
+		u.Scheme = "http"
+		if r.TLS != nil {
+			u.Scheme = "https"
+		}
+	}
+
+	return u.String()
+}
+
+// write sends the raw response, with the headers in the persona's order and casing. The headers missing from the
+// order are sent afterwards, sorted by name
+func (p *Persona) write(w io.Writer, status int, header http.Header, body []byte) error {
+	var buf bytes.Buffer
+
+	_, _ = fmt.Fprintf(&buf, "HTTP/1.1 %d %s\r\n", status, p.reason(status))
+
+	written := make(map[string]bool)
+	for _, name := range p.HeaderOrder {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		if written[key] {
+			continue
+		}
+		written[key] = true
+
+		for _, val := range header[key] {
+			_, _ = fmt.Fprintf(&buf, "%s: %s\r\n", name, headerNewlineToSpace.Replace(val))
+		}
+	}
+
+	var remaining []string
+	for key := range header {
+		if !written[textproto.CanonicalMIMEHeaderKey(key)] {
+			remaining = append(remaining, key)
+		}
+	}
+	sort.Strings(remaining)
+
+	for _, key := range remaining {
+		for _, val := range header[key] {
+			_, _ = fmt.Fprintf(&buf, "%s: %s\r\n", key, headerNewlineToSpace.Replace(val))
+		}
+	}
+
+	buf.WriteString("\r\n")
+	buf.Write(body)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
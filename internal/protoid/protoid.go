@@ -0,0 +1,418 @@
+package protoid
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/bonjourmalware/melody/internal/quicparser"
+	"github.com/bonjourmalware/melody/internal/snmpparser"
+)
+
+const (
+	// TCP is the transport used to select the TCP signatures
+	TCP = "tcp"
+	// UDP is the transport used to select the UDP signatures
+	UDP = "udp"
+
+	// Unknown is the label given to flows that did not match any signature
+	Unknown = "unknown"
+)
+
+// signature describes how to recognize an application protocol from the first bytes sent by a client
+type signature struct {
+	name  string
+	match func(payload []byte) bool
+}
+
+var (
+	httpMethods = [][]byte{
+		[]byte("GET "), []byte("POST "), []byte("HEAD "), []byte("PUT "), []byte("DELETE "),
+		[]byte("OPTIONS "), []byte("CONNECT "), []byte("TRACE "), []byte("PATCH "),
+		[]byte("PROPFIND "), []byte("PROPPATCH "), []byte("MKCOL "), []byte("COPY "), []byte("MOVE "),
+		[]byte("LOCK "), []byte("UNLOCK "), []byte("SEARCH "),
+	}
+
+	sipMethods = [][]byte{
+		[]byte("INVITE sip:"), []byte("REGISTER sip:"), []byte("OPTIONS sip:"), []byte("ACK sip:"),
+		[]byte("BYE sip:"), []byte("CANCEL sip:"), []byte("SUBSCRIBE sip:"), []byte("NOTIFY sip:"),
+		[]byte("MESSAGE sip:"), []byte("INFO sip:"),
+	}
+
+	redisInlineCommands = [][]byte{
+		[]byte("PING"), []byte("INFO"), []byte("CONFIG "), []byte("AUTH "), []byte("KEYS "),
+		[]byte("SLAVEOF "), []byte("REPLICAOF "), []byte("FLUSHALL"), []byte("MODULE "),
+	}
+
+	memcachedCommands = [][]byte{
+		[]byte("stats"), []byte("get "), []byte("gets "), []byte("set "), []byte("add "),
+		[]byte("version"), []byte("flush_all"),
+	}
+
+	smtpCommands = [][]byte{
+		[]byte("EHLO "), []byte("HELO "), []byte("ehlo "), []byte("helo "),
+	}
+
+	// The order matters, as the first matching signature wins
+	tcpSignatures = []signature{
+		{"http2", isHTTP2},
+		{"rtsp", isRTSP},
+		{"sip", isSIP},
+		{"http", isHTTP},
+		{"tls", isTLS},
+		{"ssh", isSSH},
+		{"smb", isSMB},
+		{"rdp", isRDP},
+		{"redis", isRedis},
+		{"smtp", isSMTP},
+		{"imap", isIMAP},
+		{"telnet", isTelnet},
+		{"vnc", isVNC},
+		{"mqtt", isMQTT},
+		{"postgresql", isPostgreSQL},
+		{"mssql", isMSSQL},
+		{"mongodb", isMongoDB},
+		{"rmi", isRMI},
+		{"amqp", isAMQP},
+		{"bitcoin", isBitcoin},
+		{"netbios", isNetBIOSSession},
+		{"memcached", isMemcachedText},
+	}
+
+	udpSignatures = []signature{
+		{"quic", isQUIC},
+		{"dtls", isDTLS},
+		{"ssdp", isSSDP},
+		{"sip", isSIP},
+		{"snmp", isSNMP},
+		{"memcached", isMemcachedUDP},
+		{"ntp", isNTP},
+		{"tftp", isTFTP},
+		{"ike", isIKE},
+		{"openvpn", isOpenVPN},
+		{"ubiquiti", isUbiquiti},
+		{"ws-discovery", isWSDiscovery},
+		{"dns", isDNS},
+	}
+)
+
+// Identify returns the name of the application protocol of the given payload, or Unknown if no signature matched.
+// The payload is expected to be the first data sent by the client in a TCP stream or an UDP flow
+func Identify(payload []byte, transport string) string {
+	var sigs []signature
+
+	switch transport {
+	case TCP:
+		sigs = tcpSignatures
+	case UDP:
+		sigs = udpSignatures
+	default:
+		return Unknown
+	}
+
+	if len(payload) == 0 {
+		return Unknown
+	}
+
+	for _, sig := range sigs {
+		if sig.match(payload) {
+			return sig.name
+		}
+	}
+
+	return Unknown
+}
+
+func hasAnyPrefix(payload []byte, prefixes [][]byte) bool {
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(payload, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firstLine returns the payload up to the first CRLF or LF
+func firstLine(payload []byte) []byte {
+	if idx := bytes.IndexByte(payload, '\n'); idx >= 0 {
+		return bytes.TrimRight(payload[:idx], "\r")
+	}
+
+	return payload
+}
+
+func isHTTP(payload []byte) bool {
+	return hasAnyPrefix(payload, httpMethods)
+}
+
+func isHTTP2(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte("PRI * HTTP/2.0"))
+}
+
+func isRTSP(payload []byte) bool {
+	line := firstLine(payload)
+	return bytes.HasSuffix(line, []byte(" RTSP/1.0")) || bytes.HasSuffix(line, []byte(" RTSP/2.0"))
+}
+
+func isSIP(payload []byte) bool {
+	return hasAnyPrefix(payload, sipMethods) || bytes.HasSuffix(firstLine(payload), []byte(" SIP/2.0"))
+}
+
+func isSSDP(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte("M-SEARCH * HTTP/1.1")) || bytes.HasPrefix(payload, []byte("NOTIFY * HTTP/1.1"))
+}
+
+// isTLS matches TLS records of type handshake, as well as SSLv2 compatible ClientHello
+func isTLS(payload []byte) bool {
+	if len(payload) >= 3 && payload[0] == 0x16 && payload[1] == 0x03 && payload[2] <= 0x04 {
+		return true
+	}
+
+	if len(payload) < 5 || payload[0]&0x80 == 0 || payload[2] != 0x01 || payload[3] > 0x03 {
+		return false
+	}
+
+	return (int(payload[0]&0x7f)<<8|int(payload[1]))+2 == len(payload)
+}
+
+func isDTLS(payload []byte) bool {
+	return len(payload) >= 13 && payload[0] == 0x16 && payload[1] == 0xfe && (payload[2] == 0xff || payload[2] == 0xfd)
+}
+
+func isSSH(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte("SSH-"))
+}
+
+// isSMB matches SMB1 and SMB2 messages carried over a NetBIOS session service header
+func isSMB(payload []byte) bool {
+	if len(payload) < 8 || payload[0] != 0x00 {
+		return false
+	}
+
+	return bytes.Equal(payload[4:8], []byte("\xffSMB")) || bytes.Equal(payload[4:8], []byte("\xfeSMB"))
+}
+
+// isRDP matches a TPKT header followed by an X.224 Connection Request
+func isRDP(payload []byte) bool {
+	if len(payload) < 11 || payload[0] != 0x03 || payload[1] != 0x00 {
+		return false
+	}
+
+	return int(binary.BigEndian.Uint16(payload[2:4])) == len(payload) && payload[5] == 0xe0
+}
+
+func isRedis(payload []byte) bool {
+	// RESP array of bulk strings
+	if len(payload) >= 4 && payload[0] == '*' && payload[1] >= '1' && payload[1] <= '9' {
+		return bytes.Contains(payload, []byte("\r\n$"))
+	}
+
+	return hasAnyPrefix(bytes.ToUpper(firstLine(payload)), redisInlineCommands) && bytes.HasSuffix(payload, []byte("\n"))
+}
+
+func isSMTP(payload []byte) bool {
+	return hasAnyPrefix(payload, smtpCommands)
+}
+
+// isIMAP matches a tagged IMAP command sent before authentication
+func isIMAP(payload []byte) bool {
+	fields := bytes.Fields(firstLine(payload))
+	if len(fields) < 2 {
+		return false
+	}
+
+	switch string(bytes.ToUpper(fields[1])) {
+	case "CAPABILITY", "LOGIN", "STARTTLS", "AUTHENTICATE", "ID":
+		return true
+	}
+
+	return false
+}
+
+// isTelnet matches telnet option negotiation (IAC followed by WILL, WONT, DO or DONT)
+func isTelnet(payload []byte) bool {
+	return len(payload) >= 3 && payload[0] == 0xff && payload[1] >= 0xfb && payload[1] <= 0xfe
+}
+
+func isVNC(payload []byte) bool {
+	return len(payload) == 12 && bytes.HasPrefix(payload, []byte("RFB 00"))
+}
+
+// isMQTT matches a CONNECT control packet
+func isMQTT(payload []byte) bool {
+	if len(payload) < 12 || payload[0] != 0x10 {
+		return false
+	}
+
+	return bytes.Contains(payload[2:12], []byte("MQTT")) || bytes.Contains(payload[2:12], []byte("MQIsdp"))
+}
+
+// isPostgreSQL matches a StartupMessage (protocol 3.0) or an SSLRequest
+func isPostgreSQL(payload []byte) bool {
+	if len(payload) < 8 || int(binary.BigEndian.Uint32(payload[0:4])) != len(payload) {
+		return false
+	}
+
+	code := binary.BigEndian.Uint32(payload[4:8])
+	return code == 0x00030000 || code == 80877103
+}
+
+// isMSSQL matches a TDS PRELOGIN message
+func isMSSQL(payload []byte) bool {
+	if len(payload) < 8 || payload[0] != 0x12 || payload[1] != 0x01 {
+		return false
+	}
+
+	return int(binary.BigEndian.Uint16(payload[2:4])) == len(payload)
+}
+
+// isMongoDB matches a wire protocol header using either OP_QUERY or OP_MSG
+func isMongoDB(payload []byte) bool {
+	if len(payload) < 16 || int(binary.LittleEndian.Uint32(payload[0:4])) != len(payload) {
+		return false
+	}
+
+	opcode := binary.LittleEndian.Uint32(payload[12:16])
+	return opcode == 2004 || opcode == 2013
+}
+
+func isRMI(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte("JRMI"))
+}
+
+func isAMQP(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte("AMQP"))
+}
+
+func isBitcoin(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte{0xf9, 0xbe, 0xb4, 0xd9})
+}
+
+// isNetBIOSSession matches a NetBIOS session request
+func isNetBIOSSession(payload []byte) bool {
+	return len(payload) >= 4 && payload[0] == 0x81 && payload[1] == 0x00 &&
+		int(binary.BigEndian.Uint16(payload[2:4]))+4 == len(payload)
+}
+
+func isMemcachedText(payload []byte) bool {
+	return hasAnyPrefix(payload, memcachedCommands) && bytes.HasSuffix(payload, []byte("\r\n"))
+}
+
+// isMemcachedUDP matches a text command preceded by the 8 bytes UDP frame header
+func isMemcachedUDP(payload []byte) bool {
+	return len(payload) > 8 && payload[4] == 0x00 && payload[6] == 0x00 && payload[7] == 0x00 &&
+		isMemcachedText(payload[8:])
+}
+
+func isQUIC(payload []byte) bool {
+	if !quicparser.IsLongHeader(payload) {
+		return false
+	}
+
+	_, err := quicparser.ParseInitial(payload)
+	return err == nil
+}
+
+func isSNMP(payload []byte) bool {
+	if payload[0] != 0x30 {
+		return false
+	}
+
+	_, err := snmpparser.Parse(payload)
+	return err == nil
+}
+
+// isNTP matches client mode (3) packets as well as private mode (7) requests such as monlist
+func isNTP(payload []byte) bool {
+	mode := payload[0] & 0x07
+	version := (payload[0] >> 3) & 0x07
+
+	if mode == 3 {
+		return len(payload) >= 48 && version >= 1 && version <= 4
+	}
+
+	return mode == 7 && len(payload) >= 8 && version >= 2
+}
+
+// isTFTP matches read and write requests
+func isTFTP(payload []byte) bool {
+	if len(payload) < 4 || payload[0] != 0x00 || (payload[1] != 0x01 && payload[1] != 0x02) {
+		return false
+	}
+
+	parts := bytes.Split(payload[2:], []byte{0x00})
+	if len(parts) < 3 || len(parts[0]) == 0 {
+		return false
+	}
+
+	switch string(bytes.ToLower(parts[1])) {
+	case "octet", "netascii", "mail":
+		return true
+	}
+
+	return false
+}
+
+// isIKE matches an IKE header with an empty responder SPI
+func isIKE(payload []byte) bool {
+	if len(payload) < 28 {
+		return false
+	}
+
+	if !bytes.Equal(payload[8:16], make([]byte, 8)) {
+		return false
+	}
+
+	version := payload[17]
+	return (version == 0x10 || version == 0x20) && int(binary.BigEndian.Uint32(payload[24:28])) == len(payload)
+}
+
+// isOpenVPN matches a P_CONTROL_HARD_RESET_CLIENT_V2 or V3 packet
+func isOpenVPN(payload []byte) bool {
+	return len(payload) >= 14 && (payload[0] == 0x38 || payload[0] == 0x50)
+}
+
+// isUbiquiti matches Ubiquiti discovery probes
+func isUbiquiti(payload []byte) bool {
+	return bytes.Equal(payload, []byte{0x01, 0x00, 0x00, 0x00}) || bytes.Equal(payload, []byte{0x02, 0x08, 0x00, 0x00})
+}
+
+func isWSDiscovery(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte("<?xml")) && bytes.Contains(payload, []byte("discovery"))
+}
+
+// isDNS matches standard queries holding a single valid question
+func isDNS(payload []byte) bool {
+	if len(payload) < 17 {
+		return false
+	}
+
+	// QR bit unset and standard query opcode
+	if payload[2]&0xf8 != 0x00 {
+		return false
+	}
+
+	if binary.BigEndian.Uint16(payload[4:6]) != 1 || binary.BigEndian.Uint16(payload[6:8]) != 0 {
+		return false
+	}
+
+	offset := 12
+	for {
+		if offset >= len(payload) {
+			return false
+		}
+
+		length := int(payload[offset])
+		if length == 0 {
+			break
+		}
+
+		if length > 63 {
+			return false
+		}
+		offset += length + 1
+	}
+
+	// Terminating label, QTYPE and QCLASS
+	return offset+5 <= len(payload)
+}
@@ -0,0 +1,66 @@
+package protoid
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestIdentifyTCP(t *testing.T) {
+	tests := []struct {
+		Payload  []byte
+		Expected string
+	}{
+		{[]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"), "http"},
+		{[]byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"), "http2"},
+		{mustDecodeHex(t, "160301020001"), "tls"},
+		{[]byte("SSH-2.0-libssh_0.9.6\r\n"), "ssh"},
+		{mustDecodeHex(t, "00000054ff534d4272000000"), "smb"},
+		{mustDecodeHex(t, "0300002b26e00000000000436f6f6b69653a206d737473686173683d61646d696e0d0a0100080003000000"), "rdp"},
+		{[]byte("*1\r\n$4\r\nINFO\r\n"), "redis"},
+		{[]byte("EHLO scanner\r\n"), "smtp"},
+		{[]byte("a001 CAPABILITY\r\n"), "imap"},
+		{mustDecodeHex(t, "fffd01fffb1f"), "telnet"},
+		{[]byte("RFB 003.008\n"), "vnc"},
+		{[]byte("OPTIONS rtsp://192.0.2.1:554 RTSP/1.0\r\nCSeq: 1\r\n\r\n"), "rtsp"},
+		{mustDecodeHex(t, "0000000804d2162f"), "postgresql"},
+		{[]byte("\x00\x01\x02garbage"), Unknown},
+	}
+
+	for _, test := range tests {
+		if got := Identify(test.Payload, TCP); got != test.Expected {
+			t.Errorf("expected %s, got %s for %q", test.Expected, got, test.Payload)
+		}
+	}
+}
+
+func TestIdentifyUDP(t *testing.T) {
+	tests := []struct {
+		Payload  []byte
+		Expected string
+	}{
+		// A query for example.com
+		{mustDecodeHex(t, "abcd01000001000000000000076578616d706c6503636f6d0000010001"), "dns"},
+		{append([]byte{0x23}, make([]byte, 47)...), "ntp"},
+		{mustDecodeHex(t, "1700032a00000000"), "ntp"},
+		{[]byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\n\r\n"), "ssdp"},
+		{[]byte("\x00\x01\x00\x00\x00\x01\x00\x00stats\r\n"), "memcached"},
+		{mustDecodeHex(t, "302902010104067075626c6963a01c0204567890ab020100020100300e300c06082b060102010101000500"), "snmp"},
+		{mustDecodeHex(t, "0001666f6f2e62696e006f6374657400"), "tftp"},
+		{[]byte{0x01, 0x00, 0x00, 0x00}, "ubiquiti"},
+		{[]byte("garbage"), Unknown},
+	}
+
+	for _, test := range tests {
+		if got := Identify(test.Payload, UDP); got != test.Expected {
+			t.Errorf("expected %s, got %s for %x", test.Expected, got, test.Payload)
+		}
+	}
+}
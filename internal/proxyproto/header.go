@@ -0,0 +1,295 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// v1MaxLength is the maximum length of a v1 header, including the final CRLF
+	v1MaxLength = 107
+
+	commandLocal = 0x0
+	commandProxy = 0x1
+
+	familyInet  = 0x1
+	familyInet6 = 0x2
+
+	tlvTypeSSL = 0x20
+)
+
+var (
+	v1Signature = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+	// TLVNames maps the TLV types defined by the PROXY protocol specification to the names used in the logs
+	TLVNames = map[byte]string{
+		0x01: "alpn",
+		0x02: "authority",
+		0x03: "crc32c",
+		0x04: "noop",
+		0x05: "unique_id",
+		0x20: "ssl",
+		0x21: "ssl_version",
+		0x22: "ssl_cn",
+		0x23: "ssl_cipher",
+		0x24: "ssl_sig_alg",
+		0x25: "ssl_key_alg",
+		0x30: "netns",
+		0xEA: "aws",
+		0xEE: "azure",
+	}
+)
+
+// Header describes a PROXY protocol header
+type Header struct {
+	Version int
+	// Source and Dest are nil for the connections opened by the proxy itself, such as the health checks
+	Source *net.TCPAddr
+	Dest   *net.TCPAddr
+	TLVs   []TLV
+}
+
+// TLV is a Type-Length-Value vector of a v2 header
+type TLV struct {
+	Type  byte
+	Value []byte
+}
+
+// Read reads the PROXY protocol header at the start of the given reader. It returns a nil header if the data does not
+// start with a v1 or v2 signature
+func Read(r *bufio.Reader) (*Header, error) {
+	version, err := peekVersion(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch version {
+	case 1:
+		return readV1(r)
+	case 2:
+		return readV2(r)
+	}
+
+	return nil, nil
+}
+
+// HasSignature checks if the data of the given reader starts with a v1 or v2 signature, without consuming it
+func HasSignature(r *bufio.Reader) bool {
+	version, _ := peekVersion(r)
+	return version != 0
+}
+
+// peekVersion returns the version of the signature at the start of the given reader, or 0 if there is none
+func peekVersion(r *bufio.Reader) (int, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+
+	// Avoid waiting for more data than sent by the clients writing small requests
+	switch first[0] {
+	case v1Signature[0]:
+		if sig, err := r.Peek(len(v1Signature)); err == nil && bytes.Equal(sig, v1Signature) {
+			return 1, nil
+		}
+	case v2Signature[0]:
+		if sig, err := r.Peek(len(v2Signature)); err == nil && bytes.Equal(sig, v2Signature) {
+			return 2, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func readV1(r *bufio.Reader) (*Header, error) {
+	var line []byte
+
+	for len(line) < v1MaxLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		line = append(line, b)
+		if bytes.HasSuffix(line, []byte("\r\n")) {
+			return parseV1(string(line[:len(line)-2]))
+		}
+	}
+
+	return nil, errors.New("invalid PROXY v1 header : missing CRLF")
+}
+
+func parseV1(line string) (*Header, error) {
+	fields := strings.Split(line, " ")
+	header := &Header{Version: 1}
+
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return header, nil
+	}
+
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("invalid PROXY v1 header : %q", line)
+	}
+
+	var err error
+	if header.Source, err = parseV1Addr(fields[2], fields[4]); err != nil {
+		return nil, err
+	}
+
+	if header.Dest, err = parseV1Addr(fields[3], fields[5]); err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+func parseV1Addr(rawIP string, rawPort string) (*net.TCPAddr, error) {
+	ip := net.ParseIP(rawIP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid PROXY v1 header : invalid address '%s'", rawIP)
+	}
+
+	port, err := strconv.ParseUint(rawPort, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY v1 header : invalid port '%s'", rawPort)
+	}
+
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+func readV2(r *bufio.Reader) (*Header, error) {
+	fixed := make([]byte, len(v2Signature)+4)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+
+	verCmd := fixed[len(v2Signature)]
+	family := fixed[len(v2Signature)+1] >> 4
+	length := binary.BigEndian.Uint16(fixed[len(v2Signature)+2:])
+
+	if verCmd>>4 != 2 {
+		return nil, fmt.Errorf("invalid PROXY v2 header : unsupported version %d", verCmd>>4)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	header := &Header{Version: 2}
+
+	switch verCmd & 0xF {
+	case commandLocal:
+		return header, nil
+	case commandProxy:
+	default:
+		return nil, fmt.Errorf("invalid PROXY v2 header : unsupported command %d", verCmd&0xF)
+	}
+
+	var addrLen int
+	switch family {
+	case familyInet:
+		addrLen = 2*net.IPv4len + 4
+	case familyInet6:
+		addrLen = 2*net.IPv6len + 4
+	default:
+		// Unspecified or UNIX addresses, the connection is handled as if it was not proxied
+		return header, nil
+	}
+
+	if len(payload) < addrLen {
+		return nil, errors.New("invalid PROXY v2 header : truncated addresses")
+	}
+
+	ipLen := (addrLen - 4) / 2
+	header.Source = &net.TCPAddr{
+		IP:   net.IP(payload[:ipLen]),
+		Port: int(binary.BigEndian.Uint16(payload[2*ipLen:])),
+	}
+	header.Dest = &net.TCPAddr{
+		IP:   net.IP(payload[ipLen : 2*ipLen]),
+		Port: int(binary.BigEndian.Uint16(payload[2*ipLen+2:])),
+	}
+
+	tlvs, err := parseTLVs(payload[addrLen:])
+	if err != nil {
+		return nil, err
+	}
+	header.TLVs = tlvs
+
+	return header, nil
+}
+
+func parseTLVs(data []byte) ([]TLV, error) {
+	var tlvs []TLV
+
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return nil, errors.New("invalid PROXY v2 header : truncated TLV")
+		}
+
+		length := int(binary.BigEndian.Uint16(data[1:3]))
+		if len(data) < 3+length {
+			return nil, errors.New("invalid PROXY v2 header : truncated TLV")
+		}
+
+		tlvs = append(tlvs, TLV{Type: data[0], Value: data[3 : 3+length]})
+		data = data[3+length:]
+	}
+
+	return tlvs, nil
+}
+
+// TLVMap returns the TLVs of the header indexed by name, or by hexadecimal type for the unknown ones. The sub-TLVs of
+// the SSL TLV are flattened. Printable values are kept as is, the others are hex-encoded
+func (h *Header) TLVMap() map[string]string {
+	if len(h.TLVs) == 0 {
+		return nil
+	}
+
+	tlvs := make(map[string]string)
+	for _, tlv := range h.TLVs {
+		if tlv.Type == tlvTypeSSL && len(tlv.Value) >= 5 {
+			// The client and verify fields come before the sub-TLVs
+			tlvs[TLVNames[tlvTypeSSL]] = hex.EncodeToString(tlv.Value[:5])
+			if sub, err := parseTLVs(tlv.Value[5:]); err == nil {
+				for _, subTLV := range sub {
+					tlvs[tlvName(subTLV.Type)] = tlvValue(subTLV.Value)
+				}
+			}
+			continue
+		}
+
+		tlvs[tlvName(tlv.Type)] = tlvValue(tlv.Value)
+	}
+
+	return tlvs
+}
+
+func tlvName(tlvType byte) string {
+	if name, ok := TLVNames[tlvType]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("0x%02x", tlvType)
+}
+
+func tlvValue(value []byte) string {
+	for _, r := range string(value) {
+		if r == unicode.ReplacementChar || !unicode.IsPrint(r) {
+			return hex.EncodeToString(value)
+		}
+	}
+
+	return string(value)
+}
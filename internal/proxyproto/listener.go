@@ -0,0 +1,128 @@
+package proxyproto
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// HeaderTimeout is the time given to a trusted proxy to send its header
+	HeaderTimeout = 5 * time.Second
+)
+
+type connContextKey struct{}
+
+// Addr is the address of a client connected through a trusted proxy
+type Addr struct {
+	*net.TCPAddr
+	Proxy  net.Addr
+	Header *Header
+}
+
+// NewListener wraps the given listener to read the PROXY protocol v1 and v2 headers sent by the proxies in the
+// trusted networks. The connections without a header are handled as if they were not proxied, while the headers sent
+// by the other sources are left untouched
+func NewListener(l net.Listener, trusted []*net.IPNet) net.Listener {
+	return &listener{Listener: l, trusted: trusted}
+}
+
+type listener struct {
+	net.Listener
+	trusted []*net.IPNet
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	var trusted bool
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		trusted = IsTrusted(addr.IP, l.trusted)
+	}
+
+	return &Conn{Conn: conn, trusted: trusted}, nil
+}
+
+// IsTrusted checks if the given IP belongs to one of the trusted networks, whose PROXY protocol headers are read
+func IsTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Conn is a connection accepted by a PROXY protocol listener. The header is read on the first call to Read or
+// RemoteAddr, so that a slow proxy does not block the listener
+type Conn struct {
+	net.Conn
+	trusted bool
+	once    sync.Once
+	reader  *bufio.Reader
+	header  *Header
+	err     error
+}
+
+func (c *Conn) readHeader() {
+	c.once.Do(func() {
+		if !c.trusted {
+			return
+		}
+
+		c.reader = bufio.NewReader(c.Conn)
+
+		_ = c.Conn.SetReadDeadline(time.Now().Add(HeaderTimeout))
+		c.header, c.err = Read(c.reader)
+		_ = c.Conn.SetReadDeadline(time.Time{})
+	})
+}
+
+// Read reads the data following the header
+func (c *Conn) Read(b []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	if c.reader == nil {
+		return c.Conn.Read(b)
+	}
+
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the client address sent by the trusted proxy as an *Addr, or the peer address if the
+// connection is not proxied
+func (c *Conn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.header == nil || c.header.Source == nil {
+		return c.Conn.RemoteAddr()
+	}
+
+	return &Addr{TCPAddr: c.header.Source, Proxy: c.Conn.RemoteAddr(), Header: c.header}
+}
+
+// ConnContext stores the connection in the context of its requests. Set it as the ConnContext of the http.Server
+// serving a PROXY protocol listener
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, c)
+}
+
+// ClientAddr returns the address sent by the trusted proxy the request went through, or nil if not proxied
+func ClientAddr(r *http.Request) *Addr {
+	conn, ok := r.Context().Value(connContextKey{}).(net.Conn)
+	if !ok {
+		return nil
+	}
+
+	addr, _ := conn.RemoteAddr().(*Addr)
+	return addr
+}
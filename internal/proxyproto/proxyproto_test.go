@@ -0,0 +1,123 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReadV1(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("PROXY TCP4 203.0.113.7 192.0.2.1 56324 443\r\nGET / HTTP/1.1\r\n\r\n"))
+
+	header, err := Read(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header == nil || header.Version != 1 || header.Source.String() != "203.0.113.7:56324" || header.Dest.String() != "192.0.2.1:443" {
+		t.Fatalf("unexpected header %+v", header)
+	}
+
+	rest, _ := ioutil.ReadAll(r)
+	if string(rest) != "GET / HTTP/1.1\r\n\r\n" {
+		t.Errorf("unexpected data after the header : %q", rest)
+	}
+
+	if _, err := Read(bufio.NewReader(strings.NewReader("PROXY TCP4 nope\r\n"))); err == nil {
+		t.Error("expected an error for an invalid header")
+	}
+}
+
+func TestReadV2(t *testing.T) {
+	var buf bytes.Buffer
+	addrs := []byte{203, 0, 113, 7, 192, 0, 2, 1, 0xDC, 0x04, 0x01, 0xBB}
+	tlvs := []byte{0x02, 0x00, 0x0B}
+	tlvs = append(tlvs, "example.com"...)
+	tlvs = append(tlvs, 0xE0, 0x00, 0x02, 0xFF, 0x00)
+
+	buf.Write(v2Signature)
+	buf.Write([]byte{0x21, 0x11})
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(addrs)+len(tlvs)))
+	buf.Write(addrs)
+	buf.Write(tlvs)
+	buf.WriteString("GET /")
+
+	r := bufio.NewReader(&buf)
+	header, err := Read(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header == nil || header.Version != 2 || header.Source.String() != "203.0.113.7:56324" || header.Dest.String() != "192.0.2.1:443" {
+		t.Fatalf("unexpected header %+v", header)
+	}
+
+	tlvMap := header.TLVMap()
+	if tlvMap["authority"] != "example.com" || tlvMap["0xe0"] != "ff00" {
+		t.Errorf("unexpected TLVs %v", tlvMap)
+	}
+
+	rest, _ := ioutil.ReadAll(r)
+	if string(rest) != "GET /" {
+		t.Errorf("unexpected data after the header : %q", rest)
+	}
+}
+
+func TestReadNoHeader(t *testing.T) {
+	for _, data := range []string{"POST / HTTP/1.1\r\n\r\n", "P", "\x16\x03\x01"} {
+		header, err := Read(bufio.NewReader(strings.NewReader(data)))
+		if err != nil || header != nil {
+			t.Errorf("%q : unexpected header %+v (%v)", data, header, err)
+		}
+	}
+}
+
+func TestListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	_, other, _ := net.ParseCIDR("10.0.0.0/8")
+
+	for _, test := range []struct {
+		trusted  []*net.IPNet
+		expected string
+		data     string
+	}{
+		{[]*net.IPNet{loopback}, "198.51.100.1:1234", "hello"},
+		{[]*net.IPNet{other}, "127.0.0.1", "PROXY TCP4 198.51.100.1 127.0.0.1 1234 80\r\nhello"},
+	} {
+		pln := NewListener(ln, test.trusted)
+
+		client, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _ = client.Write([]byte("PROXY TCP4 198.51.100.1 127.0.0.1 1234 80\r\nhello"))
+		client.Close()
+
+		conn, err := pln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if addr := conn.RemoteAddr().String(); !strings.HasPrefix(addr, test.expected) {
+			t.Errorf("unexpected remote address %s, expected %s", addr, test.expected)
+		}
+
+		data, _ := ioutil.ReadAll(conn)
+		if string(data) != test.data {
+			t.Errorf("unexpected data %q", data)
+		}
+
+		conn.Close()
+	}
+}
@@ -0,0 +1,142 @@
+package quicparser
+
+import (
+	"errors"
+)
+
+const (
+	handshakeTypeClientHello = 0x01
+
+	extensionServerName        = 0x0000
+	extensionALPN              = 0x0010
+	extensionSupportedVersions = 0x002b
+)
+
+var (
+	errNotClientHello   = errors.New("CRYPTO data does not start with a TLS ClientHello")
+	errMalformedHello   = errors.New("malformed TLS ClientHello")
+	errMalformedElement = errors.New("malformed TLS ClientHello extension")
+)
+
+// byteReader is a minimal helper to read TLS length-prefixed structures
+type byteReader struct {
+	buf []byte
+	err error
+}
+
+func (r *byteReader) read(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if n > len(r.buf) {
+		r.err = errMalformedHello
+		return nil
+	}
+
+	data := r.buf[:n]
+	r.buf = r.buf[n:]
+	return data
+}
+
+func (r *byteReader) readUint(n int) int {
+	var val int
+	for _, b := range r.read(n) {
+		val = val<<8 | int(b)
+	}
+	return val
+}
+
+// readVector reads a vector prefixed by its length on n bytes
+func (r *byteReader) readVector(n int) []byte {
+	return r.read(r.readUint(n))
+}
+
+// parseClientHello parses a TLS handshake message. It returns nil without error if the message is not complete yet
+// https://www.rfc-editor.org/rfc/rfc8446.html#section-4.1.2
+func parseClientHello(data []byte) (*ClientHello, error) {
+	if len(data) < 4 {
+		return nil, nil
+	}
+
+	if data[0] != handshakeTypeClientHello {
+		return nil, errNotClientHello
+	}
+
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if len(data) < 4+length {
+		return nil, nil
+	}
+
+	hello := &ClientHello{}
+	r := &byteReader{buf: data[4 : 4+length]}
+
+	r.read(2)       // legacy_version
+	r.read(32)      // random
+	r.readVector(1) // legacy_session_id
+	suites := &byteReader{buf: r.readVector(2)}
+	r.readVector(1) // legacy_compression_methods
+
+	for suites.err == nil && len(suites.buf) > 0 {
+		hello.CipherSuites = append(hello.CipherSuites, uint16(suites.readUint(2)))
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// Extensions are optional in older TLS versions
+	if len(r.buf) == 0 {
+		return hello, nil
+	}
+
+	extensions := &byteReader{buf: r.readVector(2)}
+	for extensions.err == nil && len(extensions.buf) > 0 {
+		extType := extensions.readUint(2)
+		ext := &byteReader{buf: extensions.readVector(2)}
+
+		switch extType {
+		case extensionServerName:
+			names := &byteReader{buf: ext.readVector(2)}
+			for names.err == nil && len(names.buf) > 0 {
+				nameType := names.readUint(1)
+				name := names.readVector(2)
+				// host_name
+				if nameType == 0 && hello.SNI == "" {
+					hello.SNI = string(name)
+				}
+			}
+			if names.err != nil {
+				return nil, errMalformedElement
+			}
+
+		case extensionALPN:
+			protos := &byteReader{buf: ext.readVector(2)}
+			for protos.err == nil && len(protos.buf) > 0 {
+				hello.ALPN = append(hello.ALPN, string(protos.readVector(1)))
+			}
+			if protos.err != nil {
+				return nil, errMalformedElement
+			}
+
+		case extensionSupportedVersions:
+			versions := &byteReader{buf: ext.readVector(1)}
+			for versions.err == nil && len(versions.buf) > 0 {
+				hello.SupportedVersions = append(hello.SupportedVersions, uint16(versions.readUint(2)))
+			}
+			if versions.err != nil {
+				return nil, errMalformedElement
+			}
+		}
+
+		if ext.err != nil {
+			return nil, errMalformedElement
+		}
+	}
+
+	if extensions.err != nil || r.err != nil {
+		return nil, errMalformedHello
+	}
+
+	return hello, nil
+}
@@ -0,0 +1,164 @@
+package quicparser
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	// https://www.rfc-editor.org/rfc/rfc9001.html#section-5.2
+	saltV1 = []byte{
+		0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17,
+		0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a,
+	}
+
+	// https://www.rfc-editor.org/rfc/rfc9369.html#section-3.3.1
+	saltV2 = []byte{
+		0x0d, 0xed, 0xe3, 0xde, 0xf7, 0x00, 0xa6, 0xdb, 0x81, 0x93,
+		0x81, 0xbe, 0x6e, 0x26, 0x9d, 0xcb, 0xf9, 0xbd, 0x2e, 0xd9,
+	}
+
+	// https://datatracker.ietf.org/doc/html/draft-ietf-quic-tls-29#section-5.2
+	saltDraft29 = []byte{
+		0xaf, 0xbf, 0xec, 0x28, 0x99, 0x93, 0xd2, 0x4c, 0x9e, 0x97,
+		0x86, 0xf1, 0x9c, 0x61, 0x11, 0xe0, 0x43, 0x90, 0xa8, 0x99,
+	}
+
+	// Used from draft-23 to draft-28
+	saltDraft23 = []byte{
+		0xc3, 0xee, 0xf7, 0x12, 0xc7, 0x2e, 0xbb, 0x5a, 0x11, 0xa7,
+		0xd2, 0x43, 0x2b, 0xb4, 0x63, 0x65, 0xbe, 0xf9, 0xf5, 0x02,
+	}
+
+	errDecrypt = errors.New("failed to decrypt QUIC Initial packet")
+)
+
+// initialKeys holds the client-side Initial packet protection material
+type initialKeys struct {
+	key []byte
+	iv  []byte
+	hp  []byte
+}
+
+// deriveClientInitialKeys derives the client Initial keys from the Destination Connection ID chosen by the client
+// https://www.rfc-editor.org/rfc/rfc9001.html#section-5.2
+func deriveClientInitialKeys(version uint32, dcid []byte) (initialKeys, error) {
+	salt, labelPrefix, ok := versionParams(version)
+	if !ok {
+		return initialKeys{}, ErrUnsupportedVersion
+	}
+
+	initialSecret := hkdfExtract(salt, dcid)
+	clientSecret := hkdfExpandLabel(initialSecret, "client in", sha256.Size)
+
+	return initialKeys{
+		key: hkdfExpandLabel(clientSecret, labelPrefix+" key", 16),
+		iv:  hkdfExpandLabel(clientSecret, labelPrefix+" iv", 12),
+		hp:  hkdfExpandLabel(clientSecret, labelPrefix+" hp", 16),
+	}, nil
+}
+
+func versionParams(version uint32) ([]byte, string, bool) {
+	switch {
+	case version == Version1:
+		return saltV1, "quic", true
+	case version == Version2:
+		return saltV2, "quicv2", true
+	case version == VersionDraft29:
+		return saltDraft29, "quic", true
+	case version >= VersionDraft23 && version < VersionDraft29:
+		return saltDraft23, "quic", true
+	}
+
+	return nil, "", false
+}
+
+// hkdfExtract implements HKDF-Extract (RFC 5869) with SHA-256
+func hkdfExtract(salt []byte, secret []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(secret)
+	return mac.Sum(nil)
+}
+
+// hkdfExpandLabel implements the TLS 1.3 HKDF-Expand-Label function (RFC 8446) with an empty context
+func hkdfExpandLabel(secret []byte, label string, length int) []byte {
+	fullLabel := "tls13 " + label
+	info := make([]byte, 0, 4+len(fullLabel))
+	info = append(info, byte(length>>8), byte(length))
+	info = append(info, byte(len(fullLabel)))
+	info = append(info, fullLabel...)
+	info = append(info, 0)
+
+	var out []byte
+	var prev []byte
+	for counter := byte(1); len(out) < length; counter++ {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(prev)
+		mac.Write(info)
+		mac.Write([]byte{counter})
+		prev = mac.Sum(nil)
+		out = append(out, prev...)
+	}
+
+	return out[:length]
+}
+
+// unprotect removes the header protection and decrypts the payload of a long header packet in place. pnOffset is the
+// offset of the packet number field and end the offset of the end of the packet in the datagram.
+// It returns the decrypted frames
+func unprotect(keys initialKeys, packet []byte, pnOffset int, end int) ([]byte, error) {
+	// The sample is taken 4 bytes after the start of the packet number field
+	if pnOffset+4+aes.BlockSize > end {
+		return nil, errDecrypt
+	}
+
+	hpCipher, err := aes.NewCipher(keys.hp)
+	if err != nil {
+		return nil, err
+	}
+
+	mask := make([]byte, aes.BlockSize)
+	hpCipher.Encrypt(mask, packet[pnOffset+4:pnOffset+4+aes.BlockSize])
+
+	header := make([]byte, pnOffset+4)
+	copy(header, packet[:pnOffset+4])
+
+	header[0] ^= mask[0] & 0x0f
+	pnLen := int(header[0]&0x03) + 1
+
+	var pn uint64
+	for i := 0; i < pnLen; i++ {
+		header[pnOffset+i] ^= mask[1+i]
+		pn = pn<<8 | uint64(header[pnOffset+i])
+	}
+	header = header[:pnOffset+pnLen]
+
+	block, err := aes.NewCipher(keys.key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, len(keys.iv))
+	copy(nonce, keys.iv)
+	var pnBytes [8]byte
+	binary.BigEndian.PutUint64(pnBytes[:], pn)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-8+i] ^= pnBytes[i]
+	}
+
+	plaintext, err := aead.Open(nil, nonce, packet[pnOffset+pnLen:end], header)
+	if err != nil {
+		return nil, errDecrypt
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,415 @@
+package quicparser
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// Version1 is the QUIC version 1 (RFC 9000)
+	Version1 uint32 = 0x00000001
+	// Version2 is the QUIC version 2 (RFC 9369)
+	Version2 uint32 = 0x6b3343cf
+	// VersionDraft29 is the last IETF draft version widely deployed before the RFC
+	VersionDraft29 uint32 = 0xff00001d
+	// VersionDraft23 is the first IETF draft version using the TLS 1.3 based Initial packet protection
+	VersionDraft23 uint32 = 0xff000017
+
+	// Frame types needed to walk an Initial packet payload
+	framePadding         = 0x00
+	framePing            = 0x01
+	frameAck             = 0x02
+	frameAckECN          = 0x03
+	frameCrypto          = 0x06
+	frameConnectionClose = 0x1c
+
+	// Clients must pad Initial packets carrying a ClientHello, so CRYPTO data over this size is considered bogus
+	maxCryptoDataSize = 64 * 1024
+
+	// maxPendingHandshakes caps the number of partial ClientHello kept in memory, as spoofed Initial packets are cheap
+	maxPendingHandshakes = 4096
+)
+
+var (
+	// ErrNotInitial is returned when the datagram does not start with a QUIC long header Initial packet
+	ErrNotInitial = errors.New("not a QUIC Initial packet")
+	// ErrUnsupportedVersion is returned when the QUIC version of the packet is not supported
+	ErrUnsupportedVersion = errors.New("unsupported QUIC version")
+
+	errTruncated = errors.New("truncated QUIC packet")
+
+	// PendingHandshakes buffers the ClientHello split across multiple Initial packets, by client connection
+	PendingHandshakes = make(pendingMap)
+)
+
+// Initial describes the data extracted from the client Initial packets found in a datagram
+type Initial struct {
+	Version uint32
+	DCID    []byte
+	SCID    []byte
+	// Crypto maps the offset of each received CRYPTO frame to its data
+	Crypto map[uint64][]byte
+}
+
+// ClientHello describes the interesting fields of a TLS ClientHello message
+type ClientHello struct {
+	SNI               string
+	ALPN              []string
+	SupportedVersions []uint16
+	CipherSuites      []uint16
+}
+
+// pendingHandshake abstracts a partially received ClientHello
+type pendingHandshake struct {
+	lastSeen time.Time
+	crypto   map[uint64][]byte
+}
+
+// pendingMap abstracts a hash table of multiple pendingHandshake sorted by source and connection ID
+type pendingMap map[string]*pendingHandshake
+
+// VersionName returns a human readable name for the given QUIC version
+func VersionName(version uint32) string {
+	switch {
+	case version == Version1:
+		return "v1"
+	case version == Version2:
+		return "v2"
+	case version&0xffffff00 == 0xff000000:
+		return fmt.Sprintf("draft-%d", version&0xff)
+	}
+
+	return fmt.Sprintf("0x%08x", version)
+}
+
+// IsLongHeader is a cheap check telling if the payload may start with a QUIC long header packet from a supported
+// version, before trying to decrypt it
+func IsLongHeader(payload []byte) bool {
+	if len(payload) < 7 || payload[0]&0xc0 != 0xc0 {
+		return false
+	}
+
+	_, _, ok := versionParams(uint32(payload[1])<<24 | uint32(payload[2])<<16 | uint32(payload[3])<<8 | uint32(payload[4]))
+	return ok
+}
+
+// ParseInitial decrypts the client Initial packets coalesced in a datagram and collects their CRYPTO frames
+func ParseInitial(datagram []byte) (*Initial, error) {
+	var initial *Initial
+
+	for len(datagram) > 0 {
+		// Coalesced packets are terminated by padding or by a short header packet
+		if datagram[0]&0x80 == 0 {
+			break
+		}
+
+		pkt, rest, err := parseLongHeaderPacket(datagram)
+		if err != nil {
+			if initial != nil {
+				break
+			}
+			return nil, err
+		}
+
+		datagram = rest
+		if pkt == nil {
+			// Not an Initial packet (0-RTT, Handshake...)
+			continue
+		}
+
+		if initial == nil {
+			initial = pkt
+			continue
+		}
+
+		for offset, data := range pkt.Crypto {
+			initial.Crypto[offset] = data
+		}
+	}
+
+	if initial == nil {
+		return nil, ErrNotInitial
+	}
+
+	return initial, nil
+}
+
+func parseLongHeaderPacket(datagram []byte) (*Initial, []byte, error) {
+	if len(datagram) < 7 || datagram[0]&0xc0 != 0xc0 {
+		return nil, nil, ErrNotInitial
+	}
+
+	version := uint32(datagram[1])<<24 | uint32(datagram[2])<<16 | uint32(datagram[3])<<8 | uint32(datagram[4])
+	if _, _, ok := versionParams(version); !ok {
+		return nil, nil, ErrUnsupportedVersion
+	}
+
+	packetType := (datagram[0] & 0x30) >> 4
+	isInitial := packetType == 0
+	if version == Version2 {
+		isInitial = packetType == 1
+	}
+
+	offset := 5
+	dcidLen := int(datagram[offset])
+	offset++
+	if dcidLen > 20 || offset+dcidLen >= len(datagram) {
+		return nil, nil, errTruncated
+	}
+	dcid := datagram[offset : offset+dcidLen]
+	offset += dcidLen
+
+	scidLen := int(datagram[offset])
+	offset++
+	if scidLen > 20 || offset+scidLen > len(datagram) {
+		return nil, nil, errTruncated
+	}
+	scid := datagram[offset : offset+scidLen]
+	offset += scidLen
+
+	if isInitial {
+		tokenLen, n, err := readVarint(datagram[offset:])
+		if err != nil {
+			return nil, nil, err
+		}
+		offset += n
+		if tokenLen > uint64(len(datagram)-offset) {
+			return nil, nil, errTruncated
+		}
+		offset += int(tokenLen)
+	}
+
+	length, n, err := readVarint(datagram[offset:])
+	if err != nil {
+		return nil, nil, err
+	}
+	offset += n
+
+	if length > uint64(len(datagram)-offset) {
+		return nil, nil, errTruncated
+	}
+	end := offset + int(length)
+
+	if !isInitial {
+		return nil, datagram[end:], nil
+	}
+
+	keys, err := deriveClientInitialKeys(version, dcid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plaintext, err := unprotect(keys, datagram, offset, end)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	crypto, err := parseFrames(plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	initial := &Initial{
+		Version: version,
+		DCID:    append([]byte{}, dcid...),
+		SCID:    append([]byte{}, scid...),
+		Crypto:  crypto,
+	}
+
+	return initial, datagram[end:], nil
+}
+
+// parseFrames walks the frames of a decrypted Initial packet and returns the CRYPTO frames data by offset
+func parseFrames(payload []byte) (map[uint64][]byte, error) {
+	crypto := make(map[uint64][]byte)
+
+	for len(payload) > 0 {
+		frameType, n, err := readVarint(payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = payload[n:]
+
+		switch frameType {
+		case framePadding, framePing:
+			continue
+
+		case frameAck, frameAckECN:
+			// Largest Acknowledged, ACK Delay, ACK Range Count, First ACK Range
+			var fields [4]uint64
+			for i := range fields {
+				fields[i], n, err = readVarint(payload)
+				if err != nil {
+					return nil, err
+				}
+				payload = payload[n:]
+			}
+
+			// Gap and ACK Range Length for each range, plus the 3 ECN counts
+			extra := fields[2] * 2
+			if frameType == frameAckECN {
+				extra += 3
+			}
+
+			for i := uint64(0); i < extra; i++ {
+				_, n, err = readVarint(payload)
+				if err != nil {
+					return nil, err
+				}
+				payload = payload[n:]
+			}
+
+		case frameCrypto:
+			offset, n, err := readVarint(payload)
+			if err != nil {
+				return nil, err
+			}
+			payload = payload[n:]
+
+			length, n, err := readVarint(payload)
+			if err != nil {
+				return nil, err
+			}
+			payload = payload[n:]
+
+			if length > uint64(len(payload)) || offset+length > maxCryptoDataSize {
+				return nil, errTruncated
+			}
+
+			crypto[offset] = append([]byte{}, payload[:length]...)
+			payload = payload[length:]
+
+		case frameConnectionClose:
+			return crypto, nil
+
+		default:
+			return nil, fmt.Errorf("unexpected frame type 0x%x in QUIC Initial packet", frameType)
+		}
+	}
+
+	return crypto, nil
+}
+
+// readVarint reads a QUIC variable-length integer
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-16
+func readVarint(b []byte) (uint64, int, error) {
+	if len(b) == 0 {
+		return 0, 0, errTruncated
+	}
+
+	length := 1 << (b[0] >> 6)
+	if len(b) < length {
+		return 0, 0, errTruncated
+	}
+
+	val := uint64(b[0] & 0x3f)
+	for i := 1; i < length; i++ {
+		val = val<<8 | uint64(b[i])
+	}
+
+	return val, length, nil
+}
+
+// assembleCrypto concatenates the contiguous CRYPTO data starting at offset 0
+func assembleCrypto(crypto map[uint64][]byte) []byte {
+	var offsets []uint64
+	var buf []byte
+
+	for offset := range crypto {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	for _, offset := range offsets {
+		data := crypto[offset]
+		if offset > uint64(len(buf)) {
+			break
+		}
+
+		end := offset + uint64(len(data))
+		if end > uint64(len(buf)) {
+			buf = append(buf, data[uint64(len(buf))-offset:]...)
+		}
+	}
+
+	return buf
+}
+
+// ClientHello returns the ClientHello carried by the Initial's CRYPTO frames. It returns nil if the message is
+// incomplete
+func (initial *Initial) ClientHello() (*ClientHello, error) {
+	return parseClientHello(assembleCrypto(initial.Crypto))
+}
+
+// DCIDString returns the Destination Connection ID as an hex string
+func (initial *Initial) DCIDString() string {
+	return hex.EncodeToString(initial.DCID)
+}
+
+// SCIDString returns the Source Connection ID as an hex string
+func (initial *Initial) SCIDString() string {
+	return hex.EncodeToString(initial.SCID)
+}
+
+// Reassemble merges the CRYPTO frames of the given Initial with the ones previously received for the same connection.
+// It returns the ClientHello once complete, or nil while waiting for more packets
+func (m pendingMap) Reassemble(source string, initial *Initial) (*ClientHello, error) {
+	key := source + "/" + initial.DCIDString()
+
+	if pending, ok := m[key]; ok {
+		for offset, data := range initial.Crypto {
+			pending.crypto[offset] = data
+		}
+		initial.Crypto = pending.crypto
+	}
+
+	hello, err := initial.ClientHello()
+	if err != nil || hello != nil {
+		delete(m, key)
+		return hello, err
+	}
+
+	if _, ok := m[key]; !ok && len(m) >= maxPendingHandshakes {
+		m.evictOldest()
+	}
+
+	m[key] = &pendingHandshake{
+		lastSeen: time.Now(),
+		crypto:   initial.Crypto,
+	}
+
+	return nil, nil
+}
+
+// FlushOlderThan removes the partial handshakes not updated since the given deadline
+func (m pendingMap) FlushOlderThan(deadline time.Time) {
+	for key, pending := range m {
+		if pending.lastSeen.Before(deadline) {
+			delete(m, key)
+		}
+	}
+}
+
+// evictOldest removes the least recently updated partial handshake
+func (m pendingMap) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+
+	for key, pending := range m {
+		if oldestKey == "" || pending.lastSeen.Before(oldest) {
+			oldestKey, oldest = key, pending.lastSeen
+		}
+	}
+
+	delete(m, oldestKey)
+}
+
+// FlushAll removes all the partial handshakes
+func (m pendingMap) FlushAll() {
+	for key := range m {
+		delete(m, key)
+	}
+}
@@ -0,0 +1,254 @@
+package quicparser
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestDeriveClientInitialKeys(t *testing.T) {
+	// https://www.rfc-editor.org/rfc/rfc9001.html#appendix-A.1
+	dcid, _ := hex.DecodeString("8394c8f03e515708")
+
+	keys, err := deriveClientInitialKeys(Version1, dcid)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tests := []struct {
+		Name     string
+		Got      []byte
+		Expected string
+	}{
+		{"key", keys.key, "1f369613dd76d5467730efcbe3b1a22d"},
+		{"iv", keys.iv, "fa044b2f42a3fd3b46fb255c"},
+		{"hp", keys.hp, "9f50449e04a0e810283a1e9933adedd2"},
+	}
+
+	for _, test := range tests {
+		if hex.EncodeToString(test.Got) != test.Expected {
+			t.Error(test.Name, "FAILED : got", hex.EncodeToString(test.Got))
+		}
+	}
+}
+
+func TestParseInitial(t *testing.T) {
+	dcid, _ := hex.DecodeString("8394c8f03e515708")
+	hello := makeClientHello("example.com", []string{"h3", "h3-29"})
+
+	datagram := makeInitial(t, Version1, dcid, makeCryptoFrame(0, hello))
+
+	initial, err := ParseInitial(datagram)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if initial.DCIDString() != "8394c8f03e515708" {
+		t.Error("dcid FAILED : got", initial.DCIDString())
+	}
+
+	parsed, err := initial.ClientHello()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if parsed == nil {
+		t.Error("ClientHello FAILED : got nil")
+		return
+	}
+
+	if parsed.SNI != "example.com" {
+		t.Error("sni FAILED : got", parsed.SNI)
+	}
+
+	if len(parsed.ALPN) != 2 || parsed.ALPN[0] != "h3" || parsed.ALPN[1] != "h3-29" {
+		t.Error("alpn FAILED : got", parsed.ALPN)
+	}
+
+	if _, err := ParseInitial([]byte("not a QUIC packet at all")); err == nil {
+		t.Error("garbage FAILED : got no error")
+	}
+}
+
+func TestReassembleClientHello(t *testing.T) {
+	dcid, _ := hex.DecodeString("0011223344556677")
+	hello := makeClientHello("split.example.com", []string{"h3"})
+	half := len(hello) / 2
+
+	first, err := ParseInitial(makeInitial(t, Version2, dcid, makeCryptoFrame(0, hello[:half])))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	second, err := ParseInitial(makeInitial(t, Version2, dcid, makeCryptoFrame(uint64(half), hello[half:])))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	pending := make(pendingMap)
+
+	parsed, err := pending.Reassemble("192.0.2.1", first)
+	if err != nil || parsed != nil {
+		t.Error("first fragment FAILED : got", parsed, err)
+		return
+	}
+
+	parsed, err = pending.Reassemble("192.0.2.1", second)
+	if err != nil || parsed == nil {
+		t.Error("second fragment FAILED : got", parsed, err)
+		return
+	}
+
+	if parsed.SNI != "split.example.com" {
+		t.Error("sni FAILED : got", parsed.SNI)
+	}
+
+	if len(pending) != 0 {
+		t.Error("pending FAILED : handshake not removed after completion")
+	}
+}
+
+func TestPendingHandshakesCap(t *testing.T) {
+	hello := makeClientHello("capped.example.com", []string{"h3"})
+	pending := make(pendingMap)
+
+	for i := 0; i <= maxPendingHandshakes; i++ {
+		dcid := []byte{byte(i >> 8), byte(i), 0, 0, 0, 0, 0, 0}
+		initial, err := ParseInitial(makeInitial(t, Version1, dcid, makeCryptoFrame(0, hello[:len(hello)/2])))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if _, err := pending.Reassemble("192.0.2.1", initial); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if i == 0 {
+			// Make the first handshake the oldest one
+			for _, p := range pending {
+				p.lastSeen = p.lastSeen.Add(-time.Minute)
+			}
+		}
+	}
+
+	if len(pending) != maxPendingHandshakes {
+		t.Error("cap FAILED : got", len(pending), "pending handshakes")
+	}
+
+	if _, ok := pending["192.0.2.1/0000000000000000"]; ok {
+		t.Error("eviction FAILED : the oldest handshake is still pending")
+	}
+}
+
+func makeClientHello(sni string, alpn []string) []byte {
+	var body bytes.Buffer
+
+	body.Write([]byte{0x03, 0x03})
+	body.Write(make([]byte, 32))
+	body.WriteByte(0)
+	body.Write([]byte{0x00, 0x02, 0x13, 0x01})
+	body.Write([]byte{0x01, 0x00})
+
+	var exts bytes.Buffer
+	name := []byte(sni)
+	exts.Write([]byte{0x00, 0x00})
+	writeUint16(&exts, len(name)+5)
+	writeUint16(&exts, len(name)+3)
+	exts.WriteByte(0)
+	writeUint16(&exts, len(name))
+	exts.Write(name)
+
+	var protos bytes.Buffer
+	for _, proto := range alpn {
+		protos.WriteByte(byte(len(proto)))
+		protos.WriteString(proto)
+	}
+	exts.Write([]byte{0x00, 0x10})
+	writeUint16(&exts, protos.Len()+2)
+	writeUint16(&exts, protos.Len())
+	exts.Write(protos.Bytes())
+
+	writeUint16(&body, exts.Len())
+	body.Write(exts.Bytes())
+
+	msg := []byte{handshakeTypeClientHello, byte(body.Len() >> 16), byte(body.Len() >> 8), byte(body.Len())}
+	return append(msg, body.Bytes()...)
+}
+
+func makeCryptoFrame(offset uint64, data []byte) []byte {
+	frame := []byte{frameCrypto}
+	frame = appendVarint(frame, offset)
+	frame = appendVarint(frame, uint64(len(data)))
+	return append(frame, data...)
+}
+
+// makeInitial builds a protected client Initial packet padded to 1200 bytes, as a client would
+func makeInitial(t *testing.T, version uint32, dcid []byte, frames []byte) []byte {
+	keys, err := deriveClientInitialKeys(version, dcid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pnLen := 4
+	payloadLen := 1200 - 64
+	if len(frames) < payloadLen {
+		frames = append(frames, make([]byte, payloadLen-len(frames))...)
+	}
+
+	typeBits := byte(0x00)
+	if version == Version2 {
+		typeBits = 0x10
+	}
+
+	header := []byte{0xc0 | typeBits | byte(pnLen-1), byte(version >> 24), byte(version >> 16), byte(version >> 8), byte(version)}
+	header = append(header, byte(len(dcid)))
+	header = append(header, dcid...)
+	header = append(header, 0)
+	header = appendVarint(header, 0)
+	header = appendVarint(header, uint64(pnLen+len(frames)+16))
+	pnOffset := len(header)
+	header = append(header, 0, 0, 0, 2)
+
+	block, _ := aes.NewCipher(keys.key)
+	aead, _ := cipher.NewGCM(block)
+	nonce := append([]byte{}, keys.iv...)
+	nonce[len(nonce)-1] ^= 2
+
+	packet := aead.Seal(append([]byte{}, header...), nonce, frames, header)
+
+	hpCipher, _ := aes.NewCipher(keys.hp)
+	mask := make([]byte, aes.BlockSize)
+	hpCipher.Encrypt(mask, packet[pnOffset+4:pnOffset+4+aes.BlockSize])
+	packet[0] ^= mask[0] & 0x0f
+	for i := 0; i < pnLen; i++ {
+		packet[pnOffset+i] ^= mask[1+i]
+	}
+
+	return packet
+}
+
+func appendVarint(b []byte, val uint64) []byte {
+	switch {
+	case val < 1<<6:
+		return append(b, byte(val))
+	case val < 1<<14:
+		return append(b, byte(val>>8)|0x40, byte(val))
+	default:
+		return append(b, byte(val>>24)|0x80, byte(val>>16), byte(val>>8), byte(val))
+	}
+}
+
+func writeUint16(buf *bytes.Buffer, val int) {
+	buf.WriteByte(byte(val >> 8))
+	buf.WriteByte(byte(val))
+}
@@ -0,0 +1,206 @@
+package redisd
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runID identifies the fake instance in INFO
+const runID = "7f3a1c2be54d9e0f6a8b3c7d1e2f4a5b6c7d8e9f"
+
+// defaultConfig returns the configuration of a fresh instance of Redis 5 listening on the given port, without password
+// nor protected mode
+func defaultConfig(port string) map[string]string {
+	return map[string]string{
+		"appendonly":        "no",
+		"bind":              "",
+		"daemonize":         "yes",
+		"databases":         "16",
+		"dbfilename":        "dump.rdb",
+		"dir":               "/var/lib/redis",
+		"logfile":           "/var/log/redis/redis-server.log",
+		"masterauth":        "",
+		"maxclients":        "10000",
+		"maxmemory":         "0",
+		"maxmemory-policy":  "noeviction",
+		"pidfile":           "/var/run/redis/redis-server.pid",
+		"port":              port,
+		"protected-mode":    "no",
+		"replica-read-only": "yes",
+		"requirepass":       "",
+		"save":              "900 1 300 10 60 10000",
+		"slave-read-only":   "yes",
+		"timeout":           "0",
+	}
+}
+
+// configCommand answers the CONFIG subcommands. The parameters set by the client are kept for the rest of the session
+func (s *Server) configCommand(w writer, st *state, args [][]byte) {
+	switch sub := strings.ToLower(string(args[1])); sub {
+	case "get":
+		if len(args) != 3 {
+			w.error("ERR Unknown subcommand or wrong number of arguments for 'get'. Try CONFIG HELP.")
+			return
+		}
+
+		var names []string
+		for name := range st.config {
+			if matchGlob(strings.ToLower(string(args[2])), name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		var items []string
+		for _, name := range names {
+			items = append(items, name, st.config[name])
+		}
+		w.array(items)
+	case "set":
+		if len(args) != 4 {
+			w.error("ERR Unknown subcommand or wrong number of arguments for 'set'. Try CONFIG HELP.")
+			return
+		}
+
+		name := strings.ToLower(string(args[2]))
+		if _, ok := st.config[name]; !ok {
+			w.error("ERR Unsupported CONFIG parameter: " + string(args[2]))
+			return
+		}
+
+		st.config[name] = string(args[3])
+		w.simple("OK")
+	case "resetstat", "rewrite":
+		w.simple("OK")
+	default:
+		w.error("ERR Unknown subcommand or wrong number of arguments for '" + sub + "'. Try CONFIG HELP.")
+	}
+}
+
+// info returns the INFO report of the given section, as sent by Redis 5
+func (s *Server) info(st *state, section string) string {
+	uptime := time.Since(s.start) + 12*24*time.Hour
+	all := section == "all" || section == "everything" || section == "default"
+
+	sections := []struct {
+		name  string
+		lines []string
+	}{
+		{"Server", []string{
+			"redis_version:" + s.opts.Version,
+			"redis_git_sha1:00000000",
+			"redis_git_dirty:0",
+			"redis_build_id:636cde3b5c7a3923",
+			"redis_mode:standalone",
+			"os:Linux 4.19.0-16-amd64 x86_64",
+			"arch_bits:64",
+			"multiplexing_api:epoll",
+			"atomicvar_api:atomic-builtin",
+			"gcc_version:8.3.0",
+			"process_id:612",
+			"run_id:" + runID,
+			"tcp_port:" + st.port,
+			fmt.Sprintf("uptime_in_seconds:%d", int(uptime.Seconds())),
+			fmt.Sprintf("uptime_in_days:%d", int(uptime.Hours()/24)),
+			"hz:10",
+			"configured_hz:10",
+			"executable:/usr/bin/redis-server",
+			"config_file:/etc/redis/redis.conf",
+		}},
+		{"Clients", []string{
+			"connected_clients:1",
+			"client_recent_max_input_buffer:2",
+			"client_recent_max_output_buffer:0",
+			"blocked_clients:0",
+		}},
+		{"Memory", []string{
+			"used_memory:859216",
+			"used_memory_human:839.08K",
+			"used_memory_peak:859216",
+			"used_memory_peak_human:839.08K",
+			"maxmemory:" + st.config["maxmemory"],
+			"maxmemory_human:0B",
+			"maxmemory_policy:" + st.config["maxmemory-policy"],
+			"mem_fragmentation_ratio:13.73",
+			"mem_allocator:jemalloc-5.1.0",
+		}},
+		{"Persistence", []string{
+			"loading:0",
+			fmt.Sprintf("rdb_changes_since_last_save:%d", st.changes),
+			"rdb_bgsave_in_progress:0",
+			fmt.Sprintf("rdb_last_save_time:%d", st.lastSave.Unix()),
+			"rdb_last_bgsave_status:ok",
+			"aof_enabled:0",
+			"aof_rewrite_in_progress:0",
+		}},
+		{"Stats", []string{
+			"total_connections_received:" + fmt.Sprint(4000+int(uptime.Hours())),
+			"total_commands_processed:" + fmt.Sprint(9000+int(uptime.Minutes())),
+			"rejected_connections:0",
+			"expired_keys:0",
+			"evicted_keys:0",
+		}},
+		{"Replication", s.replicationInfo(st)},
+		{"CPU", []string{
+			fmt.Sprintf("used_cpu_sys:%.6f", uptime.Hours()*0.73),
+			fmt.Sprintf("used_cpu_user:%.6f", uptime.Hours()*0.61),
+		}},
+		{"Keyspace", keyspaceInfo(st)},
+	}
+
+	var report []string
+	for _, sec := range sections {
+		if !all && strings.ToLower(sec.name) != section {
+			continue
+		}
+
+		if len(report) > 0 {
+			report = append(report, "")
+		}
+		report = append(report, "# "+sec.name)
+		report = append(report, sec.lines...)
+	}
+
+	if len(report) == 0 {
+		return ""
+	}
+
+	return strings.Join(report, "\r\n") + "\r\n"
+}
+
+func (s *Server) replicationInfo(st *state) []string {
+	if st.master == "" {
+		return []string{
+			"role:master",
+			"connected_slaves:0",
+			"master_replid:" + runID,
+			"master_repl_offset:0",
+			"repl_backlog_active:0",
+		}
+	}
+
+	host, port, _ := net.SplitHostPort(st.master)
+	return []string{
+		"role:slave",
+		"master_host:" + host,
+		"master_port:" + port,
+		"master_link_status:down",
+		"master_last_io_seconds_ago:-1",
+		"master_sync_in_progress:0",
+		"slave_repl_offset:1",
+		"slave_priority:100",
+		"slave_read_only:1",
+		"connected_slaves:0",
+	}
+}
+
+func keyspaceInfo(st *state) []string {
+	if len(st.keys) == 0 {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("db0:keys=%d,expires=0,avg_ttl=0", len(st.keys))}
+}
@@ -0,0 +1,126 @@
+package redisd
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+)
+
+const (
+	// maxArgs is the largest number of arguments accepted in a command
+	maxArgs = 1024
+
+	// maxInlineLength is the largest inline command accepted, as in Redis
+	maxInlineLength = 64 * 1024
+)
+
+// protocolError is sent to the client before closing the connection, as Redis does
+type protocolError string
+
+func (e protocolError) Error() string {
+	return "Protocol error: " + string(e)
+}
+
+// readLine reads a line terminated by CRLF or LF, without its terminator
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, protocolError("too big inline request")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// readCommand reads a command sent as an array of bulk strings, or as an inline command. The bulk strings are limited
+// to maxSize bytes
+func readCommand(r *bufio.Reader, maxSize int) ([][]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 || line[0] != '*' {
+		// The line is only valid until the next read
+		var args [][]byte
+		for _, field := range bytes.Fields(line) {
+			args = append(args, append([]byte(nil), field...))
+		}
+		return args, nil
+	}
+
+	count, err := strconv.Atoi(string(line[1:]))
+	if err != nil || count > maxArgs {
+		return nil, protocolError("invalid multibulk length")
+	}
+
+	// The null and negative arrays are ignored as empty commands, as Redis does
+	if count <= 0 {
+		return nil, nil
+	}
+
+	args := make([][]byte, 0, count)
+	for idx := 0; idx < count; idx++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, protocolError("expected '$', got '" + string(line[:1]) + "'")
+		}
+
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil || size < 0 || size > maxSize {
+			return nil, protocolError("invalid bulk length")
+		}
+
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(r, arg); err != nil {
+			return nil, err
+		}
+
+		args = append(args, arg[:size])
+	}
+
+	return args, nil
+}
+
+// writer encodes the replies of the server
+type writer struct {
+	*bufio.Writer
+}
+
+func (w writer) simple(s string) {
+	_, _ = w.WriteString("+" + s + "\r\n")
+}
+
+func (w writer) error(s string) {
+	_, _ = w.WriteString("-" + s + "\r\n")
+}
+
+func (w writer) integer(n int) {
+	_, _ = w.WriteString(":" + strconv.Itoa(n) + "\r\n")
+}
+
+// bulk sends a bulk string, or the nil bulk string if data is nil
+func (w writer) bulk(data []byte) {
+	if data == nil {
+		_, _ = w.WriteString("$-1\r\n")
+		return
+	}
+
+	_, _ = w.WriteString("$" + strconv.Itoa(len(data)) + "\r\n")
+	_, _ = w.Write(data)
+	_, _ = w.WriteString("\r\n")
+}
+
+func (w writer) array(items []string) {
+	_, _ = w.WriteString("*" + strconv.Itoa(len(items)) + "\r\n")
+	for _, item := range items {
+		w.bulk([]byte(item))
+	}
+}
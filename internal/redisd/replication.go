@@ -0,0 +1,149 @@
+package redisd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eofMarkLength is the length of the delimiter ending the payloads sent without length by the diskless masters
+const eofMarkLength = 40
+
+var (
+	errNotPublic = errors.New("the master is not a public address")
+
+	privateNetworks = mustParseCIDRs("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7")
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// isPublic checks if an address can be reached on the Internet, so that the replication cannot be used to reach the
+// network of the sensor
+func isPublic(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fetchReplication connects to a master as a replica, and downloads the payload it sends for the full
+// resynchronization. Rogue masters use it to plant a module before asking to load it. The payload is cut to maxSize
+// bytes
+func fetchReplication(host string, port string, listeningPort string, maxSize int, timeout time.Duration) ([]byte, error) {
+	addr, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+
+	if !isPublic(addr.IP) {
+		return nil, errNotPublic
+	}
+
+	c, err := net.DialTimeout("tcp", addr.String(), timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	_ = c.SetDeadline(time.Now().Add(timeout))
+	return syncReplica(c, listeningPort, maxSize)
+}
+
+// syncReplica runs the handshake of a replica with a master, and reads the payload of the full resynchronization
+func syncReplica(c net.Conn, listeningPort string, maxSize int) ([]byte, error) {
+	var err error
+	r := bufio.NewReader(c)
+	w := writer{bufio.NewWriter(c)}
+
+	for _, cmd := range [][]string{
+		{"PING"},
+		{"REPLCONF", "listening-port", listeningPort},
+		{"REPLCONF", "capa", "eof", "capa", "psync2"},
+		{"PSYNC", "?", "-1"},
+	} {
+		w.array(cmd)
+		if err := w.Flush(); err != nil {
+			return nil, err
+		}
+
+		if _, err := readLine(r); err != nil {
+			return nil, err
+		}
+	}
+
+	// The masters send newlines while preparing the payload
+	var line []byte
+	for len(line) == 0 {
+		if line, err = readLine(r); err != nil {
+			return nil, err
+		}
+	}
+
+	if line[0] != '$' {
+		return nil, fmt.Errorf("unexpected reply from the master : %q", line)
+	}
+
+	if strings.HasPrefix(string(line), "$EOF:") {
+		return readUntilMark(r, line[5:], maxSize)
+	}
+
+	size, err := strconv.Atoi(string(line[1:]))
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("invalid payload length from the master : %q", line)
+	}
+
+	if size > maxSize {
+		size = maxSize
+	}
+
+	data := make([]byte, size)
+	n, err := io.ReadFull(r, data)
+
+	return data[:n], err
+}
+
+// readUntilMark reads a payload ending with the given mark, cut to maxSize bytes
+func readUntilMark(r *bufio.Reader, mark []byte, maxSize int) ([]byte, error) {
+	if len(mark) != eofMarkLength {
+		return nil, fmt.Errorf("invalid EOF mark from the master : %q", mark)
+	}
+
+	var data []byte
+	for len(data) < maxSize+eofMarkLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return data, err
+		}
+
+		data = append(data, b)
+		if bytes.HasSuffix(data, mark) {
+			return data[:len(data)-eofMarkLength], nil
+		}
+	}
+
+	return data[:maxSize], nil
+}
@@ -0,0 +1,102 @@
+package replay
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Match returns the recorded exchange answering the given request, or nil if none does. The exchanges are selected in
+// this order :
+//   - same method, path and query
+//   - same method and path, sharing the most query parameters
+//   - same path, whatever the method
+//   - the exchange recorded for the fallback path, if not empty
+//
+// HEAD requests are matched against the recorded GET requests
+func (rec *Recording) Match(r *http.Request, fallback string) *Exchange {
+	method := r.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	var best, samePath *Exchange
+	bestScore := -1
+	query := r.URL.Query()
+
+	for _, ex := range rec.Exchanges {
+		if ex.Path != r.URL.Path {
+			continue
+		}
+
+		if ex.Method != method {
+			if samePath == nil {
+				samePath = ex
+			}
+			continue
+		}
+
+		if ex.Query == r.URL.RawQuery {
+			return ex
+		}
+
+		if score := commonParams(ex.Query, query); score > bestScore {
+			best, bestScore = ex, score
+		}
+	}
+
+	if best != nil {
+		return best
+	}
+
+	if samePath != nil {
+		return samePath
+	}
+
+	if fallback != "" && fallback != r.URL.Path {
+		for _, ex := range rec.Exchanges {
+			if ex.Path == fallback && ex.Method == http.MethodGet {
+				return ex
+			}
+		}
+	}
+
+	return nil
+}
+
+// commonParams counts the query parameters with the same value in both queries
+func commonParams(rawQuery string, query url.Values) int {
+	recorded, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for key, vals := range recorded {
+		if len(vals) > 0 && query.Get(key) == vals[0] {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Handler replays the recorded responses, and passes the requests without a matching exchange to the wrapped handler
+func (rec *Recording) Handler(h http.Handler, fallback string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ex := rec.Match(r, fallback)
+		if ex == nil {
+			h.ServeHTTP(w, r) // pass request
+			return
+		}
+
+		for key, vals := range ex.Headers {
+			w.Header()[key] = vals
+		}
+
+		w.WriteHeader(ex.Status)
+
+		if r.Method != http.MethodHead {
+			_, _ = w.Write(ex.body)
+		}
+	})
+}
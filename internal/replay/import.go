@@ -0,0 +1,223 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// RequestExt and ResponseExt are the extensions of the raw HTTP messages of a directory of recorded exchanges. An
+	// exchange is made of a request file and the response file with the same base name
+	RequestExt  = ".request"
+	ResponseExt = ".response"
+
+	// HARExt is the extension of the HAR files
+	HARExt = ".har"
+)
+
+// har describes the subset of the HAR 1.2 format used to import the exchanges
+type har struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Status  int `json:"status"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				Content struct {
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// Import creates a recording from a HAR file or from a directory of recorded exchanges. The directory can contain HAR
+// files, along with pairs of raw HTTP request and response files
+func Import(path string) (*Recording, error) {
+	rec := &Recording{}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		if err := importHAR(rec, path); err != nil {
+			return nil, err
+		}
+		return rec, nil
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort the files to get a predictable result when exchanges overlap
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+
+	for _, file := range files {
+		filePath := filepath.Join(path, file.Name())
+
+		switch filepath.Ext(file.Name()) {
+		case HARExt:
+			err = importHAR(rec, filePath)
+		case RequestExt:
+			err = importRaw(rec, filePath, strings.TrimSuffix(filePath, RequestExt)+ResponseExt)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rec, nil
+}
+
+// importHAR adds the exchanges of a HAR file to the recording
+func importHAR(rec *Recording, path string) error {
+	var archive har
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return fmt.Errorf("failed to parse HAR file '%s' : %s", path, err)
+	}
+
+	for idx, entry := range archive.Log.Entries {
+		// Aborted requests are recorded with a 0 status
+		if entry.Response.Status == 0 {
+			continue
+		}
+
+		body := []byte(entry.Response.Content.Text)
+		if entry.Response.Content.Encoding == "base64" {
+			body, err = base64.StdEncoding.DecodeString(entry.Response.Content.Text)
+			if err != nil {
+				return fmt.Errorf("failed to parse HAR file '%s' : entry %d : %s", path, idx, err)
+			}
+		}
+
+		var headers [][2]string
+		for _, header := range entry.Response.Headers {
+			headers = append(headers, [2]string{header.Name, header.Value})
+		}
+
+		ex, err := NewExchange(entry.Request.Method, entry.Request.URL, entry.Response.Status, headers, body)
+		if err != nil {
+			return fmt.Errorf("failed to parse HAR file '%s' : entry %d : %s", path, idx, err)
+		}
+
+		rec.Add(ex)
+	}
+
+	return nil
+}
+
+// importRaw adds the exchange made of a raw HTTP request and response to the recording
+func importRaw(rec *Recording, requestPath string, responsePath string) error {
+	rawReq, err := ioutil.ReadFile(requestPath)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(rawReq)))
+	if err != nil {
+		return fmt.Errorf("failed to parse request file '%s' : %s", requestPath, err)
+	}
+
+	rawResp, err := ioutil.ReadFile(responsePath)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rawResp)), req)
+	if err != nil {
+		return fmt.Errorf("failed to parse response file '%s' : %s", responsePath, err)
+	}
+	defer resp.Body.Close()
+
+	var reader io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		reader, err = gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to parse response file '%s' : %s", responsePath, err)
+		}
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to parse response file '%s' : %s", responsePath, err)
+	}
+
+	ex, err := NewExchange(req.Method, req.RequestURI, resp.StatusCode, rawHeaders(rawResp), body)
+	if err != nil {
+		return fmt.Errorf("failed to parse request file '%s' : %s", requestPath, err)
+	}
+
+	rec.Add(ex)
+	return nil
+}
+
+// rawHeaders returns the headers of a raw HTTP message in their original order and casing, as net/http does not
+// keep them
+func rawHeaders(raw []byte) [][2]string {
+	var headers [][2]string
+
+	lines := strings.Split(strings.Replace(string(raw), "\r\n", "\n", -1), "\n")
+	for _, line := range lines[1:] {
+		if line == "" {
+			break
+		}
+
+		idx := strings.Index(line, ":")
+		if idx <= 0 {
+			continue
+		}
+
+		headers = append(headers, [2]string{line[:idx], strings.TrimSpace(line[idx+1:])})
+	}
+
+	return headers
+}
+
+// splitURL returns the path and the raw query of a recorded URL, which can be absolute
+func splitURL(rawURL string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return path, u.RawQuery, nil
+}
@@ -0,0 +1,235 @@
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// IndexFile is the name of the file listing the exchanges of a recording
+	IndexFile = "replay.yml"
+
+	// BodiesDir is the directory of a recording holding the response bodies, named after their SHA-256 sum
+	BodiesDir = "bodies"
+)
+
+var (
+	// skippedHeaders are the recorded headers that are not replayed, as they depend on the connection or on the
+	// body's encoding and are set by the server
+	skippedHeaders = map[string]bool{
+		"Connection":        true,
+		"Content-Encoding":  true,
+		"Content-Length":    true,
+		"Date":              true,
+		"Keep-Alive":        true,
+		"Transfer-Encoding": true,
+		"Alt-Svc":           true,
+	}
+)
+
+// Exchange is a recorded request along with the response sent back by the real application
+type Exchange struct {
+	Method   string              `yaml:"method"`
+	Path     string              `yaml:"path"`
+	Query    string              `yaml:"query,omitempty"`
+	Status   int                 `yaml:"status"`
+	Headers  map[string][]string `yaml:"headers,omitempty"`
+	BodyFile string              `yaml:"body_file,omitempty"`
+
+	// HeaderOrder is the order and casing of the response headers, as recorded, including the ones not replayed
+	HeaderOrder []string `yaml:"header_order,omitempty"`
+
+	body []byte
+}
+
+// Recording is a set of exchanges captured from a real web application
+type Recording struct {
+	Exchanges []*Exchange `yaml:"exchanges"`
+}
+
+// NewExchange creates an Exchange from a recorded request and response. The headers that are not replayed are
+// dropped
+func NewExchange(method string, rawURL string, status int, headers [][2]string, body []byte) (*Exchange, error) {
+	path, query, err := splitURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if http.StatusText(status) == "" {
+		return nil, fmt.Errorf("'%d' is not a valid HTTP code status", status)
+	}
+
+	ex := &Exchange{
+		Method:  strings.ToUpper(method),
+		Path:    path,
+		Query:   query,
+		Status:  status,
+		Headers: make(map[string][]string),
+		body:    body,
+	}
+
+	seen := make(map[string]bool)
+	for _, header := range headers {
+		name := header[0]
+		key := http.CanonicalHeaderKey(name)
+
+		// Skip the HTTP/2 pseudo-headers
+		if strings.HasPrefix(name, ":") {
+			continue
+		}
+
+		if !seen[key] {
+			seen[key] = true
+			ex.HeaderOrder = append(ex.HeaderOrder, name)
+		}
+
+		if !skippedHeaders[key] {
+			ex.Headers[key] = append(ex.Headers[key], header[1])
+		}
+	}
+
+	return ex, nil
+}
+
+// Body returns the recorded response body
+func (ex *Exchange) Body() []byte {
+	return ex.body
+}
+
+// Add appends an exchange to the recording. An exchange for the same method, path and query replaces the previous one
+func (rec *Recording) Add(ex *Exchange) {
+	for idx, existing := range rec.Exchanges {
+		if existing.Method == ex.Method && existing.Path == ex.Path && existing.Query == ex.Query {
+			rec.Exchanges[idx] = ex
+			return
+		}
+	}
+
+	rec.Exchanges = append(rec.Exchanges, ex)
+}
+
+// Load reads the recording stored in the given directory
+func Load(dir string) (*Recording, error) {
+	rec := &Recording{}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recording '%s' : %s", dir, err)
+	}
+
+	for idx, ex := range rec.Exchanges {
+		if ex.Method == "" || !strings.HasPrefix(ex.Path, "/") {
+			return nil, fmt.Errorf("failed to parse recording '%s' : exchange %d needs a method and an absolute path", dir, idx)
+		}
+
+		if ex.Status == 0 {
+			ex.Status = http.StatusOK
+		}
+
+		if http.StatusText(ex.Status) == "" {
+			return nil, fmt.Errorf("failed to parse recording '%s' : exchange %d has an invalid status '%d'", dir, idx, ex.Status)
+		}
+
+		if ex.BodyFile == "" {
+			continue
+		}
+
+		// The bodies must stay in the recording's directory, as recordings can be shared
+		bodyFile := filepath.Clean(filepath.FromSlash(ex.BodyFile))
+		if filepath.IsAbs(bodyFile) || bodyFile == ".." || strings.HasPrefix(bodyFile, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("failed to parse recording '%s' : exchange %d has a body file outside of the recording", dir, idx)
+		}
+
+		ex.body, err = ioutil.ReadFile(filepath.Join(dir, bodyFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load recording '%s' : %s", dir, err)
+		}
+	}
+
+	return rec, nil
+}
+
+// Save writes the recording in the given directory. The bodies are stored once in the bodies directory
+func (rec *Recording) Save(dir string) error {
+	if err := os.MkdirAll(filepath.Join(dir, BodiesDir), 0755); err != nil {
+		return err
+	}
+
+	for _, ex := range rec.Exchanges {
+		if len(ex.body) == 0 {
+			ex.BodyFile = ""
+			continue
+		}
+
+		sum := sha256.Sum256(ex.body)
+		ex.BodyFile = filepath.ToSlash(filepath.Join(BodiesDir, hex.EncodeToString(sum[:])))
+
+		if err := ioutil.WriteFile(filepath.Join(dir, ex.BodyFile), ex.body, 0644); err != nil {
+			return err
+		}
+	}
+
+	out, err := yaml.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, IndexFile), out, 0644)
+}
+
+// HeaderOrder returns the order of the response headers of the first recorded HTML page, or of the first exchange if
+// there is none
+func (rec *Recording) HeaderOrder() []string {
+	for _, ex := range rec.Exchanges {
+		for _, ctype := range ex.Headers["Content-Type"] {
+			if strings.HasPrefix(ctype, "text/html") {
+				return fixCasing(ex.HeaderOrder)
+			}
+		}
+	}
+
+	if len(rec.Exchanges) > 0 {
+		return fixCasing(rec.Exchanges[0].HeaderOrder)
+	}
+
+	return nil
+}
+
+// fixCasing restores the usual casing of the headers recorded over HTTP/2, which are all lowercase
+func fixCasing(names []string) []string {
+	for _, name := range names {
+		if name != strings.ToLower(name) {
+			return names
+		}
+	}
+
+	fixed := make([]string, len(names))
+	for idx, name := range names {
+		fixed[idx] = http.CanonicalHeaderKey(name)
+	}
+
+	return fixed
+}
+
+// Server returns the first recorded Server header
+func (rec *Recording) Server() string {
+	for _, ex := range rec.Exchanges {
+		if vals := ex.Headers["Server"]; len(vals) > 0 {
+			return vals[0]
+		}
+	}
+
+	return ""
+}
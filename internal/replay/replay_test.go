@@ -0,0 +1,256 @@
+package replay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testHAR = `{
+  "log": {
+    "version": "1.2",
+    "entries": [
+      {
+        "request": {"method": "GET", "url": "http://192.168.1.1/login.html"},
+        "response": {
+          "status": 200,
+          "headers": [
+            {"name": "Server", "value": "GoAhead-Webs"},
+            {"name": "Date", "value": "Mon, 01 Jan 2018 00:00:00 GMT"},
+            {"name": "Content-Type", "value": "text/html"},
+            {"name": "Set-Cookie", "value": "a=1"},
+            {"name": "Set-Cookie", "value": "b=2"}
+          ],
+          "content": {"text": "<html>login</html>"}
+        }
+      },
+      {
+        "request": {"method": "GET", "url": "http://192.168.1.1/cgi-bin/status.cgi?page=wan&lang=en"},
+        "response": {
+          "status": 200,
+          "headers": [{"name": "Content-Type", "value": "application/json"}],
+          "content": {"text": "eyJ3YW4iOiJ1cCJ9", "encoding": "base64"}
+        }
+      },
+      {
+        "request": {"method": "GET", "url": "http://192.168.1.1/cgi-bin/status.cgi?page=lan&lang=en"},
+        "response": {
+          "status": 200,
+          "headers": [{"name": "Content-Type", "value": "application/json"}],
+          "content": {"text": "{\"lan\":\"up\"}"}
+        }
+      },
+      {
+        "request": {"method": "POST", "url": "http://192.168.1.1/cgi-bin/login.cgi"},
+        "response": {
+          "status": 302,
+          "headers": [{"name": "Location", "value": "/index.html"}],
+          "content": {"text": ""}
+        }
+      },
+      {
+        "request": {"method": "GET", "url": "http://192.168.1.1/aborted"},
+        "response": {"status": 0, "headers": [], "content": {}}
+      }
+    ]
+  }
+}`
+
+func importTestHAR(t *testing.T, dir string) *Recording {
+	path := filepath.Join(dir, "capture.har")
+	if err := ioutil.WriteFile(path, []byte(testHAR), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := Import(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rec
+}
+
+func TestImportHAR(t *testing.T) {
+	dir, err := ioutil.TempDir("", "melody-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rec := importTestHAR(t, dir)
+
+	if len(rec.Exchanges) != 4 {
+		t.Fatalf("expected 4 exchanges, got %d", len(rec.Exchanges))
+	}
+
+	login := rec.Exchanges[0]
+	if _, ok := login.Headers["Date"]; ok {
+		t.Error("the Date header should not be replayed")
+	}
+
+	if len(login.Headers["Set-Cookie"]) != 2 {
+		t.Errorf("expected 2 Set-Cookie headers, got %v", login.Headers["Set-Cookie"])
+	}
+
+	if rec.Server() != "GoAhead-Webs" {
+		t.Errorf("unexpected server '%s'", rec.Server())
+	}
+
+	order := rec.HeaderOrder()
+	if len(order) != 4 || order[0] != "Server" || order[1] != "Date" {
+		t.Errorf("unexpected header order %v", order)
+	}
+
+	if string(rec.Exchanges[1].Body()) != `{"wan":"up"}` {
+		t.Errorf("unexpected decoded body '%s'", rec.Exchanges[1].Body())
+	}
+}
+
+func TestImportRawDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "melody-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"index.request":  "GET /index.html HTTP/1.1\r\nHost: nas.local\r\n\r\n",
+		"index.response": "HTTP/1.1 200 OK\r\nServer: lighttpd/1.4.35\r\nContent-Type: text/html\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n",
+	}
+
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec, err := Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec.Exchanges) != 1 {
+		t.Fatalf("expected 1 exchange, got %d", len(rec.Exchanges))
+	}
+
+	ex := rec.Exchanges[0]
+	if ex.Path != "/index.html" || string(ex.Body()) != "hello" || ex.Headers["Server"][0] != "lighttpd/1.4.35" {
+		t.Errorf("unexpected exchange %+v", ex)
+	}
+
+	if _, ok := ex.Headers["Transfer-Encoding"]; ok {
+		t.Error("the Transfer-Encoding header should not be replayed")
+	}
+}
+
+func TestSaveLoadMatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "melody-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := importTestHAR(t, dir).Save(filepath.Join(dir, "router")); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := Load(filepath.Join(dir, "router"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method   string
+		target   string
+		fallback string
+		body     string
+		status   int
+	}{
+		{"GET", "/cgi-bin/status.cgi?page=lan&lang=en", "", `{"lan":"up"}`, 200},
+		{"GET", "/cgi-bin/status.cgi?lang=fr&page=lan", "", `{"lan":"up"}`, 200},
+		{"GET", "/cgi-bin/status.cgi?page=wan", "", `{"wan":"up"}`, 200},
+		{"GET", "/cgi-bin/login.cgi", "", "", 302},
+		{"GET", "/unknown", "/login.html", "<html>login</html>", 200},
+		{"GET", "/unknown", "", "next", 404},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("next"))
+	})
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		rec.Handler(next, test.fallback).ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+
+		if w.Code != test.status || w.Body.String() != test.body {
+			t.Errorf("%s %s : got %d '%s', expected %d '%s'", test.method, test.target, w.Code, w.Body.String(), test.status, test.body)
+		}
+	}
+}
+
+func TestLoadInvalidStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "melody-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	index := "exchanges:\n  - method: GET\n    path: /\n    status: 999\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, IndexFile), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(dir); err == nil {
+		t.Error("expected an error for an invalid status")
+	}
+}
+
+func TestLoadBodyFileOutside(t *testing.T) {
+	dir, err := ioutil.TempDir("", "melody-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recDir := filepath.Join(dir, "router")
+	if err := os.MkdirAll(filepath.Join(recDir, BodiesDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bodyFile := range []string{"../secret", "bodies/../../secret", "..", filepath.Join(dir, "secret")} {
+		index := "exchanges:\n  - method: GET\n    path: /\n    body_file: '" + bodyFile + "'\n"
+		if err := ioutil.WriteFile(filepath.Join(recDir, IndexFile), []byte(index), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := Load(recDir); err == nil {
+			t.Errorf("expected an error for the body file '%s'", bodyFile)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(recDir, BodiesDir, "index"), []byte("index"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	index := "exchanges:\n  - method: GET\n    path: /\n    body_file: bodies/./index\n"
+	if err := ioutil.WriteFile(filepath.Join(recDir, IndexFile), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := Load(recDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(rec.Exchanges[0].Body()) != "index" {
+		t.Error("body FAILED : got", string(rec.Exchanges[0].Body()))
+	}
+}
@@ -0,0 +1,232 @@
+package responders
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EncodingText is the encoding of the responders defining their banner and responses as text
+	EncodingText = "text"
+
+	// EncodingHex is the encoding of the responders defining their banner and responses as hex strings, used by the
+	// binary protocols
+	EncodingHex = "hex"
+)
+
+var (
+	// Loaded holds the responders loaded from the responders directory
+	Loaded []*Responder
+)
+
+// Responder describes a low-interaction TCP service sending a scripted banner, and answering the client messages
+// matching its replies
+type Responder struct {
+	Name    string
+	Ports   []int
+	Banner  []byte
+	Replies []Reply
+	Default []byte
+	// Raw disables the splitting of the client data into lines, each read being handled as a single message
+	Raw      bool
+	Encoding string
+	Timeout  time.Duration
+}
+
+// Reply describes the response sent to the client messages matching a regex
+type Reply struct {
+	Match    *regexp.Regexp
+	Response []byte
+	Close    bool
+}
+
+// RawResponder is the YAML definition of a Responder
+type RawResponder struct {
+	Ports    []int      `yaml:"ports"`
+	Banner   string     `yaml:"banner"`
+	Replies  []RawReply `yaml:"replies"`
+	Default  string     `yaml:"default"`
+	Raw      bool       `yaml:"raw"`
+	Encoding string     `yaml:"encoding"`
+	Timeout  string     `yaml:"timeout"`
+}
+
+// RawReply is the YAML definition of a Reply
+type RawReply struct {
+	Match    string `yaml:"match"`
+	Response string `yaml:"response"`
+	Close    bool   `yaml:"close"`
+}
+
+// Parse validates a RawResponder and creates the corresponding Responder
+func (raw RawResponder) Parse(name string) (*Responder, error) {
+	if len(raw.Ports) == 0 {
+		return nil, fmt.Errorf("responder '%s' : missing 'ports' key", name)
+	}
+
+	for _, port := range raw.Ports {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("responder '%s' : invalid port %d", name, port)
+		}
+	}
+
+	responder := &Responder{
+		Name:     name,
+		Ports:    raw.Ports,
+		Raw:      raw.Raw,
+		Encoding: raw.Encoding,
+	}
+
+	if responder.Encoding == "" {
+		responder.Encoding = EncodingText
+	} else if responder.Encoding != EncodingText && responder.Encoding != EncodingHex {
+		return nil, fmt.Errorf("responder '%s' : invalid encoding '%s' (wanted : %s or %s)", name, raw.Encoding, EncodingText, EncodingHex)
+	}
+
+	var err error
+	if responder.Banner, err = responder.decode(raw.Banner); err != nil {
+		return nil, fmt.Errorf("responder '%s' : failed to decode the banner : %s", name, err)
+	}
+
+	if responder.Default, err = responder.decode(raw.Default); err != nil {
+		return nil, fmt.Errorf("responder '%s' : failed to decode the default response : %s", name, err)
+	}
+
+	if raw.Timeout != "" {
+		timeout, err := time.ParseDuration(raw.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("responder '%s' : failed to parse the timeout value : %s", name, err)
+		}
+		responder.Timeout = timeout
+	}
+
+	for idx, rawReply := range raw.Replies {
+		match, err := regexp.Compile(rawReply.Match)
+		if err != nil {
+			return nil, fmt.Errorf("responder '%s' : failed to compile the match of reply %d : %s", name, idx, err)
+		}
+
+		response, err := responder.decode(rawReply.Response)
+		if err != nil {
+			return nil, fmt.Errorf("responder '%s' : failed to decode the response of reply %d : %s", name, idx, err)
+		}
+
+		responder.Replies = append(responder.Replies, Reply{
+			Match:    match,
+			Response: response,
+			Close:    rawReply.Close,
+		})
+	}
+
+	return responder, nil
+}
+
+// decode converts a banner or a response to bytes using the encoding of the responder. Whitespaces are ignored in
+// hex-encoded values
+func (r *Responder) decode(value string) ([]byte, error) {
+	if r.Encoding == EncodingHex {
+		return hex.DecodeString(strings.Join(strings.Fields(value), ""))
+	}
+
+	return []byte(value), nil
+}
+
+// Answer returns the response to a client message, and whether the connection should be closed afterwards. The
+// response of the first matching reply is used, or the default response if none matches. Text responses are expanded
+// with the submatches of the regex ($1, ${name})
+func (r *Responder) Answer(msg []byte) ([]byte, bool) {
+	for _, reply := range r.Replies {
+		submatches := reply.Match.FindSubmatchIndex(msg)
+		if submatches == nil {
+			continue
+		}
+
+		if r.Encoding == EncodingHex {
+			return reply.Response, reply.Close
+		}
+
+		return reply.Match.Expand(nil, reply.Response, msg, submatches), reply.Close
+	}
+
+	return r.Default, false
+}
+
+// LoadDir parses the YAML responder files of the given directory. Each file maps the responders' names to their
+// definition
+func LoadDir(dir string) ([]*Responder, error) {
+	var loaded []*Responder
+	ports := make(map[int]string)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the responders directory [%s] : %s", dir, err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || file.Name() == ".gitkeep" {
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+		if !strings.HasSuffix(path, ".yml") {
+			return nil, fmt.Errorf("invalid responder file (wanted : .yml) : %s", path)
+		}
+
+		responders, err := ParseFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read YAML responder file [%s] : %s", path, err)
+		}
+
+		for _, responder := range responders {
+			for _, port := range responder.Ports {
+				if other, ok := ports[port]; ok {
+					return nil, fmt.Errorf("responders '%s' and '%s' both listen on port %d", other, responder.Name, port)
+				}
+				ports[port] = responder.Name
+			}
+		}
+
+		loaded = append(loaded, responders...)
+	}
+
+	return loaded, nil
+}
+
+// ParseFile parses a YAML responder file, and returns its responders sorted by name
+func ParseFile(path string) ([]*Responder, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rawResponders := make(map[string]RawResponder)
+	if err := yaml.Unmarshal(data, &rawResponders); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range rawResponders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var responders []*Responder
+	for _, name := range names {
+		responder, err := rawResponders[name].Parse(name)
+		if err != nil {
+			return nil, err
+		}
+
+		responders = append(responders, responder)
+	}
+
+	return responders, nil
+}
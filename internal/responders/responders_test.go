@@ -0,0 +1,109 @@
+package responders
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadAvailable(t *testing.T) {
+	loaded, err := LoadDir("../../responders/responders-available")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded) == 0 {
+		t.Fatal("no responder loaded")
+	}
+
+	for _, responder := range loaded {
+		if responder.Name == "mysql" && (responder.Banner[4] != 0x0a || len(responder.Banner) != 95) {
+			t.Errorf("unexpected mysql banner %x", responder.Banner)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for name, raw := range map[string]RawResponder{
+		"no ports":      {},
+		"invalid port":  {Ports: []int{70000}},
+		"invalid regex": {Ports: []int{21}, Replies: []RawReply{{Match: "("}}},
+		"invalid hex":   {Ports: []int{21}, Encoding: EncodingHex, Banner: "zz"},
+		"invalid enc":   {Ports: []int{21}, Encoding: "rot13"},
+	} {
+		if _, err := raw.Parse(name); err == nil {
+			t.Errorf("%s : expected an error", name)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	responder, err := RawResponder{
+		Ports:  []int{21},
+		Banner: "220 ready\r\n",
+		Replies: []RawReply{
+			{Match: "^USER (.*)$", Response: "331 Password required for $1\r\n"},
+			{Match: "^QUIT", Response: "221 Goodbye.\r\n", Close: true},
+		},
+		Default: "530 Please login\r\n",
+	}.Parse("ftp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, server := net.Pipe()
+	sessions := make(chan Session, 1)
+	go func() {
+		sessions <- responder.Serve(server, time.Second, 1024)
+	}()
+
+	reader := bufio.NewReader(client)
+	for _, step := range []struct {
+		send     string
+		expected string
+	}{
+		{"", "220 ready\r\n"},
+		{"USER anonymous\r\n", "331 Password required for anonymous\r\n"},
+		{"PASS guest\r\n", "530 Please login\r\n"},
+		{"QUIT\r\n", "221 Goodbye.\r\n"},
+	} {
+		if step.send != "" {
+			if _, err := client.Write([]byte(step.send)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		line, err := reader.ReadString('\n')
+		if err != nil || line != step.expected {
+			t.Fatalf("unexpected response %q (%v), expected %q", line, err, step.expected)
+		}
+	}
+
+	session := <-sessions
+	if session.ClosedBy != ClosedByServer || len(session.Dialogue) != 7 || session.Truncated {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	if msg := session.Dialogue[1]; msg.From != FromClient || string(msg.Data) != "USER anonymous\r\n" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestServeTruncated(t *testing.T) {
+	responder, _ := RawResponder{Ports: []int{23}, Raw: true}.Parse("raw")
+
+	client, server := net.Pipe()
+	sessions := make(chan Session, 1)
+	go func() {
+		sessions <- responder.Serve(server, 50*time.Millisecond, 8)
+	}()
+
+	_, _ = client.Write([]byte(strings.Repeat("A", 16)))
+
+	session := <-sessions
+	if session.ClosedBy != ClosedByTimeout || !session.Truncated || len(session.Dialogue) != 1 || len(session.Dialogue[0].Data) != 8 {
+		t.Errorf("unexpected session %+v", session)
+	}
+}
@@ -0,0 +1,144 @@
+package responders
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/netutils"
+)
+
+const (
+	// FromClient is the source of the messages sent by the client
+	FromClient = "client"
+
+	// FromServer is the source of the messages sent by the responder
+	FromServer = "server"
+
+	// ClosedByClient is the reason of the sessions closed by the client
+	ClosedByClient = "client"
+
+	// ClosedByServer is the reason of the sessions closed after a reply asking to
+	ClosedByServer = "server"
+
+	// ClosedByTimeout is the reason of the sessions closed after the client stayed idle for too long
+	ClosedByTimeout = "timeout"
+
+	readSize = 4096
+)
+
+// Message describes a message of the dialogue between a client and a responder
+type Message struct {
+	From string
+	Time time.Time
+	Data []byte
+}
+
+// Session describes the dialogue between a client and a responder
+type Session struct {
+	SourceIP   string
+	SourcePort uint16
+	DestPort   uint16
+	Start      time.Time
+	Duration   time.Duration
+	Dialogue   []Message
+	// Truncated is set if the dialogue went over the maximum size, in which case the last messages are missing
+	Truncated bool
+	ClosedBy  string
+
+	recorded int
+	maxSize  int
+}
+
+func (s *Session) record(from string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	if s.recorded+len(data) > s.maxSize {
+		data = data[:s.maxSize-s.recorded]
+		s.Truncated = true
+	}
+
+	if len(data) > 0 {
+		s.recorded += len(data)
+		s.Dialogue = append(s.Dialogue, Message{From: from, Time: time.Now(), Data: append([]byte(nil), data...)})
+	}
+}
+
+// Serve sends the banner to the client, then answers its messages until it leaves, stays idle for longer than the
+// timeout, or a reply closes the connection. Up to maxSize bytes of the dialogue are recorded
+func (r *Responder) Serve(conn net.Conn, timeout time.Duration, maxSize int) Session {
+	defer conn.Close()
+
+	session := Session{Start: time.Now(), maxSize: maxSize}
+	session.SourceIP, session.SourcePort, session.DestPort = netutils.Endpoints(conn)
+
+	if r.Timeout > 0 {
+		timeout = r.Timeout
+	}
+
+	session.ClosedBy = r.dialogue(conn, &session, timeout)
+	session.Duration = time.Since(session.Start)
+
+	return session
+}
+
+func (r *Responder) dialogue(conn net.Conn, session *Session, timeout time.Duration) string {
+	if len(r.Banner) > 0 {
+		if _, err := conn.Write(r.Banner); err != nil {
+			return ClosedByClient
+		}
+		session.record(FromServer, r.Banner)
+	}
+
+	reader := bufio.NewReaderSize(conn, readSize)
+	buf := make([]byte, readSize)
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+
+		var msg []byte
+		var err error
+		if r.Raw {
+			var n int
+			n, err = reader.Read(buf)
+			msg = buf[:n]
+		} else {
+			// Overlong lines are handled in chunks
+			msg, err = reader.ReadSlice('\n')
+			if err == bufio.ErrBufferFull {
+				err = nil
+			}
+		}
+
+		session.record(FromClient, msg)
+
+		if len(msg) > 0 {
+			if !r.Raw {
+				msg = bytes.TrimRight(msg, "\r\n")
+			}
+
+			response, closing := r.Answer(msg)
+			if len(response) > 0 {
+				if _, err := conn.Write(response); err != nil {
+					return ClosedByClient
+				}
+				session.record(FromServer, response)
+			}
+
+			if closing {
+				return ClosedByServer
+			}
+		}
+
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return ClosedByTimeout
+			}
+
+			return ClosedByClient
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package router
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/credentials"
+	"github.com/bonjourmalware/melody/internal/logging"
+)
+
+const unauthorizedBody = "<html><head><title>401 Unauthorized</title></head><body><h1>401 Unauthorized</h1></body></html>\n"
+
+// authHandler challenges the clients requesting the protected paths and serves the fake login forms, so that the
+// credentials they send are logged along their requests
+func authHandler(h http.Handler, headers map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if challenge := config.Cfg.MatchAuthChallenge(r.URL.Path); challenge != nil {
+			creds := credentials.FromAuthorization(r.Header.Get("Authorization"), r.Method)
+			if creds == nil || creds.Scheme != challenge.Scheme || !challenge.Accept {
+				unauthorized(w, r, challenge, headers)
+				return
+			}
+		}
+
+		if form := config.Cfg.MatchAuthForm(r.URL.Path); form != nil && form.Page != "" {
+			// The posted credentials are always refused
+			servePage(w, form.Page, headers)
+			return
+		}
+
+		h.ServeHTTP(w, r) // pass request
+	})
+}
+
+// unauthorized answers the request with the challenge of the scheme protecting its path
+func unauthorized(w http.ResponseWriter, r *http.Request, challenge *config.AuthChallenge, headers map[string]string) {
+	for header, val := range headers {
+		w.Header().Set(header, val)
+	}
+
+	switch challenge.Scheme {
+	case credentials.SchemeBasic:
+		w.Header().Set("WWW-Authenticate", credentials.BasicChallenge(challenge.Realm))
+	case credentials.SchemeDigest:
+		w.Header().Set("WWW-Authenticate", credentials.DigestChallenge(challenge.Realm))
+	case credentials.SchemeNTLM:
+		w.Header().Set("WWW-Authenticate", "NTLM")
+		if isNTLMNegotiate(r.Header.Get("Authorization")) {
+			msg := credentials.NTLMChallenge(config.Cfg.ServerAuthNTLMDomain, config.Cfg.ServerAuthNTLMComputer)
+			w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(msg))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusUnauthorized)
+	_, _ = w.Write([]byte(unauthorizedBody))
+}
+
+func isNTLMNegotiate(header string) bool {
+	idx := strings.Index(header, " ")
+	if idx < 0 {
+		return false
+	}
+
+	scheme := strings.ToLower(header[:idx])
+	if scheme != credentials.SchemeNTLM && scheme != "negotiate" {
+		return false
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(header[idx+1:]))
+	if err != nil {
+		return false
+	}
+
+	return credentials.IsNTLMNegotiate(raw)
+}
+
+// servePage sends the page of a fake login form
+func servePage(w http.ResponseWriter, page string, headers map[string]string) {
+	content, err := ioutil.ReadFile(page)
+	if err != nil {
+		logging.Errors.Printf("failed to read the login form page '%s' : %s", page, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	for header, val := range headers {
+		w.Header().Set(header, val)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(content)
+}
@@ -0,0 +1,88 @@
+package router
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/credentials"
+)
+
+var nextHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte("next"))
+})
+
+func TestAuthHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "melody-router")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	page := filepath.Join(dir, "login.html")
+	if err := ioutil.WriteFile(page, []byte("<form>login</form>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config.Cfg.ServerAuthChallenges = []config.AuthChallenge{
+		{Path: "/basic", Scheme: credentials.SchemeBasic, Realm: `Admin "zone"`},
+		{Path: "/digest", Scheme: credentials.SchemeDigest, Realm: "Admin"},
+		{Path: "/ntlm", Scheme: credentials.SchemeNTLM},
+		{Path: "/accept", Scheme: credentials.SchemeBasic, Realm: "Admin", Accept: true},
+	}
+	config.Cfg.ServerAuthForms = []config.AuthForm{{Path: "/login", Page: page}}
+	defer func() {
+		config.Cfg.ServerAuthChallenges = nil
+		config.Cfg.ServerAuthForms = nil
+	}()
+
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:password"))
+	negotiate := "NTLM " + base64.StdEncoding.EncodeToString([]byte("NTLMSSP\x00\x01\x00\x00\x00"))
+
+	tests := []struct {
+		method        string
+		path          string
+		authorization string
+		status        int
+		challenge     string
+		body          string
+	}{
+		{"GET", "/basic/admin", "", 401, `Basic realm="Admin \"zone\""`, unauthorizedBody},
+		{"GET", "/basic/admin", basic, 401, `Basic realm="Admin \"zone\""`, unauthorizedBody},
+		{"GET", "/digest", "", 401, `Digest realm="Admin", qop="auth", nonce="`, unauthorizedBody},
+		{"GET", "/ntlm", "", 401, "NTLM", unauthorizedBody},
+		{"GET", "/ntlm", negotiate, 401, "NTLM TlRMTVNTUAACAAAA", unauthorizedBody},
+		{"GET", "/accept", "", 401, `Basic realm="Admin"`, unauthorizedBody},
+		{"GET", "/accept", "Digest username=\"admin\"", 401, `Basic realm="Admin"`, unauthorizedBody},
+		{"GET", "/accept", basic, 200, "", "next"},
+		{"GET", "/login", "", 200, "", "<form>login</form>"},
+		{"POST", "/login", "", 200, "", "<form>login</form>"},
+		{"GET", "/public", "", 200, "", "next"},
+	}
+
+	handler := authHandler(nextHandler, map[string]string{"Server": "Apache"})
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.authorization != "" {
+			r.Header.Set("Authorization", test.authorization)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		challenge := w.Header().Get("WWW-Authenticate")
+		if w.Code != test.status || !strings.HasPrefix(challenge, test.challenge) || w.Body.String() != test.body {
+			t.Errorf("%s %s (%s) : got %d %q '%s'", test.method, test.path, test.authorization, w.Code, challenge, w.Body.String())
+		}
+
+		if test.challenge != "" && w.Header().Get("Server") != "Apache" {
+			t.Errorf("%s %s : missing the server headers", test.method, test.path)
+		}
+	}
+}
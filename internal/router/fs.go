@@ -6,6 +6,8 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+
+	"github.com/bonjourmalware/melody/internal/templating"
 )
 
 // https://www.alexedwards.net/blog/disable-http-fileserver-directory-listings#using-a-custom-filesystem
@@ -28,10 +30,22 @@ func melodyFs(root http.FileSystem, notFoundCode int) http.Handler {
 		}
 		upath = path.Clean(upath)
 
+		// Never serve the templates' source
+		if strings.HasSuffix(upath, templating.Extension) {
+			w.WriteHeader(notFoundCode)
+			_, _ = w.Write([]byte{})
+			return
+		}
+
 		// attempt to open the file via the http.FileSystem
 		f, err := root.Open(upath)
 		if err != nil {
 			if os.IsNotExist(err) {
+				if tmplPath, ok := findTemplate(root, upath); ok {
+					serveTemplate(w, r, root, tmplPath)
+					return
+				}
+
 				w.WriteHeader(notFoundCode)
 				_, _ = w.Write([]byte{})
 				return
@@ -44,6 +58,11 @@ func melodyFs(root http.FileSystem, notFoundCode int) http.Handler {
 			if _, err := root.Open(index); err != nil {
 				_ = f.Close()
 				if os.IsNotExist(err) {
+					if tmplPath, ok := findTemplate(root, index); ok {
+						serveTemplate(w, r, root, tmplPath)
+						return
+					}
+
 					w.WriteHeader(notFoundCode)
 					_, _ = w.Write([]byte{})
 					return
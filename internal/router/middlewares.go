@@ -1,14 +1,23 @@
 package router
 
 import (
+	"context"
 	"net/http"
 	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/dedup"
+	"github.com/bonjourmalware/melody/internal/rules"
+	"github.com/bonjourmalware/melody/internal/templating"
 
 	"github.com/bonjourmalware/melody/internal/config"
 
 	"github.com/bonjourmalware/melody/internal/logging"
 
 	"github.com/bonjourmalware/melody/internal/events"
+
+	"github.com/rs/xid"
 )
 
 func (nfs neuteredFileSystem) Open(path string) (http.File, error) {
@@ -42,23 +51,147 @@ func headersHandler(h http.Handler, headers map[string]string) http.Handler {
 	})
 }
 
-func httpsLogger(h http.Handler, eventChan chan events.Event) http.Handler {
+// loggedRequestContextKey is used to pass the loggedRequest of the requestLogger to the next handlers
+const loggedRequestContextKey contextKey = "logged_request"
+
+// loggedRequest is the event of a request handled by the requestLogger, sent at most once
+type loggedRequest struct {
+	ev        *events.HTTPEvent
+	eventChan chan events.Event
+	once      sync.Once
+}
+
+func (lr *loggedRequest) send() {
+	lr.once.Do(func() {
+		lr.eventChan <- lr.ev
+	})
+}
+
+// requestLogger sends the events generated from the requests received by the dummy servers. When the packet capture
+// is active, the HTTP events are only sent if the same request has not been sniffed
+func requestLogger(h http.Handler, eventChan chan events.Event) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if _, ok := config.Cfg.DiscardProto4[config.HTTPSKind]; ok {
+		kind := config.HTTPKind
+		if r.TLS != nil {
+			kind = config.HTTPSKind
+		}
+
+		if _, ok := config.Cfg.DiscardProto4[kind]; ok {
 			h.ServeHTTP(w, r) // pass request
 			return
-		} else if _, ok := config.Cfg.DiscardProto6[config.HTTPSKind]; ok {
+		} else if _, ok := config.Cfg.DiscardProto6[kind]; ok {
 			h.ServeHTTP(w, r) // pass request
 			return
 		}
 
-		ev, err := events.NewHTTPEventFromRequest(r)
+		ev, r, err := requestEvent(r)
 		if err != nil {
 			logging.Errors.Println(err)
 			return
 		}
-		eventChan <- ev
 
-		h.ServeHTTP(w, r) // pass request
+		// The sensor drops the sniffed requests sent through the trusted proxies, as they lack the client address
+		lr := &loggedRequest{ev: ev, eventChan: eventChan}
+		if kind == config.HTTPKind && !config.Cfg.IsServerOnly() && ev.Proxy == nil {
+			go sendUnlessSniffed(lr)
+		} else {
+			lr.send()
+		}
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), loggedRequestContextKey, lr))) // pass request
+	})
+}
+
+// sendUnlessSniffed gives the sensor some time to reassemble the same request from the captured packets, and only
+// sends the event if it did not
+func sendUnlessSniffed(lr *loggedRequest) {
+	time.Sleep(dedup.Window)
+
+	ev := lr.ev
+	if dedup.SniffedHTTPRequests.Seen(dedup.HTTPKey(ev.SourceIP, ev.SourcePort, ev.Verb, ev.RequestURI)) {
+		return
+	}
+
+	lr.send()
+}
+
+// logRequestSession sends the event of a request taken over by a handler, such as the WebSocket server or the tarpit,
+// and returns its session to link the handler's events to it. The event is sent even if the request is sniffed or if
+// the server does not log its requests, as the sniffed event is on another session. It is only sent once
+func logRequestSession(r *http.Request, eventChan chan events.Event) string {
+	if lr, ok := r.Context().Value(loggedRequestContextKey).(*loggedRequest); ok {
+		lr.send()
+		return lr.ev.Session
+	}
+
+	kind := config.HTTPKind
+	if r.TLS != nil {
+		kind = config.HTTPSKind
+	}
+
+	if _, ok := config.Cfg.DiscardProto4[kind]; ok {
+		return xid.New().String()
+	} else if _, ok := config.Cfg.DiscardProto6[kind]; ok {
+		return xid.New().String()
+	}
+
+	ev, _, err := requestEvent(r)
+	if err != nil {
+		logging.Errors.Println(err)
+		return xid.New().String()
+	}
+
+	eventChan <- ev
+	return ev.Session
+}
+
+// responseHandler sends back the response defined by the first rule matching the request, if any
+func responseHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kind := config.HTTPKind
+		if r.TLS != nil {
+			kind = config.HTTPSKind
+		}
+
+		if !rules.HasResponses(kind) {
+			h.ServeHTTP(w, r) // pass request
+			return
+		}
+
+		ev, r, err := requestEvent(r)
+		if err != nil {
+			logging.Errors.Println(err)
+			h.ServeHTTP(w, r) // pass request
+			return
+		}
+
+		rule := rules.MatchResponse(ev)
+		if rule == nil {
+			h.ServeHTTP(w, r) // pass request
+			return
+		}
+
+		if rule.Response.Delay > 0 {
+			select {
+			case <-time.After(rule.Response.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		for header, val := range rule.Response.Headers {
+			w.Header().Set(header, val)
+		}
+
+		body := rule.Response.Body
+		if rule.Response.Template != nil {
+			body, err = templating.Render(rule.Response.Template, newTemplateData(r, ev, rules.Rules{*rule}))
+			if err != nil {
+				logging.Errors.Printf("failed to render the response template of rule '%s' : %s", rule.Name, err)
+			}
+		}
+
+		w.WriteHeader(rule.Response.Status)
+		_, _ = w.Write(body)
 	})
 }
@@ -0,0 +1,170 @@
+package router
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/dedup"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/proxyproto"
+	"github.com/bonjourmalware/melody/internal/rules"
+)
+
+func init() {
+	config.Cfg = config.NewConfig()
+
+	// The tarpit shared by the tests holds a single client, and releases it quickly
+	config.Cfg.ServerTarpitInterval = 10 * time.Millisecond
+	config.Cfg.ServerTarpitMaxDuration = 500 * time.Millisecond
+	config.Cfg.ServerTarpitMaxConnections = 1
+}
+
+func TestRequestLoggerProxied(t *testing.T) {
+	// The sniffed requests are deduplicated in the capture mode
+	config.Cfg.ServerHTTPLogRequests = true
+	defer func() {
+		config.Cfg.ServerHTTPLogRequests = false
+	}()
+
+	eventChan := make(chan events.Event, 1)
+	srv := httptest.NewUnstartedServer(requestLogger(http.NotFoundHandler(), eventChan))
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	srv.Listener = proxyproto.NewListener(srv.Listener, []*net.IPNet{loopback})
+	srv.Config.ConnContext = proxyproto.ConnContext
+	srv.Start()
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, _ = fmt.Fprint(conn, "PROXY TCP4 192.0.2.1 127.0.0.1 4242 80\r\nGET /proxied HTTP/1.1\r\nHost: example.com\r\n\r\n")
+
+	// The event of a proxied request is kept even if the request has been sniffed, and sent without waiting for it
+	dedup.SniffedHTTPRequests.Add(dedup.HTTPKey("192.0.2.1", 4242, "GET", "/proxied"))
+	defer dedup.SniffedHTTPRequests.FlushAll()
+
+	select {
+	case ev := <-eventChan:
+		httpEv := ev.(*events.HTTPEvent)
+		if httpEv.SourceIP != "192.0.2.1" || httpEv.SourcePort != 4242 || httpEv.Proxy == nil {
+			t.Errorf("unexpected event %+v", httpEv)
+		}
+	case <-time.After(dedup.Window / 2):
+		t.Fatal("the event of the proxied request has not been sent")
+	}
+}
+
+const responseRules = `forbidden:
+  layer: http
+  id: 0e7d4a92-1f6b-4c3e-8d25-b94a7f1c3e68
+  match:
+    http.uri:
+      is:
+        - "/admin"
+  response:
+    status: 403
+    headers:
+      X-Rule: "forbidden"
+    body: "Forbidden"
+
+echo:
+  layer: http
+  id: 2d8b5f3e-9a61-4c07-b4e2-7f1c0a9d3b58
+  match:
+    http.uri:
+      regex:
+        - '^/cgi-bin/echo\?msg=(?P<msg>.+)$'
+  response:
+    template: true
+    body: '{{ .Capture "msg" }}'
+`
+
+// loadRules replaces the HTTP rules with the ones of the given YAML ruleset until the returned function is called
+func loadRules(t *testing.T, ruleset string) func() {
+	f, err := ioutil.TempFile("", "melody-rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	_, _ = f.WriteString(ruleset)
+	_ = f.Close()
+
+	rawRules, err := rules.ParseYAMLRulesFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded rules.Rules
+	for name, rawRule := range rawRules {
+		rule, err := rawRule.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule.Name = name
+		loaded = append(loaded, rule)
+	}
+
+	backup := rules.GlobalRules[config.HTTPKind]
+	rules.GlobalRules[config.HTTPKind] = []rules.Rules{loaded}
+
+	return func() {
+		rules.GlobalRules[config.HTTPKind] = backup
+	}
+}
+
+func TestResponseHandler(t *testing.T) {
+	handler := responseHandler(nextHandler)
+
+	// Without response rules, the requests are passed without generating their event
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/admin", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "next" {
+		t.Errorf("unexpected response without rules : %d '%s'", w.Code, w.Body.String())
+	}
+
+	defer loadRules(t, responseRules)()
+
+	tests := []struct {
+		target string
+		status int
+		body   string
+		header string
+	}{
+		{"/admin", 403, "Forbidden", "forbidden"},
+		{"/cgi-bin/echo?msg=hello", 200, "hello", ""},
+		{"/index.html", 200, "next", ""},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+
+		if w.Code != test.status || w.Body.String() != test.body || w.Header().Get("X-Rule") != test.header {
+			t.Errorf("%s : got %d '%s' %v, expected %d '%s'", test.target, w.Code, w.Body.String(), w.Header(), test.status, test.body)
+		}
+	}
+
+	// The event attached by a previous handler is matched rather than generated again
+	r := httptest.NewRequest("GET", "/index.html", nil)
+	ev, _, err := requestEvent(httptest.NewRequest("GET", "/admin", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, withEvent(r, ev))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("the attached event has not been used : %d '%s'", w.Code, w.Body.String())
+	}
+}
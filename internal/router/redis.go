@@ -0,0 +1,51 @@
+package router
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/logging"
+	"github.com/bonjourmalware/melody/internal/redisd"
+)
+
+// StartRedis starts the Redis server on the given port. An event holding the commands is sent at the end of each
+// session
+func StartRedis(quitErrChan chan error, eventChan chan events.Event, port int) {
+	server := redisd.New(redisd.Options{
+		Version:            config.Cfg.ServerRedisVersion,
+		MaxSize:            int(config.Cfg.ServerRedisMaxSize),
+		MaxCommands:        config.Cfg.ServerRedisMaxCommands,
+		Timeout:            config.Cfg.ServerRedisTimeout,
+		MaxDuration:        config.Cfg.ServerRedisMaxDuration,
+		FetchReplication:   config.Cfg.ServerRedisReplicationFetch,
+		ReplicationTimeout: config.Cfg.ServerRedisReplicationTimeout,
+	})
+
+	ln, err := net.Listen("tcp", net.JoinHostPort(config.Cfg.ServerRedisAddress, strconv.Itoa(port)))
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	logging.Std.Println("Started Redis server on", ln.Addr())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			quitErrChan <- err
+			return
+		}
+
+		go func() {
+			eventChan <- events.NewRedisEvent(server.Serve(conn))
+		}()
+	}
+}
@@ -0,0 +1,42 @@
+package router
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/logging"
+	"github.com/bonjourmalware/melody/internal/responders"
+)
+
+// StartResponder starts a listener for the given responder on the given port. An event holding the dialogue is sent
+// at the end of each session
+func StartResponder(quitErrChan chan error, eventChan chan events.Event, responder *responders.Responder, port int) {
+	ln, err := net.Listen("tcp", net.JoinHostPort(config.Cfg.RespondersAddress, strconv.Itoa(port)))
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	logging.Std.Printf("Started responder '%s' on %s\n", responder.Name, ln.Addr())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			quitErrChan <- err
+			return
+		}
+
+		go func() {
+			session := responder.Serve(conn, config.Cfg.RespondersTimeout, int(config.Cfg.RespondersMaxSize))
+			eventChan <- events.NewResponderEvent(responder.Name, session)
+		}()
+	}
+}
@@ -1,9 +1,17 @@
 package router
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bonjourmalware/melody/internal/certs"
+	"github.com/bonjourmalware/melody/internal/persona"
+	"github.com/bonjourmalware/melody/internal/proxyproto"
 
 	"github.com/bonjourmalware/melody/internal/events"
 
@@ -12,33 +20,256 @@ import (
 	"github.com/bonjourmalware/melody/internal/config"
 )
 
-// StartHTTP starts the dummy HTTP server
-func StartHTTP(quitErrChan chan error) {
-	r := http.NewServeMux()
-	r.Handle("/",
-		headersHandler(
-			melodyFs(http.Dir(config.Cfg.ServerHTTPDir), config.Cfg.ServerHTTPMissingResponseStatus),
-			config.Cfg.ServerHTTPHeaders))
-
-	logging.Std.Println("Started HTTP server on port", config.Cfg.ServerHTTPPort)
-	quitErrChan <- http.ListenAndServe(fmt.Sprintf(":%d", config.Cfg.ServerHTTPPort), r)
+// StartHTTP starts the dummy HTTP server. It sends the events generated from the requests it receives if
+// server.http.log_requests is set or if the packet capture is disabled
+func StartHTTP(quitErrChan chan error, eventChan chan events.Event) {
+	startServer(quitErrChan, eventChan, config.Cfg.HTTPListener())
 }
 
 // StartHTTPS starts the dummy HTTPS server
 func StartHTTPS(quitErrChan chan error, eventChan chan events.Event) {
+	startServer(quitErrChan, eventChan, config.Cfg.HTTPSListener())
+}
+
+// StartListener starts an additional dummy HTTP/S server from the server.listeners list
+func StartListener(quitErrChan chan error, eventChan chan events.Event, l config.Listener) {
+	startServer(quitErrChan, eventChan, l)
+}
+
+// startServer starts a dummy HTTP/S server on the given listener. The HTTP events are sent under the same conditions
+// as for the main HTTP server, while the HTTPS events are always sent
+func startServer(quitErrChan chan error, eventChan chan events.Event, l config.Listener) {
+	p, err := loadPersona(l.Persona)
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	handler, err := newServerHandler(l, p, eventChan)
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
 	r := http.NewServeMux()
-	r.Handle("/",
-		httpsLogger(
-			headersHandler(
-				melodyFs(http.Dir(config.Cfg.ServerHTTPSDir), config.Cfg.ServerHTTPSMissingResponseStatus),
-				config.Cfg.ServerHTTPSHeaders), eventChan))
+	r.Handle("/", handler)
 
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", config.Cfg.ServerHTTPSPort),
-		Handler:      r,
-		TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
+		Handler: r,
+	}
+
+	ln, err := listen(l.Address, l.Port, l.ProxyProtocol)
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	if !l.TLS {
+		// Replace the error responses sent by net/http to the malformed requests
+		if p != nil {
+			ln = p.Listener(ln)
+		}
+
+		logging.Std.Println("Started HTTP server on", ln.Addr())
+		quitErrChan <- serve(srv, ln)
+		return
+	}
+
+	srv.TLSConfig = &tls.Config{
+		NextProtos: []string{"http/1.1"},
+	}
+
+	// A non-nil and empty TLSNextProto disables HTTP/2 negotiation
+	if l.HTTP2 {
+		srv.TLSConfig.NextProtos = []string{"h2", "http/1.1"}
+	} else {
+		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+
+	store, err := loadHTTPSCertificates(l.Cert, l.Key)
+	if err != nil {
+		_ = ln.Close()
+		quitErrChan <- err
+		return
+	}
+
+	// The certificates are served by the store
+	srv.TLSConfig.GetCertificate = store.GetCertificate
+
+	logging.Std.Println("Started HTTPS server on", ln.Addr())
+	// The TLS listener is set up here rather than by ServeTLS so that the persona can hand the TLS connections back
+	quitErrChan <- serve(srv, tls.NewListener(ln, srv.TLSConfig))
+}
+
+// serve serves the connections of the listener. The connections hijacked by the personas of the server or of its
+// virtual hosts to write their responses are served again afterwards when they are kept alive
+func serve(srv *http.Server, ln net.Listener) error {
+	kl := persona.NewKeepAliveListener(ln)
+	srv.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		return kl.ConnContext(proxyproto.ConnContext(ctx, c), c)
+	}
+
+	return srv.Serve(kl)
+}
+
+// newServerHandler creates the handler chain of a dummy server using the given persona. The requests go through the
+// requestLogger first, then the WebSocket upgrades, the tarpit, the authentication and the virtual hosts
+func newServerHandler(l config.Listener, p *persona.Persona, eventChan chan events.Event) (http.Handler, error) {
+	handler, err := newVHostsHandler(newHandler(l.Dir, l.MissingStatusCode, l.Headers, p), l.VHosts, l.MissingStatusCode, l.Headers, l.Persona)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(config.Cfg.ServerAuthChallenges) > 0 || len(config.Cfg.ServerAuthForms) > 0 {
+		handler = authHandler(handler, l.Headers)
+	}
+
+	if l.Tarpit != "" {
+		handler = tarpitHandler(handler, l.Tarpit, eventChan)
+	}
+
+	if len(config.Cfg.ServerWebSocketPaths) > 0 {
+		handler = webSocketHandler(handler, eventChan)
+	}
+
+	if l.TLS || config.Cfg.LogHTTPRequests() {
+		handler = requestLogger(handler, eventChan)
+	}
+
+	return handler, nil
+}
+
+// listen opens the TCP listener of a dummy server on the given address, or on all the addresses if empty. It reads
+// the PROXY protocol headers sent by the trusted proxies if proxyProtocol is set
+func listen(address string, port int, proxyProtocol bool) (net.Listener, error) {
+	ln, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyProtocol {
+		ln = proxyproto.NewListener(ln, config.Cfg.ServerProxyProtocolTrustedCIDRs)
+	}
+
+	return ln, nil
+}
+
+// newHandler creates the handler chain of a dummy server, serving the given directory as the given persona if not nil
+func newHandler(dir string, missingStatus int, headers map[string]string, p *persona.Persona) http.Handler {
+	fs := melodyFs(http.Dir(dir), missingStatus)
+	if p != nil {
+		fs = p.MethodsHandler(fs)
+
+		// The recorded exchanges take precedence over the persona's handling of the methods
+		if p.Replay != nil {
+			fs = p.Replay.Handler(fs, p.ReplayFallback)
+		}
+	}
+
+	h := headersHandler(responseHandler(fs), headers)
+	if p != nil {
+		h = p.Handler(h)
+	}
+
+	return h
+}
+
+// newVHostsHandler routes the requests to the handler of the virtual host matching their Host header, or to the
+// default handler if none does. The virtual hosts inherit the missing status code, headers and persona of the server
+// if they do not set their own
+func newVHostsHandler(def http.Handler, vhosts []config.VHost, missingStatus int, headers map[string]string, personaName string) (http.Handler, error) {
+	if len(vhosts) == 0 {
+		return def, nil
+	}
+
+	handlers := make(map[string]http.Handler)
+	for _, vhost := range vhosts {
+		vhostMissingStatus := missingStatus
+		if vhost.MissingStatusCode != 0 {
+			vhostMissingStatus = vhost.MissingStatusCode
+		}
+
+		vhostHeaders := headers
+		if vhost.Headers != nil {
+			vhostHeaders = vhost.Headers
+		}
+
+		vhostPersona := personaName
+		if vhost.Persona != "" {
+			vhostPersona = vhost.Persona
+		}
+
+		p, err := loadPersona(vhostPersona)
+		if err != nil {
+			return nil, fmt.Errorf("virtual host '%s' : %s", vhost.Name, err)
+		}
+
+		handlers[vhost.Name] = newHandler(vhost.Dir, vhostMissingStatus, vhostHeaders, p)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if vhost := config.MatchVHost(vhosts, r.Host); vhost != nil {
+			handlers[vhost.Name].ServeHTTP(w, r)
+			return
+		}
+
+		def.ServeHTTP(w, r)
+	}), nil
+}
+
+// loadPersona returns the persona with the given name, or nil if empty
+func loadPersona(name string) (*persona.Persona, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	return persona.Get(name, config.Cfg.ServerPersonasDir)
+}
+
+// loadHTTPSCertificates loads the given default certificate and the ones selected by SNI, generating the missing ones
+// if server.https.autocert.enable is set
+func loadHTTPSCertificates(certPath string, keyPath string) (*certs.Store, error) {
+	opts := certs.Options{
+		Subject:      config.Cfg.ServerHTTPSAutocertSubject,
+		SANs:         config.Cfg.ServerHTTPSAutocertSANs,
+		KeyType:      config.Cfg.ServerHTTPSAutocertKeyType,
+		ValidityDays: config.Cfg.ServerHTTPSAutocertValidityDays,
+	}
+
+	defaultCert, err := certs.LoadOrGenerate(certPath, keyPath, config.Cfg.ServerHTTPSAutocertEnable, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	store := certs.NewStore(defaultCert)
+
+	for _, entry := range config.Cfg.ServerHTTPSCertificates {
+		// Generated certificates use the first host as common name and all of the hosts as SANs
+		hostOpts := opts
+		hostOpts.Subject = setCommonName(opts.Subject, entry.Hosts[0])
+		hostOpts.SANs = entry.Hosts
+
+		cert, err := certs.LoadOrGenerate(entry.Cert, entry.Key, config.Cfg.ServerHTTPSAutocertEnable, hostOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		store.Add(entry.Hosts, cert)
+	}
+
+	return store, nil
+}
+
+// setCommonName replaces the CN attribute of a subject written in the openssl one-line format
+func setCommonName(subject string, commonName string) string {
+	var parts []string
+
+	for _, part := range strings.Split(subject, "/") {
+		if part == "" || strings.HasPrefix(strings.ToUpper(part), "CN=") {
+			continue
+		}
+		parts = append(parts, part)
 	}
 
-	logging.Std.Println("Started HTTPS server on port", config.Cfg.ServerHTTPSPort)
-	quitErrChan <- srv.ListenAndServeTLS(config.Cfg.ServerHTTPSCert, config.Cfg.ServerHTTPSKey)
+	return "/" + strings.Join(append(parts, "CN="+commonName), "/")
 }
@@ -0,0 +1,171 @@
+package router
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/credentials"
+	"github.com/bonjourmalware/melody/internal/events"
+)
+
+const webSocketUpgrade = "Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+
+// statusLine sends the raw request to the server and returns the status line of the response. The connection is
+// closed afterwards
+func statusLine(t *testing.T, srv *httptest.Server, request string) string {
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprint(conn, request); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimSpace(line)
+}
+
+// eventTypes collects the types of the events sent until no event is received for a while
+func eventTypes(eventChan chan events.Event) []string {
+	var types []string
+	for {
+		select {
+		case ev := <-eventChan:
+			types = append(types, ev.GetKind())
+		case <-time.After(800 * time.Millisecond):
+			return types
+		}
+	}
+}
+
+// newTestDir creates a directory serving an index.html file with the given content
+func newTestDir(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "melody-router")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestServerHandlerOrder(t *testing.T) {
+	dir := newTestDir(t, "default")
+	defer os.RemoveAll(dir)
+
+	listenMode := config.Cfg.ListenMode
+	config.Cfg.ListenMode = config.ListenModeServerOnly
+	config.Cfg.ServerAuthChallenges = []config.AuthChallenge{{Path: "/secret", Scheme: credentials.SchemeBasic, Realm: "Admin"}}
+	config.Cfg.ServerWebSocketPaths = []string{"/secret/ws"}
+	defer func() {
+		config.Cfg.ListenMode = listenMode
+		config.Cfg.ServerAuthChallenges = nil
+		config.Cfg.ServerWebSocketPaths = nil
+	}()
+
+	tests := []struct {
+		tarpit  string
+		request string
+		status  string
+		events  []string
+	}{
+		// The requests are logged before being answered by the authentication or the virtual hosts
+		{"", "GET /secret HTTP/1.1\r\nHost: www.example.com\r\n\r\n", "HTTP/1.1 401 Unauthorized", []string{"http"}},
+		// The WebSocket upgrades are completed before the authentication
+		{"", "GET /secret/ws HTTP/1.1\r\nHost: www.example.com\r\n" + webSocketUpgrade + "\r\n", "HTTP/1.1 101 Switching Protocols", []string{"http"}},
+		// The tarpit traps the clients before the authentication
+		{"headers", "GET /secret HTTP/1.1\r\nHost: www.example.com\r\n\r\n", "HTTP/1.1 200 OK", []string{"http", "tarpit"}},
+		// The WebSocket upgrades are completed before the tarpit
+		{"headers", "GET /secret/ws HTTP/1.1\r\nHost: www.example.com\r\n" + webSocketUpgrade + "\r\n", "HTTP/1.1 101 Switching Protocols", []string{"http"}},
+	}
+
+	for _, test := range tests {
+		eventChan := make(chan events.Event, 16)
+		handler, err := newServerHandler(config.Listener{Dir: dir, MissingStatusCode: 404, Tarpit: test.tarpit}, nil, eventChan)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		srv := httptest.NewServer(handler)
+		if status := statusLine(t, srv, test.request); status != test.status {
+			t.Errorf("%q (tarpit '%s') : unexpected status %q", test.request, test.tarpit, status)
+		}
+
+		if types := eventTypes(eventChan); strings.Join(types, ",") != strings.Join(test.events, ",") {
+			t.Errorf("%q (tarpit '%s') : unexpected events %v", test.request, test.tarpit, types)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestVHostsHandler(t *testing.T) {
+	def, wildcard, exact := newTestDir(t, "default"), newTestDir(t, "wildcard"), newTestDir(t, "exact")
+	defer os.RemoveAll(def)
+	defer os.RemoveAll(wildcard)
+	defer os.RemoveAll(exact)
+
+	vhosts := []config.VHost{
+		{Name: "wildcard", Hosts: []string{"*.example.com"}, Dir: wildcard, MissingStatusCode: 403, Headers: map[string]string{"Server": "nginx"}},
+		{Name: "exact", Hosts: []string{"www.example.com", "exact.test"}, Dir: exact},
+	}
+
+	headers := map[string]string{"Server": "Apache"}
+	handler, err := newVHostsHandler(newHandler(def, 404, headers, nil), vhosts, 404, headers, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host   string
+		path   string
+		status int
+		body   string
+		server string
+	}{
+		{"app.example.com", "/", 200, "wildcard", "nginx"},
+		{"app.example.com:8080", "/", 200, "wildcard", "nginx"},
+		{"app.example.com", "/missing", 403, "", "nginx"},
+		// The first matching virtual host is used
+		{"www.example.com", "/", 200, "wildcard", "nginx"},
+		{"exact.test", "/", 200, "exact", "Apache"},
+		{"exact.test", "/missing", 404, "", "Apache"},
+		{"example.com", "/", 200, "default", "Apache"},
+		{"192.0.2.1", "/", 200, "default", "Apache"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.path, nil)
+		r.Host = test.host
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != test.status || (test.body != "" && w.Body.String() != test.body) || w.Header().Get("Server") != test.server {
+			t.Errorf("%s%s : got %d '%s' (%s)", test.host, test.path, w.Code, w.Body.String(), w.Header().Get("Server"))
+		}
+	}
+
+	if _, err := newVHostsHandler(handler, []config.VHost{{Name: "broken", Hosts: []string{"a.test"}, Persona: "unknown"}}, 404, headers, ""); err == nil {
+		t.Error("expected an error for an unknown persona")
+	}
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"crypto/tls"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/logging"
+	"github.com/bonjourmalware/melody/internal/smtpd"
+)
+
+// StartSMTP starts the SMTP server on the given port. The STARTTLS command uses the certificates of the dummy HTTPS
+// server. An event is sent at the end of each session
+func StartSMTP(quitErrChan chan error, eventChan chan events.Event, port int) {
+	opts := smtpd.Options{
+		Hostname:      config.Cfg.ServerSMTPHostname,
+		Banner:        config.Cfg.ServerSMTPBanner,
+		MaxSize:       int(config.Cfg.ServerSMTPMaxSize),
+		MaxRecipients: config.Cfg.ServerSMTPMaxRecipients,
+		MaxMessages:   config.Cfg.ServerSMTPMaxMessages,
+		Timeout:       config.Cfg.ServerSMTPTimeout,
+		MaxDuration:   config.Cfg.ServerSMTPMaxDuration,
+	}
+
+	if config.Cfg.ServerSMTPStartTLS {
+		store, err := loadHTTPSCertificates(config.Cfg.ServerHTTPSCert, config.Cfg.ServerHTTPSKey)
+		if err != nil {
+			quitErrChan <- err
+			return
+		}
+
+		opts.TLSConfig = &tls.Config{
+			GetCertificate: store.GetCertificate,
+		}
+	}
+
+	server := smtpd.New(opts)
+
+	ln, err := net.Listen("tcp", net.JoinHostPort(config.Cfg.ServerSMTPAddress, strconv.Itoa(port)))
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	logging.Std.Println("Started SMTP server on", ln.Addr())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			quitErrChan <- err
+			return
+		}
+
+		go func() {
+			eventChan <- events.NewSMTPEvent(server.Serve(conn))
+		}()
+	}
+}
@@ -0,0 +1,60 @@
+package router
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/logging"
+	"github.com/bonjourmalware/melody/internal/sshd"
+
+	"github.com/rs/xid"
+)
+
+// StartSSH starts the SSH server. An event is sent for each authentication attempt and command of its clients
+func StartSSH(quitErrChan chan error, eventChan chan events.Event) {
+	signers, err := sshd.LoadHostKeys(config.Cfg.ServerSSHHostKeys)
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	server := sshd.New(sshd.Options{
+		Version:     config.Cfg.ServerSSHVersion,
+		Hostname:    config.Cfg.ServerSSHHostname,
+		Signers:     signers,
+		Accept:      config.Cfg.ServerSSHAccept,
+		MaxDuration: config.Cfg.ServerSSHMaxDuration,
+	})
+
+	ln, err := net.Listen("tcp", net.JoinHostPort(config.Cfg.ServerSSHAddress, strconv.Itoa(config.Cfg.ServerSSHPort)))
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	logging.Std.Println("Started SSH server on", ln.Addr())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			quitErrChan <- err
+			return
+		}
+
+		go func() {
+			// All the events of a connection share the same session
+			session := xid.New().String()
+			server.ServeConn(conn, func(activity sshd.Activity) {
+				eventChan <- events.NewSSHEvent(activity, session)
+			})
+		}()
+	}
+}
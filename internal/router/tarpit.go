@@ -0,0 +1,85 @@
+package router
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/logging"
+	"github.com/bonjourmalware/melody/internal/tarpit"
+)
+
+var (
+	sharedTarpit     *tarpit.Tarpit
+	sharedTarpitOnce sync.Once
+)
+
+// getTarpit returns the tarpit shared by the dummy servers and the TCP tarpit listeners, so that
+// server.tarpit.max_connections applies to all of them
+func getTarpit() *tarpit.Tarpit {
+	sharedTarpitOnce.Do(func() {
+		sharedTarpit = tarpit.New(tarpit.Options{
+			Interval:       config.Cfg.ServerTarpitInterval,
+			MaxDuration:    config.Cfg.ServerTarpitMaxDuration,
+			MaxConnections: config.Cfg.ServerTarpitMaxConnections,
+		})
+	})
+
+	return sharedTarpit
+}
+
+// tarpitHandler traps the clients of a dummy server using the given mode, and sends a summary event once they are
+// released. The requests are passed to the given handler while the tarpit is full
+func tarpitHandler(h http.Handler, mode string, eventChan chan events.Event) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Link the summary to the event of the request, sent before the client is held
+		session := logRequestSession(r, eventChan)
+
+		summary, ok := getTarpit().ServeHTTP(w, r, mode)
+		if !ok {
+			h.ServeHTTP(w, r) // pass request
+			return
+		}
+
+		appProto := config.HTTPKind
+		if r.TLS != nil {
+			appProto = config.HTTPSKind
+		}
+
+		eventChan <- events.NewTarpitEvent(summary, appProto, session)
+	})
+}
+
+// StartTCPTarpit starts a TCP tarpit listener on the given port. The connections are held open without ever being
+// answered, and a summary event is sent once they are released
+func StartTCPTarpit(quitErrChan chan error, eventChan chan events.Event, port int) {
+	ln, err := tarpit.ListenTCP(config.Cfg.ServerTarpitTCPAddress, port)
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	logging.Std.Println("Started TCP tarpit on", ln.Addr())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			quitErrChan <- err
+			return
+		}
+
+		go func() {
+			if summary, ok := getTarpit().ServeConn(conn); ok {
+				eventChan <- events.NewTarpitEvent(summary, "", "")
+			}
+		}()
+	}
+}
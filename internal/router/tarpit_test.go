@@ -0,0 +1,89 @@
+package router
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/tarpit"
+)
+
+func TestTarpitHandler(t *testing.T) {
+	// The events of the requests are sent right away in the server-only mode
+	listenMode := config.Cfg.ListenMode
+	config.Cfg.ListenMode = config.ListenModeServerOnly
+	defer func() {
+		config.Cfg.ListenMode = listenMode
+	}()
+
+	for _, logRequests := range []bool{false, true} {
+		eventChan := make(chan events.Event, 16)
+
+		handler := tarpitHandler(nextHandler, tarpit.ModeHeaders, eventChan)
+		if logRequests {
+			handler = requestLogger(handler, eventChan)
+		}
+
+		srv := httptest.NewServer(handler)
+
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _ = fmt.Fprint(conn, "GET /trapped HTTP/1.1\r\nHost: www.example.com\r\n\r\n")
+		if status, _ := bufio.NewReader(conn).ReadString('\n'); status != "HTTP/1.1 200 OK\r\n" {
+			t.Errorf("unexpected status of the trapped request %q", status)
+		}
+
+		// The request's event is sent before the client is held
+		var httpEv *events.HTTPEvent
+		select {
+		case ev := <-eventChan:
+			httpEv = ev.(*events.HTTPEvent)
+		case <-time.After(time.Second):
+			t.Fatal("the event of the trapped request has not been sent")
+		}
+
+		// The requests are passed to the next handler while the tarpit is full
+		if status := statusLine(t, srv, "GET /passed HTTP/1.1\r\nHost: www.example.com\r\n\r\n"); status != "HTTP/1.1 200 OK" {
+			t.Errorf("unexpected status of the passed request %q", status)
+		}
+
+		_ = conn.Close()
+
+		// The passed request is logged once, then the summary is sent when the client leaves
+		types := []string{"http", config.TarpitKind}
+
+		var summary *events.TarpitEvent
+		var received []string
+		for range types {
+			select {
+			case ev := <-eventChan:
+				received = append(received, ev.GetKind())
+				if tarpitEv, ok := ev.(*events.TarpitEvent); ok {
+					summary = tarpitEv
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("log_requests %v : missing events, got %v", logRequests, received)
+			}
+		}
+
+		select {
+		case ev := <-eventChan:
+			t.Errorf("log_requests %v : unexpected %s event", logRequests, ev.GetKind())
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		if summary == nil || summary.Session != httpEv.Session || summary.AppProto != config.HTTPKind || summary.ReleasedBy != tarpit.ReleasedByClient {
+			t.Errorf("log_requests %v : unexpected summary %+v for the request's session %s", logRequests, summary, httpEv.Session)
+		}
+
+		srv.Close()
+	}
+}
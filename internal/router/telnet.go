@@ -0,0 +1,54 @@
+package router
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/logging"
+	"github.com/bonjourmalware/melody/internal/telnetd"
+)
+
+// StartTelnet starts the Telnet server on the given port. An event holding the transcript is sent at the end of each
+// session
+func StartTelnet(quitErrChan chan error, eventChan chan events.Event, port int) {
+	server := telnetd.New(telnetd.Options{
+		Banner:         config.Cfg.ServerTelnetBanner,
+		LoginPrompt:    config.Cfg.ServerTelnetLoginPrompt,
+		PasswordPrompt: config.Cfg.ServerTelnetPasswordPrompt,
+		Motd:           config.Cfg.ServerTelnetMotd,
+		Prompt:         config.Cfg.ServerTelnetPrompt,
+		Accept:         config.Cfg.ServerTelnetAccept,
+		MaxAttempts:    config.Cfg.ServerTelnetMaxAttempts,
+		Timeout:        config.Cfg.ServerTelnetTimeout,
+		MaxDuration:    config.Cfg.ServerTelnetMaxDuration,
+		MaxSize:        int(config.Cfg.ServerTelnetMaxSize),
+	})
+
+	ln, err := net.Listen("tcp", net.JoinHostPort(config.Cfg.ServerTelnetAddress, strconv.Itoa(port)))
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	logging.Std.Println("Started Telnet server on", ln.Addr())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			quitErrChan <- err
+			return
+		}
+
+		go func() {
+			eventChan <- events.NewTelnetEvent(server.Serve(conn))
+		}()
+	}
+}
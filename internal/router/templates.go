@@ -0,0 +1,127 @@
+package router
+
+import (
+	"context"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/logging"
+	"github.com/bonjourmalware/melody/internal/rules"
+	"github.com/bonjourmalware/melody/internal/templating"
+)
+
+type contextKey string
+
+// eventContextKey is used to pass the event generated from the request by the first handler needing it to the next
+// handlers
+const eventContextKey contextKey = "event"
+
+// withEvent attaches the HTTP event generated from the request to its context
+func withEvent(r *http.Request, ev *events.HTTPEvent) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), eventContextKey, ev))
+}
+
+// requestEvent returns the HTTP event attached to the request by a previous handler, or generates it and attaches it
+// to the returned request. Generating the event reads the body and stores the artifacts, so it is only done once per
+// request
+func requestEvent(r *http.Request) (*events.HTTPEvent, *http.Request, error) {
+	if ev, ok := r.Context().Value(eventContextKey).(*events.HTTPEvent); ok {
+		return ev, r, nil
+	}
+
+	ev, err := events.NewHTTPEventFromRequest(r)
+	if err != nil {
+		return nil, r, err
+	}
+
+	return ev, withEvent(r, ev), nil
+}
+
+// newTemplateData creates the data made available to the templates, along with the captures of the given rules
+func newTemplateData(r *http.Request, ev *events.HTTPEvent, matches rules.Rules) *templating.Request {
+	data := templating.NewRequest(r, ev.Body.Content)
+
+	for _, rule := range matches {
+		captures, named := rule.HTTPCaptures(ev)
+		data.Captures = append(data.Captures, captures...)
+		for name, val := range named {
+			data.NamedCaptures[name] = val
+		}
+	}
+
+	return data
+}
+
+// findTemplate looks for the template version of the given file, and returns its path if it exists
+func findTemplate(root http.FileSystem, name string) (string, bool) {
+	tmplPath := name + templating.Extension
+
+	f, err := root.Open(tmplPath)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	s, err := f.Stat()
+	if err != nil || s.IsDir() {
+		return "", false
+	}
+
+	return tmplPath, true
+}
+
+// serveTemplate renders the given template file with the request's data. The content type is guessed from the
+// extension of the file without its template suffix
+func serveTemplate(w http.ResponseWriter, r *http.Request, root http.FileSystem, tmplPath string) {
+	f, err := root.Open(tmplPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+	defer f.Close()
+
+	raw, err := ioutil.ReadAll(f)
+	if err != nil {
+		logging.Errors.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tmpl, err := templating.Parse(path.Base(tmplPath), string(raw))
+	if err != nil {
+		logging.Errors.Printf("failed to parse template '%s' : %s", tmplPath, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ev, r, err := requestEvent(r)
+	if err != nil {
+		logging.Errors.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, err := templating.Render(tmpl, newTemplateData(r, ev, rules.MatchingRules(ev)))
+	if err != nil {
+		logging.Errors.Printf("failed to render template '%s' : %s", tmplPath, err)
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		if ctype := mime.TypeByExtension(path.Ext(strings.TrimSuffix(tmplPath, templating.Extension))); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		} else {
+			w.Header().Set("Content-Type", http.DetectContentType(body))
+		}
+	}
+
+	_, _ = w.Write(body)
+}
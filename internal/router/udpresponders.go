@@ -0,0 +1,35 @@
+package router
+
+import (
+	"net"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/logging"
+	"github.com/bonjourmalware/melody/internal/udpresponders"
+)
+
+// StartUDPResponder binds the socket used by the UDP responders to answer the queries sent to the given port. The
+// queries are read from the captured packets, so the datagrams received by the socket are discarded
+func StartUDPResponder(quitErrChan chan error, port int) {
+	conn, err := udpresponders.Default.Listen(config.Cfg.RespondersUDPAddress, port)
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	logging.Std.Println("Started UDP responder on", conn.LocalAddr())
+
+	buf := make([]byte, 65535)
+	for {
+		if _, _, err := conn.ReadFromUDP(buf); err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			quitErrChan <- err
+			return
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package router
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/logging"
+	"github.com/bonjourmalware/melody/internal/vncd"
+)
+
+// StartVNC starts the VNC server on the given port. An event holding the handshake is sent at the end of each session
+func StartVNC(quitErrChan chan error, eventChan chan events.Event, port int) {
+	var securityTypes []uint8
+	for _, secType := range config.Cfg.ServerVNCSecurityTypes {
+		securityTypes = append(securityTypes, uint8(secType))
+	}
+
+	server := vncd.New(vncd.Options{
+		Version:       config.Cfg.ServerVNCVersion,
+		SecurityTypes: securityTypes,
+		Timeout:       config.Cfg.ServerVNCTimeout,
+	})
+
+	ln, err := net.Listen("tcp", net.JoinHostPort(config.Cfg.ServerVNCAddress, strconv.Itoa(port)))
+	if err != nil {
+		quitErrChan <- err
+		return
+	}
+
+	logging.Std.Println("Started VNC server on", ln.Addr())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(time.Second)
+				continue
+			}
+
+			quitErrChan <- err
+			return
+		}
+
+		go func() {
+			eventChan <- events.NewVNCEvent(server.Serve(conn))
+		}()
+	}
+}
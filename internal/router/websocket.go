@@ -0,0 +1,57 @@
+package router
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/websocket"
+)
+
+var (
+	sharedWebSocket     *websocket.Server
+	sharedWebSocketOnce sync.Once
+)
+
+// getWebSocket returns the WebSocket server shared by the dummy servers
+func getWebSocket() *websocket.Server {
+	sharedWebSocketOnce.Do(func() {
+		sharedWebSocket = websocket.New(websocket.Options{
+			MaxFrames:      config.Cfg.ServerWebSocketMaxFrames,
+			MaxPayloadSize: int(config.Cfg.ServerWebSocketMaxPayload),
+			Timeout:        config.Cfg.ServerWebSocketTimeout,
+			MaxDuration:    config.Cfg.ServerWebSocketMaxDuration,
+		})
+	})
+
+	return sharedWebSocket
+}
+
+// webSocketHandler completes the WebSocket upgrades requested on the paths of server.websocket.paths, and sends an
+// event for each frame sent by the clients. The other requests, and the upgrades that cannot be completed such as
+// with HTTP/2, are passed to the given handler
+func webSocketHandler(h http.Handler, eventChan chan events.Event) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !websocket.IsUpgrade(r) || !config.Cfg.MatchWebSocketPath(r.URL.Path) {
+			h.ServeHTTP(w, r) // pass request
+			return
+		}
+
+		// The upgrades cannot be completed over HTTP/2
+		if _, ok := w.(http.Hijacker); !ok {
+			h.ServeHTTP(w, r) // pass request
+			return
+		}
+
+		// Link the frames to the event of the upgrade request
+		session := logRequestSession(r, eventChan)
+
+		err := getWebSocket().Serve(w, r, func(frame websocket.Frame) {
+			eventChan <- events.NewWebSocketEvent(r, frame, session)
+		})
+		if err != nil {
+			h.ServeHTTP(w, r) // pass request
+		}
+	})
+}
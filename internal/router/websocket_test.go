@@ -0,0 +1,99 @@
+package router
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/websocket"
+)
+
+// maskedFrame encodes a frame sent by a client
+func maskedFrame(opcode byte, payload string) []byte {
+	mask := []byte{1, 2, 3, 4}
+	frame := append([]byte{0x80 | opcode, 0x80 | byte(len(payload))}, mask...)
+	for idx := 0; idx < len(payload); idx++ {
+		frame = append(frame, payload[idx]^mask[idx%4])
+	}
+
+	return frame
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	config.Cfg.ServerWebSocketPaths = []string{"/ws/*"}
+	defer func() {
+		config.Cfg.ServerWebSocketPaths = nil
+	}()
+
+	eventChan := make(chan events.Event, 16)
+	srv := httptest.NewServer(webSocketHandler(nextHandler, eventChan))
+	defer srv.Close()
+
+	// The other paths and the plain requests are passed to the next handler
+	for _, request := range []string{
+		"GET /chat HTTP/1.1\r\nHost: www.example.com\r\n" + webSocketUpgrade + "\r\n",
+		"GET /ws/chat HTTP/1.1\r\nHost: www.example.com\r\n\r\n",
+	} {
+		if status := statusLine(t, srv, request); status != "HTTP/1.1 200 OK" {
+			t.Errorf("%q : unexpected status %q", request, status)
+		}
+	}
+
+	// The upgrades cannot be completed without hijacking the connection
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/ws/chat", nil)
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	webSocketHandler(nextHandler, eventChan).ServeHTTP(w, r)
+	if w.Body.String() != "next" {
+		t.Errorf("unexpected response to the upgrade without hijacking : %d '%s'", w.Code, w.Body.String())
+	}
+
+	select {
+	case ev := <-eventChan:
+		t.Fatalf("unexpected %s event for the requests passed to the next handler", ev.GetKind())
+	default:
+	}
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, _ = fmt.Fprint(conn, "GET /ws/chat HTTP/1.1\r\nHost: www.example.com\r\n"+webSocketUpgrade+"\r\n")
+	if status, _ := bufio.NewReader(conn).ReadString('\n'); status != "HTTP/1.1 101 Switching Protocols\r\n" {
+		t.Fatalf("unexpected status %q", status)
+	}
+
+	_, _ = conn.Write(maskedFrame(websocket.OpText, "hello"))
+	_, _ = conn.Write(maskedFrame(websocket.OpClose, ""))
+
+	// The upgrade request is logged even without the requestLogger, and the frames share its session
+	var session string
+	for _, kind := range []string{"http", config.WebSocketKind, config.WebSocketKind} {
+		select {
+		case ev := <-eventChan:
+			switch ev := ev.(type) {
+			case *events.HTTPEvent:
+				session = ev.Session
+			case *events.WebSocketEvent:
+				if ev.Session != session || ev.Path != "/ws/chat" {
+					t.Errorf("unexpected frame event %+v for the session %s", ev, session)
+				}
+			}
+
+			if ev.GetKind() != kind {
+				t.Errorf("unexpected %s event, expected %s", ev.GetKind(), kind)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("missing %s event", kind)
+		}
+	}
+}
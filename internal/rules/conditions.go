@@ -83,6 +83,53 @@ func (clst ConditionsList) Match(received []byte) bool {
 	return true
 }
 
+// Captures returns the groups captured by the regex conditions of the list. The unnamed groups are returned in order,
+// while the named ones are returned in a map
+func (clst ConditionsList) Captures(received []byte) ([]string, map[string]string) {
+	var captures []string
+	named := make(map[string]string)
+
+	for _, cds := range clst.Conditions {
+		if !cds.Options.Regex {
+			continue
+		}
+
+		data := received
+		if cds.Options.Nocase {
+			data = bytes.ToLower(data)
+		}
+
+		if cds.Options.Offset > 0 && cds.Options.Offset < uint(len(data)) {
+			data = data[cds.Options.Offset:]
+		}
+
+		if cds.Options.Depth > 0 && cds.Options.Depth < uint(len(data)) {
+			data = data[:cds.Options.Depth]
+		}
+
+		for _, condVal := range cds.Values {
+			submatches := condVal.CompiledRegex.FindSubmatch(data)
+			if submatches == nil {
+				continue
+			}
+
+			for idx, name := range condVal.CompiledRegex.SubexpNames() {
+				if idx == 0 {
+					continue
+				}
+
+				if name == "" {
+					captures = append(captures, string(submatches[idx]))
+				} else {
+					named[name] = string(submatches[idx])
+				}
+			}
+		}
+	}
+
+	return captures, named
+}
+
 // Match matches a byte array against a set of conditions
 func (cds Conditions) Match(received []byte) bool {
 	var contentMatch bool
@@ -66,6 +66,11 @@ func (rl *Rule) Match(ev events.Event) bool {
 		}
 	}
 
+	// The rule fails if the application protocol identified for the event's flow is not the expected one
+	if rl.AppProto != nil && !rl.AppProto.Match([]byte(ev.GetAppProto())) {
+		return false
+	}
+
 	switch ev.GetKind() {
 	case config.UDPKind:
 		return rl.MatchUDPEvent(ev)
@@ -79,6 +84,14 @@ func (rl *Rule) Match(ev events.Event) bool {
 		fallthrough
 	case config.HTTPSKind:
 		return rl.MatchHTTPEvent(ev)
+	case config.QUICKind:
+		return rl.MatchQUICEvent(ev)
+	case config.SNMPKind:
+		return rl.MatchSNMPEvent(ev)
+	case config.SSHKind:
+		return rl.MatchSSHEvent(ev)
+	case config.VNCKind:
+		return rl.MatchVNCEvent(ev)
 	}
 
 	return false
@@ -500,3 +513,241 @@ func (rl *Rule) MatchHTTPEvent(ev events.Event) bool {
 
 	return false
 }
+
+// MatchQUICEvent attempt to match a QUIC event against the calling Rule
+func (rl *Rule) MatchQUICEvent(ev events.Event) bool {
+	quicData := ev.GetQUICData()
+
+	var condOK bool
+
+	if rl.MatchAll {
+		if rl.QUIC.Version != nil {
+			if quicData.Version != *rl.QUIC.Version {
+				return false
+			}
+		}
+
+		if rl.QUIC.SNI != nil {
+			if !rl.QUIC.SNI.Match([]byte(quicData.SNI)) {
+				return false
+			}
+		}
+
+		if rl.QUIC.ALPN != nil {
+			condOK = false
+
+			for _, proto := range quicData.ALPN {
+				if rl.QUIC.ALPN.Match([]byte(proto)) {
+					condOK = true
+					break
+				}
+			}
+
+			if !condOK {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if rl.QUIC.Version != nil {
+		if quicData.Version == *rl.QUIC.Version {
+			return true
+		}
+	}
+
+	if rl.QUIC.SNI != nil {
+		if rl.QUIC.SNI.Match([]byte(quicData.SNI)) {
+			return true
+		}
+	}
+
+	if rl.QUIC.ALPN != nil {
+		for _, proto := range quicData.ALPN {
+			if rl.QUIC.ALPN.Match([]byte(proto)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MatchSNMPEvent attempt to match an SNMP event against the calling Rule
+func (rl *Rule) MatchSNMPEvent(ev events.Event) bool {
+	snmpData := ev.GetSNMPData()
+
+	var condOK bool
+
+	if rl.MatchAll {
+		if rl.SNMP.Version != nil {
+			if !rl.SNMP.Version.Match([]byte(snmpData.Version)) {
+				return false
+			}
+		}
+
+		if rl.SNMP.Community != nil {
+			if !rl.SNMP.Community.Match([]byte(snmpData.Community)) {
+				return false
+			}
+		}
+
+		if rl.SNMP.User != nil {
+			if !rl.SNMP.User.Match([]byte(snmpData.User)) {
+				return false
+			}
+		}
+
+		if rl.SNMP.PDUType != nil {
+			if !rl.SNMP.PDUType.Match([]byte(snmpData.PDUType)) {
+				return false
+			}
+		}
+
+		if rl.SNMP.OIDs != nil {
+			condOK = false
+
+			for _, oid := range snmpData.OIDs {
+				if rl.SNMP.OIDs.Match([]byte(oid)) {
+					condOK = true
+					break
+				}
+			}
+
+			if !condOK {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if rl.SNMP.Version != nil {
+		if rl.SNMP.Version.Match([]byte(snmpData.Version)) {
+			return true
+		}
+	}
+
+	if rl.SNMP.Community != nil {
+		if rl.SNMP.Community.Match([]byte(snmpData.Community)) {
+			return true
+		}
+	}
+
+	if rl.SNMP.User != nil {
+		if rl.SNMP.User.Match([]byte(snmpData.User)) {
+			return true
+		}
+	}
+
+	if rl.SNMP.PDUType != nil {
+		if rl.SNMP.PDUType.Match([]byte(snmpData.PDUType)) {
+			return true
+		}
+	}
+
+	if rl.SNMP.OIDs != nil {
+		for _, oid := range snmpData.OIDs {
+			if rl.SNMP.OIDs.Match([]byte(oid)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MatchSSHEvent attempt to match an SSH event against the calling Rule
+func (rl *Rule) MatchSSHEvent(ev events.Event) bool {
+	sshData := ev.GetSSHData()
+
+	if rl.MatchAll {
+		if rl.SSH.ClientVersion != nil {
+			if !rl.SSH.ClientVersion.Match([]byte(sshData.ClientVersion)) {
+				return false
+			}
+		}
+
+		if rl.SSH.Action != nil {
+			if !rl.SSH.Action.Match([]byte(sshData.Action)) {
+				return false
+			}
+		}
+
+		if rl.SSH.Username != nil {
+			if !rl.SSH.Username.Match([]byte(sshData.Username)) {
+				return false
+			}
+		}
+
+		if rl.SSH.Password != nil {
+			if !rl.SSH.Password.Match([]byte(sshData.Password)) {
+				return false
+			}
+		}
+
+		if rl.SSH.Command != nil {
+			if !rl.SSH.Command.Match([]byte(sshData.Command)) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if rl.SSH.ClientVersion != nil {
+		if rl.SSH.ClientVersion.Match([]byte(sshData.ClientVersion)) {
+			return true
+		}
+	}
+
+	if rl.SSH.Action != nil {
+		if rl.SSH.Action.Match([]byte(sshData.Action)) {
+			return true
+		}
+	}
+
+	if rl.SSH.Username != nil {
+		if rl.SSH.Username.Match([]byte(sshData.Username)) {
+			return true
+		}
+	}
+
+	if rl.SSH.Password != nil {
+		if rl.SSH.Password.Match([]byte(sshData.Password)) {
+			return true
+		}
+	}
+
+	if rl.SSH.Command != nil {
+		if rl.SSH.Command.Match([]byte(sshData.Command)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchVNCEvent attempt to match a VNC event against the calling Rule
+func (rl *Rule) MatchVNCEvent(ev events.Event) bool {
+	vncData := ev.GetVNCData()
+
+	if rl.MatchAll {
+		if rl.VNC.ClientVersion != nil {
+			if !rl.VNC.ClientVersion.Match([]byte(vncData.ClientVersion)) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if rl.VNC.ClientVersion != nil {
+		if rl.VNC.ClientVersion.Match([]byte(vncData.ClientVersion)) {
+			return true
+		}
+	}
+
+	return false
+}
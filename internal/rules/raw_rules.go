@@ -116,6 +116,70 @@ type ParsedUDPRule struct {
 	Payload  *ConditionsList
 }
 
+// QUICRule describes the raw "match" section of a rule targeting QUIC
+type QUICRule struct {
+	Version *uint32       `yaml:"quic.version"`
+	SNI     RawConditions `yaml:"quic.sni"`
+	ALPN    RawConditions `yaml:"quic.alpn"`
+	Any     bool          `yaml:"any"`
+}
+
+// ParsedQUICRule describes the parsed "match" section of a rule targeting QUIC
+type ParsedQUICRule struct {
+	Version *uint32
+	SNI     *ConditionsList
+	ALPN    *ConditionsList
+}
+
+// SNMPRule describes the raw "match" section of a rule targeting SNMP
+type SNMPRule struct {
+	Version   RawConditions `yaml:"snmp.version"`
+	Community RawConditions `yaml:"snmp.community"`
+	User      RawConditions `yaml:"snmp.user"`
+	PDUType   RawConditions `yaml:"snmp.pdu_type"`
+	OIDs      RawConditions `yaml:"snmp.oids"`
+	Any       bool          `yaml:"any"`
+}
+
+// ParsedSNMPRule describes the parsed "match" section of a rule targeting SNMP
+type ParsedSNMPRule struct {
+	Version   *ConditionsList
+	Community *ConditionsList
+	User      *ConditionsList
+	PDUType   *ConditionsList
+	OIDs      *ConditionsList
+}
+
+// SSHRule describes the raw "match" section of a rule targeting SSH
+type SSHRule struct {
+	ClientVersion RawConditions `yaml:"ssh.client_version"`
+	Action        RawConditions `yaml:"ssh.action"`
+	Username      RawConditions `yaml:"ssh.username"`
+	Password      RawConditions `yaml:"ssh.password"`
+	Command       RawConditions `yaml:"ssh.command"`
+	Any           bool          `yaml:"any"`
+}
+
+// ParsedSSHRule describes the parsed "match" section of a rule targeting SSH
+type ParsedSSHRule struct {
+	ClientVersion *ConditionsList
+	Action        *ConditionsList
+	Username      *ConditionsList
+	Password      *ConditionsList
+	Command       *ConditionsList
+}
+
+// VNCRule describes the raw "match" section of a rule targeting VNC
+type VNCRule struct {
+	ClientVersion RawConditions `yaml:"vnc.client_version"`
+	Any           bool          `yaml:"any"`
+}
+
+// ParsedVNCRule describes the parsed "match" section of a rule targeting VNC
+type ParsedVNCRule struct {
+	ClientVersion *ConditionsList
+}
+
 // Filters groups the exposed rule filters
 type Filters struct {
 	Ports []string `yaml:"ports"`
@@ -143,9 +207,12 @@ type RawRule struct {
 	Tags       map[string]string `yaml:"tags"`
 	Layer      string            `yaml:"layer"`
 	IPProtocol RawConditions     `yaml:"ip_protocol"`
+	AppProto   RawConditions     `yaml:"app_proto"`
 
 	Metadata   Metadata          `yaml:"meta"`
 	Additional map[string]string `yaml:"embed"`
+
+	Response *RawResponse `yaml:"response"`
 }
 
 var (
@@ -157,6 +224,17 @@ func (rawRule RawRule) Parse() (Rule, error) {
 	var err error
 	rule := NewRule(rawRule)
 
+	if rawRule.Response != nil {
+		if rawRule.Layer != "http" && rawRule.Layer != "https" {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : the response block is not supported with layer '%s'", rawRule.Metadata.ID, rawRule.Layer)
+		}
+
+		rule.Response, err = rawRule.Response.Parse()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+	}
+
 	if rawRule.Match == nil {
 		return rule, nil
 	}
@@ -323,6 +401,137 @@ func (rawRule RawRule) Parse() (Rule, error) {
 			Payload:  parsedPayload,
 		}
 
+		rule.MatchAll = !buf.Any
+
+	case "quic":
+		var buf QUICRule
+
+		err = yaml.Unmarshal(rawMatch, &buf)
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedSNI, err := buf.SNI.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedALPN, err := buf.ALPN.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		rule.QUIC = ParsedQUICRule{
+			Version: buf.Version,
+			SNI:     parsedSNI,
+			ALPN:    parsedALPN,
+		}
+
+		rule.MatchAll = !buf.Any
+
+	case "snmp":
+		var buf SNMPRule
+
+		err = yaml.Unmarshal(rawMatch, &buf)
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedVersion, err := buf.Version.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedCommunity, err := buf.Community.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedUser, err := buf.User.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedPDUType, err := buf.PDUType.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedOIDs, err := buf.OIDs.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		rule.SNMP = ParsedSNMPRule{
+			Version:   parsedVersion,
+			Community: parsedCommunity,
+			User:      parsedUser,
+			PDUType:   parsedPDUType,
+			OIDs:      parsedOIDs,
+		}
+
+		rule.MatchAll = !buf.Any
+
+	case "ssh":
+		var buf SSHRule
+
+		err = yaml.Unmarshal(rawMatch, &buf)
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedClientVersion, err := buf.ClientVersion.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedAction, err := buf.Action.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedUsername, err := buf.Username.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedPassword, err := buf.Password.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedCommand, err := buf.Command.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		rule.SSH = ParsedSSHRule{
+			ClientVersion: parsedClientVersion,
+			Action:        parsedAction,
+			Username:      parsedUsername,
+			Password:      parsedPassword,
+			Command:       parsedCommand,
+		}
+
+		rule.MatchAll = !buf.Any
+
+	case "vnc":
+		var buf VNCRule
+
+		err = yaml.Unmarshal(rawMatch, &buf)
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		parsedClientVersion, err := buf.ClientVersion.ParseList()
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		}
+
+		rule.VNC = ParsedVNCRule{
+			ClientVersion: parsedClientVersion,
+		}
+
 		rule.MatchAll = !buf.Any
 	}
 
@@ -0,0 +1,171 @@
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/config"
+	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/templating"
+)
+
+// RawResponse describes the "response" section of a rule, as written by the user
+type RawResponse struct {
+	Status   int               `yaml:"status"`
+	Headers  map[string]string `yaml:"headers"`
+	Body     string            `yaml:"body"`
+	BodyFile string            `yaml:"body_file"`
+	Delay    string            `yaml:"delay"`
+	Template bool              `yaml:"template"`
+}
+
+// Response describes the HTTP response sent by the dummy servers when the rule matches a request
+type Response struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+	Delay   time.Duration
+
+	// Template is set if the body has to be rendered with the request's data
+	Template *template.Template
+}
+
+// Parse creates a Response from a RawResponse
+func (raw RawResponse) Parse() (*Response, error) {
+	var err error
+
+	resp := &Response{
+		Status:  raw.Status,
+		Headers: raw.Headers,
+		Body:    []byte(raw.Body),
+	}
+
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+
+	if http.StatusText(resp.Status) == "" {
+		return nil, fmt.Errorf("'%d' is not a valid HTTP code status", resp.Status)
+	}
+
+	if raw.Body != "" && raw.BodyFile != "" {
+		return nil, fmt.Errorf("'body' and 'body_file' can't be used together")
+	}
+
+	if raw.BodyFile != "" {
+		resp.Body, err = ioutil.ReadFile(raw.BodyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if raw.Template {
+		resp.Template, err = templating.Parse("response", string(resp.Body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid body template : %s", err)
+		}
+	}
+
+	if raw.Delay != "" {
+		resp.Delay, err = time.ParseDuration(raw.Delay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delay '%s' : %s", raw.Delay, err)
+		}
+	}
+
+	return resp, nil
+}
+
+// HasResponses checks if any of the rules of the given kind defines a response
+func HasResponses(kind string) bool {
+	for _, ruleset := range GlobalRules[kind] {
+		for _, rule := range ruleset {
+			if rule.Response != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MatchResponse returns the rule defining the response to send back for the given HTTP event, or nil if none matches.
+// If multiple rules match, the first one in alphabetical order of their name is used
+func MatchResponse(ev events.Event) *Rule {
+	var selected *Rule
+
+	if ev.GetKind() != config.HTTPKind && ev.GetKind() != config.HTTPSKind {
+		return nil
+	}
+
+	for _, ruleset := range GlobalRules[ev.GetKind()] {
+		for idx := range ruleset {
+			rule := &ruleset[idx]
+			if rule.Response == nil {
+				continue
+			}
+
+			if selected != nil && selected.Name < rule.Name {
+				continue
+			}
+
+			if rule.Match(ev) {
+				selected = rule
+			}
+		}
+	}
+
+	return selected
+}
+
+// MatchingRules returns the rules matching the given event, sorted by name
+func MatchingRules(ev events.Event) Rules {
+	var matches Rules
+
+	for _, ruleset := range GlobalRules[ev.GetKind()] {
+		for _, rule := range ruleset {
+			if rule.Match(ev) {
+				matches = append(matches, rule)
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Name < matches[j].Name
+	})
+
+	return matches
+}
+
+// HTTPCaptures returns the groups captured by the regex conditions of the rule on the given HTTP event
+func (rl *Rule) HTTPCaptures(ev events.Event) ([]string, map[string]string) {
+	var captures []string
+	named := make(map[string]string)
+	httpData := ev.GetHTTPData()
+
+	collect := func(clst *ConditionsList, received string) {
+		if clst == nil {
+			return
+		}
+
+		groups, namedGroups := clst.Captures([]byte(received))
+		captures = append(captures, groups...)
+		for name, val := range namedGroups {
+			named[name] = val
+		}
+	}
+
+	collect(rl.HTTP.URI, httpData.RequestURI)
+	collect(rl.HTTP.Body, httpData.Body.Content)
+	collect(rl.HTTP.Verb, httpData.Verb)
+	collect(rl.HTTP.Proto, httpData.Proto)
+	for _, inlineHeader := range httpData.InlineHeaders {
+		collect(rl.HTTP.Headers, inlineHeader)
+	}
+
+	return captures, named
+}
@@ -17,17 +17,23 @@ type Rule struct {
 	Layer string
 
 	IPProtocol *ConditionsList
+	AppProto   *ConditionsList
 
 	HTTP   ParsedHTTPRule
 	TCP    ParsedTCPRule
 	UDP    ParsedUDPRule
 	ICMPv4 ParsedICMPv4Rule
 	ICMPv6 ParsedICMPv6Rule
+	QUIC   ParsedQUICRule
+	SNMP   ParsedSNMPRule
+	SSH    ParsedSSHRule
+	VNC    ParsedVNCRule
 
 	IPs        filters.IPRules
 	Ports      filters.PortRules
 	Metadata   Metadata
 	Additional map[string]string
+	Response   *Response
 
 	MatchAll bool
 }
@@ -53,9 +59,16 @@ func NewRule(rawRule RawRule) Rule {
 		return Rule{}
 	}
 
+	parsedAppProto, err := rawRule.AppProto.ParseList()
+	if err != nil {
+		logging.Errors.Printf("failed to parse rule '%s' : %s", rawRule.Metadata.ID, err)
+		return Rule{}
+	}
+
 	rule := Rule{
 		Tags:       rawRule.Tags,
 		IPProtocol: parsedIPProtocol,
+		AppProto:   parsedAppProto,
 		ID:         rawRule.Metadata.ID,
 		Layer:      rawRule.Layer,
 		Ports:      portsList,
@@ -14,6 +14,10 @@ func LoadValidMatchKeysMap() map[string]interface{} {
 		loadUDPYamlTags,
 		loadICMPv4YamlTags,
 		loadICMPv6YamlTags,
+		loadQUICYamlTags,
+		loadSNMPYamlTags,
+		loadSSHYamlTags,
+		loadVNCYamlTags,
 	}
 
 	matchKeysMap := make(map[string]interface{})
@@ -101,3 +105,59 @@ func loadICMPv6YamlTags() ([]string, error) {
 
 	return tags, nil
 }
+
+func loadQUICYamlTags() ([]string, error) {
+	var tags []string
+	for i := 0; i < reflect.TypeOf(QUICRule{}).NumField(); i++ {
+		ruleTag := reflect.TypeOf(QUICRule{}).Field(i).Tag
+		tagValue, err := tagparser.ParseYamlTagValue(ruleTag)
+		if err != nil {
+			return tags, err
+		}
+		tags = append(tags, tagValue)
+	}
+
+	return tags, nil
+}
+
+func loadSNMPYamlTags() ([]string, error) {
+	var tags []string
+	for i := 0; i < reflect.TypeOf(SNMPRule{}).NumField(); i++ {
+		ruleTag := reflect.TypeOf(SNMPRule{}).Field(i).Tag
+		tagValue, err := tagparser.ParseYamlTagValue(ruleTag)
+		if err != nil {
+			return tags, err
+		}
+		tags = append(tags, tagValue)
+	}
+
+	return tags, nil
+}
+
+func loadSSHYamlTags() ([]string, error) {
+	var tags []string
+	for i := 0; i < reflect.TypeOf(SSHRule{}).NumField(); i++ {
+		ruleTag := reflect.TypeOf(SSHRule{}).Field(i).Tag
+		tagValue, err := tagparser.ParseYamlTagValue(ruleTag)
+		if err != nil {
+			return tags, err
+		}
+		tags = append(tags, tagValue)
+	}
+
+	return tags, nil
+}
+
+func loadVNCYamlTags() ([]string, error) {
+	var tags []string
+	for i := 0; i < reflect.TypeOf(VNCRule{}).NumField(); i++ {
+		ruleTag := reflect.TypeOf(VNCRule{}).Field(i).Tag
+		tagValue, err := tagparser.ParseYamlTagValue(ruleTag)
+		if err != nil {
+			return tags, err
+		}
+		tags = append(tags, tagValue)
+	}
+
+	return tags, nil
+}
@@ -2,13 +2,18 @@ package sensor
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"time"
 
 	"github.com/bonjourmalware/melody/internal/logging"
 
+	"github.com/bonjourmalware/melody/internal/dedup"
 	"github.com/bonjourmalware/melody/internal/engine"
 	"github.com/bonjourmalware/melody/internal/events"
+	"github.com/bonjourmalware/melody/internal/quicparser"
+	"github.com/bonjourmalware/melody/internal/snmpparser"
+	"github.com/bonjourmalware/melody/internal/udpresponders"
 	"github.com/google/gopacket/layers"
 
 	"github.com/bonjourmalware/melody/internal/sessions"
@@ -22,6 +27,15 @@ import (
 
 // Start starts the pipeline to receive packets
 func Start(quitErrChan chan error, shutdownChan chan bool, sensorStoppedChan chan bool) {
+	if config.Cfg.IsServerOnly() {
+		logging.Std.Println("Packet capture disabled, only the dummy servers are logging")
+		go func() {
+			<-shutdownChan
+			close(sensorStoppedChan)
+		}()
+		return
+	}
+
 	go ReceivePackets(quitErrChan, shutdownChan, sensorStoppedChan)
 }
 
@@ -77,6 +91,8 @@ func ReceivePackets(quitErrChan chan error, shutdownChan chan bool, sensorStoppe
 	defer func() {
 		httpAssembler.FlushAll()
 		sessions.SessionMap.FlushAll()
+		quicparser.PendingHandshakes.FlushAll()
+		dedup.SniffedHTTPRequests.FlushAll()
 		close(sensorStoppedChan)
 	}()
 
@@ -95,6 +111,8 @@ loop:
 		case <-sessionsFlushTicker.C:
 			// Every 30 seconds, flush inactive flows
 			sessions.SessionMap.FlushOlderThan(time.Now().Add(time.Second * -30))
+			quicparser.PendingHandshakes.FlushOlderThan(time.Now().Add(time.Second * -30))
+			dedup.SniffedHTTPRequests.FlushOlderThan(time.Now().Add(time.Second * -30))
 		case <-shutdownChan:
 			return
 		}
@@ -122,16 +140,27 @@ func handlePacket(packet gopacket.Packet, assembler *tcpassembly.Assembler) {
 				}
 
 			case layers.IPProtocolUDP:
+				if _, ok := config.Cfg.DiscardProto4[config.QUICKind]; !ok {
+					handleQUIC(packet, 4)
+				}
+
+				if _, ok := config.Cfg.DiscardProto4[config.SNMPKind]; !ok {
+					handleSNMP(packet, 4)
+				}
+
 				if _, ok := config.Cfg.DiscardProto4[config.UDPKind]; ok {
 					return
 				}
 
-				event, err = events.NewUDPEvent(packet, 4)
+				udpEvent, err := events.NewUDPEvent(packet, 4)
 				if err != nil {
 					logging.Errors.Println(err)
 					return
 				}
 
+				udpEvent.Reply = answerUDP(packet)
+				event = udpEvent
+
 			case layers.IPProtocolTCP:
 				tcpPacket := packet.TransportLayer().(*layers.TCP)
 				assembler.AssembleWithTimestamp(packet.NetworkLayer().NetworkFlow(), tcpPacket, packet.Metadata().Timestamp)
@@ -185,16 +214,27 @@ func handlePacket(packet gopacket.Packet, assembler *tcpassembly.Assembler) {
 					}
 
 				case layers.IPProtocolUDP.LayerType():
+					if _, ok := config.Cfg.DiscardProto6[config.QUICKind]; !ok {
+						handleQUIC(packet, 6)
+					}
+
+					if _, ok := config.Cfg.DiscardProto6[config.SNMPKind]; !ok {
+						handleSNMP(packet, 6)
+					}
+
 					if _, ok := config.Cfg.DiscardProto6[config.UDPKind]; ok {
 						return
 					}
 
-					event, err = events.NewUDPEvent(packet, 6)
+					udpEvent, err := events.NewUDPEvent(packet, 6)
 					if err != nil {
 						logging.Errors.Println(err)
 						return
 					}
 
+					udpEvent.Reply = answerUDP(packet)
+					event = udpEvent
+
 				default:
 					return
 				}
@@ -208,3 +248,55 @@ func handlePacket(packet gopacket.Packet, assembler *tcpassembly.Assembler) {
 		}
 	}
 }
+
+// answerUDP sends the reply of the UDP responder listening on the destination port of a packet, if any
+func answerUDP(packet gopacket.Packet) *udpresponders.Reply {
+	if udpresponders.Default == nil || *config.Cli.Dump {
+		return nil
+	}
+
+	UDPHeader, _ := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	flow := packet.NetworkLayer().NetworkFlow()
+
+	return udpresponders.Default.Answer(
+		&net.UDPAddr{IP: net.IP(flow.Src().Raw()), Port: int(UDPHeader.SrcPort)},
+		&net.UDPAddr{IP: net.IP(flow.Dst().Raw()), Port: int(UDPHeader.DstPort)},
+		UDPHeader.Payload,
+	)
+}
+
+// handleQUIC decodes the QUIC Initial packets carried by an UDP packet and sends a QUIC event once a full ClientHello
+// has been received
+func handleQUIC(packet gopacket.Packet, IPVersion uint) {
+	if *config.Cli.Dump || !quicparser.IsLongHeader(packet.TransportLayer().LayerPayload()) {
+		return
+	}
+
+	// Any UDP payload can pass the long header check, so the packets failing to decrypt or parse are dropped silently
+	ev, err := events.NewQUICEvent(packet, IPVersion)
+	if err != nil {
+		return
+	}
+
+	if ev != nil {
+		engine.EventChan <- ev
+	}
+}
+
+// handleSNMP decodes the SNMP message carried by an UDP packet and sends an SNMP event if the payload is valid
+func handleSNMP(packet gopacket.Packet, IPVersion uint) {
+	payload := packet.TransportLayer().LayerPayload()
+	if *config.Cli.Dump || len(payload) == 0 || payload[0] != 0x30 {
+		return
+	}
+
+	ev, err := events.NewSNMPEvent(packet, IPVersion)
+	if err != nil {
+		if err != snmpparser.ErrNotSNMP {
+			logging.Errors.Println(err)
+		}
+		return
+	}
+
+	engine.EventChan <- ev
+}
@@ -1,6 +1,7 @@
 package sessions
 
 import (
+	"sync"
 	"time"
 
 	"github.com/rs/xid"
@@ -10,6 +11,7 @@ import (
 type Session struct {
 	lastSeen time.Time
 	uid      string
+	appProto string
 }
 
 // sessionMap abstracts a hash table of multiple Session sorted by their flow data
@@ -18,9 +20,15 @@ type sessionMap map[string]*Session
 var (
 	// SessionMap is the global sessions hash table
 	SessionMap = make(sessionMap)
+
+	// The map is shared by the sensor and the assembler's streams
+	mu sync.Mutex
 )
 
 func (m sessionMap) GetUID(flow string) string {
+	mu.Lock()
+	defer mu.Unlock()
+
 	if session, ok := m[flow]; ok {
 		return session.uid
 	}
@@ -28,6 +36,31 @@ func (m sessionMap) GetUID(flow string) string {
 	return m.add(flow)
 }
 
+// GetAppProto returns the application protocol identified for the given flow, or an empty string if it is not known
+// yet
+func (m sessionMap) GetAppProto(flow string) string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if session, ok := m[flow]; ok {
+		return session.appProto
+	}
+
+	return ""
+}
+
+// SetAppProto records the application protocol identified for the given flow
+func (m sessionMap) SetAppProto(flow string, appProto string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := m[flow]; !ok {
+		m.add(flow)
+	}
+
+	m[flow].appProto = appProto
+}
+
 func (m *sessionMap) add(flow string) string {
 	//var ts = strconv.FormatInt(time.Now().UnixNano(), 10)
 	var ts = xid.New().String()
@@ -41,6 +74,9 @@ func (m *sessionMap) add(flow string) string {
 
 // FlushOlderThan cleans the session mapping of sessions not seen since the given deadline
 func (m *sessionMap) FlushOlderThan(deadline time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+
 	for flow, session := range *m {
 		if session.lastSeen.Before(deadline) {
 			delete(*m, flow)
@@ -50,6 +86,9 @@ func (m *sessionMap) FlushOlderThan(deadline time.Time) {
 
 // FlushAll removes all sessions from the session mapping
 func (m *sessionMap) FlushAll() {
+	mu.Lock()
+	defer mu.Unlock()
+
 	for flow := range *m {
 		delete(*m, flow)
 	}
@@ -0,0 +1,119 @@
+package smtpd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+)
+
+// maxPartsDepth is the maximum nesting level of the multipart bodies searched for attachments
+const maxPartsDepth = 5
+
+// Content describes the main headers and the attachments of a message
+type Content struct {
+	Subject     string
+	From        string
+	To          string
+	MessageID   string
+	Attachments []Attachment
+}
+
+// Attachment describes a file attached to a message, after the decoding of its transfer encoding
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+var wordDecoder = new(mime.WordDecoder)
+
+// ParseContent parses the headers of a message, then walks its multipart body to extract the attachments. The
+// attachments found before a parsing error are returned along with it
+func ParseContent(data []byte) (Content, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return Content{}, err
+	}
+
+	content := Content{
+		Subject:   decodeHeader(msg.Header.Get("Subject")),
+		From:      decodeHeader(msg.Header.Get("From")),
+		To:        decodeHeader(msg.Header.Get("To")),
+		MessageID: msg.Header.Get("Message-ID"),
+	}
+
+	content.Attachments, err = attachments(msg.Header.Get("Content-Type"), msg.Body, 0)
+	return content, err
+}
+
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// attachments returns the parts of a multipart body having a file name, looking into the nested multipart parts
+func attachments(contentType string, body io.Reader, depth int) ([]Attachment, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || depth >= maxPartsDepth {
+		return nil, nil
+	}
+
+	var found []Attachment
+	reader := multipart.NewReader(body, params["boundary"])
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return found, nil
+		} else if err != nil {
+			return found, err
+		}
+
+		partType := part.Header.Get("Content-Type")
+		if strings.HasPrefix(strings.ToLower(partType), "multipart/") {
+			nested, err := attachments(partType, part, depth+1)
+			found = append(found, nested...)
+			if err != nil {
+				return found, err
+			}
+			continue
+		}
+
+		filename := part.FileName()
+		if filename == "" {
+			// Some clients only name the attachments in the Content-Type header
+			if _, typeParams, err := mime.ParseMediaType(partType); err == nil {
+				filename = typeParams["name"]
+			}
+		}
+
+		if filename == "" {
+			continue
+		}
+
+		// The quoted-printable parts are decoded by the multipart reader
+		var partReader io.Reader = part
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			partReader = base64.NewDecoder(base64.StdEncoding, part)
+		}
+
+		data, err := ioutil.ReadAll(partReader)
+		if err != nil {
+			return found, err
+		}
+
+		found = append(found, Attachment{
+			Filename:    decodeHeader(filename),
+			ContentType: partType,
+			Data:        data,
+		})
+	}
+}
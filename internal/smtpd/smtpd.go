@@ -0,0 +1,477 @@
+package smtpd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/netutils"
+)
+
+const (
+	// ClosedByClient is the reason of the sessions closed by the client, with or without a QUIT command
+	ClosedByClient = "client"
+
+	// ClosedByServer is the reason of the sessions closed after too many messages or a failed TLS handshake
+	ClosedByServer = "server"
+
+	// ClosedByTimeout is the reason of the sessions closed after the client stayed idle for too long, or reached the
+	// maximum duration
+	ClosedByTimeout = "timeout"
+
+	// MechanismPlain is the PLAIN SASL mechanism (RFC 4616)
+	MechanismPlain = "plain"
+
+	// MechanismLogin is the non-standard LOGIN SASL mechanism
+	MechanismLogin = "login"
+
+	// maxLineLength is the maximum length of the command lines, as defined by RFC 5321, plus some slack for the
+	// extensions
+	maxLineLength = 2048
+)
+
+// errQuit is returned when the client sends a QUIT command, so that the session is marked as closed by the client
+var errQuit = errors.New("quit")
+
+// Options describes the behavior of a Server
+type Options struct {
+	Hostname string
+	// Banner is sent after the hostname in the greeting
+	Banner string
+	// TLSConfig is used to answer the STARTTLS command. The extension is not offered if it is nil
+	TLSConfig     *tls.Config
+	MaxSize       int
+	MaxRecipients int
+	// MaxMessages is the number of messages accepted in a session before closing it
+	MaxMessages int
+	// Timeout is the time after which the idle clients are disconnected
+	Timeout     time.Duration
+	MaxDuration time.Duration
+}
+
+// Auth describes an authentication attempt
+type Auth struct {
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// Message describes a message sent by a client. The message is not accepted if it is truncated
+type Message struct {
+	MailFrom  string
+	RcptTo    []string
+	Data      []byte
+	Size      int
+	Truncated bool
+	QueueID   string
+}
+
+// Session describes the transactions of a client
+type Session struct {
+	SourceIP   string
+	SourcePort uint16
+	DestPort   uint16
+	Start      time.Time
+	Duration   time.Duration
+	Helo       string
+	TLS        bool
+	Auths      []Auth
+	Messages   []Message
+	ClosedBy   string
+}
+
+// Server is a low-interaction SMTP server, posing as an open relay. It accepts every message, but never relays them
+type Server struct {
+	opts Options
+}
+
+// New creates a Server
+func New(opts Options) *Server {
+	return &Server{opts: opts}
+}
+
+// conn holds the state of an SMTP connection
+type conn struct {
+	net.Conn
+	reader   *bufio.Reader
+	opts     *Options
+	deadline time.Time
+	session  *Session
+
+	message *Message
+}
+
+// readLine returns the next line sent by the client, without its line ending. The lines over maxLength are truncated
+func (c *conn) readLine(maxLength int) (string, error) {
+	deadline := time.Now().Add(c.opts.Timeout)
+	if !c.deadline.IsZero() && c.deadline.Before(deadline) {
+		deadline = c.deadline
+	}
+	_ = c.SetReadDeadline(deadline)
+
+	var line []byte
+	for {
+		chunk, err := c.reader.ReadSlice('\n')
+		if len(line) < maxLength {
+			line = append(line, chunk...)
+		}
+
+		if err != bufio.ErrBufferFull {
+			if len(line) > maxLength {
+				line = line[:maxLength]
+			}
+			return strings.TrimRight(string(line), "\r\n"), err
+		}
+	}
+}
+
+func (c *conn) reply(format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(c, format+"\r\n", args...)
+	return err
+}
+
+// Serve handles an SMTP connection until the client leaves, stays idle for too long, or reaches the maximum duration
+func (s *Server) Serve(netConn net.Conn) Session {
+	session := Session{Start: time.Now()}
+	session.SourceIP, session.SourcePort, session.DestPort = netutils.Endpoints(netConn)
+
+	c := &conn{
+		Conn:    netConn,
+		reader:  bufio.NewReader(netConn),
+		opts:    &s.opts,
+		session: &session,
+	}
+
+	if s.opts.MaxDuration > 0 {
+		c.deadline = session.Start.Add(s.opts.MaxDuration)
+		_ = netConn.SetWriteDeadline(c.deadline)
+	}
+
+	err := c.serve()
+	// The connection may have been upgraded to TLS
+	_ = c.Close()
+
+	session.ClosedBy = ClosedByClient
+	if err == nil {
+		session.ClosedBy = ClosedByServer
+	} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		session.ClosedBy = ClosedByTimeout
+	}
+	session.Duration = time.Since(session.Start)
+
+	return session
+}
+
+// serve answers the commands of the client. A nil error means that the server closed the session
+func (c *conn) serve() error {
+	if err := c.reply("220 %s %s", c.opts.Hostname, c.opts.Banner); err != nil {
+		return err
+	}
+
+	for {
+		line, err := c.readLine(maxLineLength)
+		if err != nil {
+			return err
+		}
+
+		verb, arg := line, ""
+		if idx := strings.IndexByte(line, ' '); idx >= 0 {
+			verb, arg = line[:idx], strings.TrimSpace(line[idx+1:])
+		}
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			c.session.Helo = arg
+			c.message = nil
+			err = c.reply("250 %s", c.opts.Hostname)
+		case "EHLO":
+			c.session.Helo = arg
+			c.message = nil
+			err = c.ehlo()
+		case "STARTTLS":
+			if c.opts.TLSConfig == nil || c.session.TLS {
+				err = c.reply("502 5.5.1 Error: command not implemented")
+				break
+			}
+
+			if err := c.reply("220 2.0.0 Ready to start TLS"); err != nil {
+				return err
+			}
+
+			tlsConn := tls.Server(c.Conn, c.opts.TLSConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return nil
+			}
+
+			// The client must start over after the handshake (RFC 3207)
+			c.Conn = tlsConn
+			c.reader = bufio.NewReader(tlsConn)
+			c.session.TLS = true
+			c.session.Helo = ""
+			c.message = nil
+		case "AUTH":
+			err = c.auth(arg)
+		case "MAIL":
+			err = c.mail(arg)
+		case "RCPT":
+			err = c.rcpt(arg)
+		case "DATA":
+			if c.message == nil || len(c.message.RcptTo) == 0 {
+				err = c.reply("503 5.5.1 Error: need RCPT command")
+				break
+			}
+
+			if err := c.data(); err != nil {
+				return err
+			}
+
+			if len(c.session.Messages) >= c.opts.MaxMessages {
+				_ = c.reply("421 4.7.0 %s Error: too many messages, closing connection", c.opts.Hostname)
+				return nil
+			}
+		case "RSET":
+			c.message = nil
+			err = c.reply("250 2.0.0 Ok")
+		case "NOOP":
+			err = c.reply("250 2.0.0 Ok")
+		case "VRFY":
+			err = c.reply("252 2.0.0 %s", arg)
+		case "QUIT":
+			_ = c.reply("221 2.0.0 Bye")
+			return errQuit
+		default:
+			err = c.reply("502 5.5.2 Error: command not recognized")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *conn) ehlo() error {
+	extensions := []string{
+		c.opts.Hostname,
+		"PIPELINING",
+		fmt.Sprintf("SIZE %d", c.opts.MaxSize),
+		"VRFY",
+		"ETRN",
+	}
+
+	if c.opts.TLSConfig != nil && !c.session.TLS {
+		extensions = append(extensions, "STARTTLS")
+	}
+
+	extensions = append(extensions, "AUTH PLAIN LOGIN", "ENHANCEDSTATUSCODES", "8BITMIME", "DSN")
+
+	for idx, extension := range extensions {
+		sep := "-"
+		if idx == len(extensions)-1 {
+			sep = " "
+		}
+
+		if err := c.reply("250%s%s", sep, extension); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// auth records the credentials sent with the PLAIN or LOGIN mechanisms. Every attempt succeeds
+func (c *conn) auth(arg string) error {
+	if c.session.Helo == "" {
+		return c.reply("503 5.5.1 Error: send HELO/EHLO first")
+	}
+
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return c.reply("501 5.5.4 Syntax: AUTH mechanism")
+	}
+
+	auth := Auth{Mechanism: strings.ToLower(fields[0])}
+
+	switch auth.Mechanism {
+	case MechanismPlain:
+		response := ""
+		if len(fields) > 1 {
+			response = fields[1]
+		} else {
+			if err := c.reply("334 "); err != nil {
+				return err
+			}
+
+			line, err := c.readLine(maxLineLength)
+			if err != nil {
+				return err
+			}
+			response = line
+		}
+
+		// authzid \0 authcid \0 passwd
+		decoded, err := base64.StdEncoding.DecodeString(response)
+		parts := bytes.SplitN(decoded, []byte{0}, 3)
+		if err != nil || len(parts) != 3 {
+			return c.reply("535 5.7.8 Error: authentication failed: bad protocol / cancel")
+		}
+
+		auth.Username = string(parts[1])
+		auth.Password = string(parts[2])
+	case MechanismLogin:
+		values := make([]string, 0, 2)
+		if len(fields) > 1 {
+			values = append(values, fields[1])
+		}
+
+		for _, prompt := range []string{"Username:", "Password:"}[len(values):] {
+			if err := c.reply("334 %s", base64.StdEncoding.EncodeToString([]byte(prompt))); err != nil {
+				return err
+			}
+
+			line, err := c.readLine(maxLineLength)
+			if err != nil {
+				return err
+			}
+			values = append(values, line)
+		}
+
+		for idx, value := range values {
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return c.reply("535 5.7.8 Error: authentication failed: bad protocol / cancel")
+			}
+			values[idx] = string(decoded)
+		}
+
+		auth.Username = values[0]
+		auth.Password = values[1]
+	default:
+		return c.reply("535 5.7.8 Error: authentication failed: Invalid authentication mechanism")
+	}
+
+	c.session.Auths = append(c.session.Auths, auth)
+	return c.reply("235 2.7.0 Authentication successful")
+}
+
+func (c *conn) mail(arg string) error {
+	if c.session.Helo == "" {
+		return c.reply("503 5.5.1 Error: send HELO/EHLO first")
+	}
+
+	if c.message != nil {
+		return c.reply("503 5.5.1 Error: nested MAIL command")
+	}
+
+	address, ok := parsePath(arg, "FROM:")
+	if !ok {
+		return c.reply("501 5.5.4 Syntax: MAIL FROM:<address>")
+	}
+
+	c.message = &Message{MailFrom: address}
+	return c.reply("250 2.1.0 Ok")
+}
+
+func (c *conn) rcpt(arg string) error {
+	if c.message == nil {
+		return c.reply("503 5.5.1 Error: need MAIL command")
+	}
+
+	address, ok := parsePath(arg, "TO:")
+	if !ok || address == "" {
+		return c.reply("501 5.5.4 Syntax: RCPT TO:<address>")
+	}
+
+	if len(c.message.RcptTo) >= c.opts.MaxRecipients {
+		return c.reply("452 4.5.3 Error: too many recipients")
+	}
+
+	c.message.RcptTo = append(c.message.RcptTo, address)
+	return c.reply("250 2.1.5 Ok")
+}
+
+// parsePath returns the address of a MAIL FROM or RCPT TO argument, without its angle brackets and parameters
+func parsePath(arg string, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	path := strings.TrimSpace(arg[len(prefix):])
+	if strings.HasPrefix(path, "<") {
+		end := strings.IndexByte(path, '>')
+		if end < 0 {
+			return "", false
+		}
+		return path[1:end], true
+	}
+
+	if fields := strings.Fields(path); len(fields) > 0 {
+		return fields[0], true
+	}
+
+	return "", false
+}
+
+// data reads the content of a message until the terminating dot line. The messages over the maximum size are read
+// until the end, but truncated and rejected
+func (c *conn) data() error {
+	if err := c.reply("354 End data with <CR><LF>.<CR><LF>"); err != nil {
+		return err
+	}
+
+	message := c.message
+	c.message = nil
+
+	var data bytes.Buffer
+	for {
+		// The lines longer than the maximum size are truncated, but still go over it
+		line, err := c.readLine(c.opts.MaxSize + 1)
+		if err != nil {
+			message.Data = data.Bytes()
+			message.Truncated = true
+			c.session.Messages = append(c.session.Messages, *message)
+			return err
+		}
+
+		if line == "." {
+			break
+		}
+
+		// Dot-stuffing (RFC 5321 4.5.2)
+		line = strings.TrimPrefix(line, ".")
+		message.Size += len(line) + 2
+
+		if message.Size <= c.opts.MaxSize {
+			data.WriteString(line)
+			data.WriteString("\r\n")
+		} else {
+			message.Truncated = true
+		}
+	}
+
+	message.Data = data.Bytes()
+
+	if message.Truncated {
+		c.session.Messages = append(c.session.Messages, *message)
+		return c.reply("552 5.3.4 Error: message file too big")
+	}
+
+	message.QueueID = queueID()
+	c.session.Messages = append(c.session.Messages, *message)
+
+	return c.reply("250 2.0.0 Ok: queued as %s", message.QueueID)
+}
+
+// queueID returns a random queue identifier, in the format used by Postfix
+func queueID() string {
+	buf := make([]byte, 5)
+	_, _ = rand.Read(buf)
+	return strings.ToUpper(hex.EncodeToString(buf))
+}
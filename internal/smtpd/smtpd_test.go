@@ -0,0 +1,144 @@
+package smtpd
+
+import (
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMessage = "From: =?UTF-8?B?QWxpY2U=?= <alice@example.com>\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: Invoice\r\n" +
+	"Message-ID: <1234@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"See attached\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/octet-stream; name=\"invoice.exe\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"TVqQAAMAAAAE\r\n" +
+	"AAAA//8AALgA\r\n" +
+	"--outer--\r\n"
+
+func TestParseContent(t *testing.T) {
+	content, err := ParseContent([]byte(testMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content.Subject != "Invoice" || content.From != "Alice <alice@example.com>" || content.MessageID != "<1234@example.com>" {
+		t.Errorf("unexpected content %+v", content)
+	}
+
+	if len(content.Attachments) != 1 {
+		t.Fatalf("unexpected attachments %+v", content.Attachments)
+	}
+
+	if attachment := content.Attachments[0]; attachment.Filename != "invoice.exe" || len(attachment.Data) != 18 || string(attachment.Data[:2]) != "MZ" {
+		t.Errorf("unexpected attachment %+v", attachment)
+	}
+}
+
+func TestServe(t *testing.T) {
+	server := New(Options{
+		Hostname:      "mail.example.com",
+		Banner:        "ESMTP Postfix",
+		MaxSize:       1024,
+		MaxRecipients: 2,
+		MaxMessages:   10,
+		Timeout:       time.Second,
+	})
+
+	client, conn := net.Pipe()
+	sessions := make(chan Session, 1)
+	go func() {
+		sessions <- server.Serve(conn)
+	}()
+
+	c, err := smtp.NewClient(client, "localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Hello("spammer"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		t.Error("STARTTLS offered without TLS config")
+	}
+
+	if err := c.Auth(smtp.PlainAuth("", "admin", "password123", "localhost")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Mail("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rcpt := range []string{"bob@example.com", "carol@example.com"} {
+		if err := c.Rcpt(rcpt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Rcpt("dave@example.com"); err == nil || !strings.HasPrefix(err.Error(), "452") {
+		t.Errorf("expected the recipients limit to be enforced (%v)", err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte(".hidden line\r\n" + testMessage))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Too large
+	_ = c.Mail("alice@example.com")
+	_ = c.Rcpt("bob@example.com")
+	w, _ = c.Data()
+	_, _ = w.Write([]byte(strings.Repeat("A", 2048)))
+	if err := w.Close(); err == nil || !strings.HasPrefix(err.Error(), "552") {
+		t.Errorf("expected the message to be rejected (%v)", err)
+	}
+
+	if err := c.Quit(); err != nil {
+		t.Fatal(err)
+	}
+
+	session := <-sessions
+	if session.Helo != "spammer" || session.ClosedBy != ClosedByClient || len(session.Messages) != 2 {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	if len(session.Auths) != 1 || session.Auths[0] != (Auth{MechanismPlain, "admin", "password123"}) {
+		t.Errorf("unexpected auths %+v", session.Auths)
+	}
+
+	message := session.Messages[0]
+	if message.MailFrom != "alice@example.com" || len(message.RcptTo) != 2 || message.Truncated || message.QueueID == "" {
+		t.Errorf("unexpected message %+v", message)
+	}
+
+	// The client escapes the leading dot, which must be removed
+	if !strings.HasPrefix(string(message.Data), ".hidden line\r\nFrom:") {
+		t.Errorf("unexpected data %q", message.Data[:32])
+	}
+
+	if rejected := session.Messages[1]; !rejected.Truncated || rejected.QueueID != "" || len(rejected.Data) > 1024 {
+		t.Errorf("unexpected rejected message %+v", rejected)
+	}
+}
@@ -0,0 +1,369 @@
+package snmpparser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagOID         = 0x06
+	tagSequence    = 0x30
+
+	// Maximum number of OIDs kept from a single message
+	maxOIDs = 64
+)
+
+var (
+	// ErrNotSNMP is returned when the payload is not a valid SNMP message
+	ErrNotSNMP = errors.New("not an SNMP message")
+
+	pduTypes = map[byte]string{
+		0xa0: "get-request",
+		0xa1: "get-next-request",
+		0xa2: "response",
+		0xa3: "set-request",
+		0xa4: "trap",
+		0xa5: "get-bulk-request",
+		0xa6: "inform-request",
+		0xa7: "snmpv2-trap",
+		0xa8: "report",
+	}
+)
+
+// Message describes the interesting fields of an SNMP message
+type Message struct {
+	Version   string
+	Community string
+	User      string
+	PDUType   string
+	RequestID int64
+	OIDs      []string
+}
+
+// tlv is a decoded BER Type-Length-Value element
+type tlv struct {
+	tag   byte
+	value []byte
+}
+
+// readTLV reads a single BER element and returns it with the remaining bytes
+func readTLV(b []byte) (tlv, []byte, error) {
+	if len(b) < 2 {
+		return tlv{}, nil, ErrNotSNMP
+	}
+
+	tag := b[0]
+	// Multi-bytes tags are not used by SNMP
+	if tag&0x1f == 0x1f {
+		return tlv{}, nil, ErrNotSNMP
+	}
+
+	length := int(b[1])
+	offset := 2
+
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		// Indefinite length is not allowed in SNMP, and 4 bytes are more than enough for an UDP payload
+		if n == 0 || n > 4 || len(b) < 2+n {
+			return tlv{}, nil, ErrNotSNMP
+		}
+
+		length = 0
+		for _, c := range b[2 : 2+n] {
+			length = length<<8 | int(c)
+		}
+		offset += n
+	}
+
+	if length < 0 || length > len(b)-offset {
+		return tlv{}, nil, ErrNotSNMP
+	}
+
+	return tlv{tag: tag, value: b[offset : offset+length]}, b[offset+length:], nil
+}
+
+// expect reads a BER element and checks its tag
+func expect(b []byte, tag byte) (tlv, []byte, error) {
+	elem, rest, err := readTLV(b)
+	if err != nil {
+		return tlv{}, nil, err
+	}
+
+	if elem.tag != tag {
+		return tlv{}, nil, ErrNotSNMP
+	}
+
+	return elem, rest, nil
+}
+
+func parseInteger(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 8 {
+		return 0, ErrNotSNMP
+	}
+
+	// Sign extend
+	var val int64
+	if b[0]&0x80 != 0 {
+		val = -1
+	}
+
+	for _, c := range b {
+		val = val<<8 | int64(c)
+	}
+
+	return val, nil
+}
+
+// parseOID decodes a BER encoded object identifier to its dotted representation
+func parseOID(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", ErrNotSNMP
+	}
+
+	var parts []string
+	var val uint64
+
+	for idx, c := range b {
+		if val > 1<<56 {
+			return "", ErrNotSNMP
+		}
+
+		val = val<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			if idx == len(b)-1 {
+				return "", ErrNotSNMP
+			}
+			continue
+		}
+
+		if len(parts) == 0 {
+			// The first sub-identifier encodes the first two arcs
+			first := val / 40
+			if first > 2 {
+				first = 2
+			}
+			parts = append(parts, strconv.FormatUint(first, 10), strconv.FormatUint(val-first*40, 10))
+		} else {
+			parts = append(parts, strconv.FormatUint(val, 10))
+		}
+		val = 0
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
+// Parse decodes an SNMP v1, v2c or v3 message
+func Parse(payload []byte) (*Message, error) {
+	msg, rest, err := expect(payload, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trailing data is not expected after the message
+	if len(rest) > 0 {
+		return nil, ErrNotSNMP
+	}
+
+	rawVersion, data, err := expect(msg.value, tagInteger)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := parseInteger(rawVersion.value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch version {
+	case 0, 1:
+		return parseCommunityMessage(version, data)
+	case 3:
+		return parseV3Message(data)
+	}
+
+	return nil, ErrNotSNMP
+}
+
+func parseCommunityMessage(version int64, data []byte) (*Message, error) {
+	message := &Message{Version: "1"}
+	if version == 1 {
+		message.Version = "2c"
+	}
+
+	community, data, err := expect(data, tagOctetString)
+	if err != nil {
+		return nil, err
+	}
+	message.Community = string(community.value)
+
+	if err := message.parsePDU(data); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func parseV3Message(data []byte) (*Message, error) {
+	message := &Message{Version: "3"}
+
+	// msgGlobalData
+	globalData, data, err := expect(data, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+
+	msgID, _, err := expect(globalData.value, tagInteger)
+	if err != nil {
+		return nil, err
+	}
+
+	message.RequestID, err = parseInteger(msgID.value)
+	if err != nil {
+		return nil, err
+	}
+
+	// msgSecurityParameters, holding the USM parameters
+	securityParameters, data, err := expect(data, tagOctetString)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(securityParameters.value) > 0 {
+		usm, _, err := expect(securityParameters.value, tagSequence)
+		if err != nil {
+			return nil, err
+		}
+
+		// msgAuthoritativeEngineID, msgAuthoritativeEngineBoots, msgAuthoritativeEngineTime
+		fields := usm.value
+		for _, tag := range []byte{tagOctetString, tagInteger, tagInteger} {
+			if _, fields, err = expect(fields, tag); err != nil {
+				return nil, err
+			}
+		}
+
+		user, _, err := expect(fields, tagOctetString)
+		if err != nil {
+			return nil, err
+		}
+		message.User = string(user.value)
+	}
+
+	msgData, _, err := readTLV(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch msgData.tag {
+	case tagOctetString:
+		message.PDUType = "encrypted"
+		return message, nil
+
+	case tagSequence:
+		// contextEngineID and contextName precede the PDU
+		scoped := msgData.value
+		for i := 0; i < 2; i++ {
+			if _, scoped, err = expect(scoped, tagOctetString); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := message.parsePDU(scoped); err != nil {
+			return nil, err
+		}
+
+		return message, nil
+	}
+
+	return nil, ErrNotSNMP
+}
+
+// parsePDU decodes the PDU type and the OIDs of its variable bindings
+func (message *Message) parsePDU(data []byte) error {
+	pdu, _, err := readTLV(data)
+	if err != nil {
+		return err
+	}
+
+	pduType, ok := pduTypes[pdu.tag]
+	if !ok {
+		return ErrNotSNMP
+	}
+	message.PDUType = pduType
+
+	fields := pdu.value
+
+	if pdu.tag == 0xa4 {
+		// SNMPv1 Trap-PDU : enterprise, agent-addr, generic-trap, specific-trap, time-stamp
+		enterprise, rest, err := expect(fields, tagOID)
+		if err != nil {
+			return err
+		}
+
+		oid, err := parseOID(enterprise.value)
+		if err != nil {
+			return err
+		}
+		message.OIDs = append(message.OIDs, oid)
+
+		fields = rest
+		for i := 0; i < 4; i++ {
+			if _, fields, err = readTLV(fields); err != nil {
+				return err
+			}
+		}
+	} else {
+		requestID, rest, err := expect(fields, tagInteger)
+		if err != nil {
+			return err
+		}
+
+		// The msgID is kept for v3 messages
+		if message.Version != "3" {
+			message.RequestID, err = parseInteger(requestID.value)
+			if err != nil {
+				return err
+			}
+		}
+
+		// error-status and error-index, or non-repeaters and max-repetitions for GetBulkRequest
+		fields = rest
+		for i := 0; i < 2; i++ {
+			if _, fields, err = expect(fields, tagInteger); err != nil {
+				return err
+			}
+		}
+	}
+
+	varbinds, _, err := expect(fields, tagSequence)
+	if err != nil {
+		return err
+	}
+
+	list := varbinds.value
+	for len(list) > 0 && len(message.OIDs) < maxOIDs {
+		var varbind tlv
+
+		varbind, list, err = expect(list, tagSequence)
+		if err != nil {
+			return err
+		}
+
+		name, _, err := expect(varbind.value, tagOID)
+		if err != nil {
+			return err
+		}
+
+		oid, err := parseOID(name.value)
+		if err != nil {
+			return err
+		}
+
+		message.OIDs = append(message.OIDs, oid)
+	}
+
+	return nil
+}
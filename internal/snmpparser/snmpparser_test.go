@@ -0,0 +1,89 @@
+package snmpparser
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func encode(tag byte, children ...[]byte) []byte {
+	var value []byte
+	for _, child := range children {
+		value = append(value, child...)
+	}
+
+	if len(value) < 0x80 {
+		return append([]byte{tag, byte(len(value))}, value...)
+	}
+
+	return append([]byte{tag, 0x82, byte(len(value) >> 8), byte(len(value))}, value...)
+}
+
+func TestParse(t *testing.T) {
+	// snmpget -v2c -c public <host> 1.3.6.1.2.1.1.1.0
+	v2c, _ := hex.DecodeString("302902010104067075626c6963a01c0204127b3c4d020100020100300e300c06082b060102010101000500")
+
+	sysDescr, _ := hex.DecodeString("2b06010201010100")
+	v3 := encode(tagSequence,
+		[]byte{tagInteger, 1, 3},
+		encode(tagSequence,
+			[]byte{tagInteger, 2, 0x4a, 0x69},
+			[]byte{tagInteger, 3, 0x00, 0xff, 0xe3},
+			[]byte{tagOctetString, 1, 0x04},
+			[]byte{tagInteger, 1, 3},
+		),
+		encode(tagOctetString, encode(tagSequence,
+			[]byte{tagOctetString, 0},
+			[]byte{tagInteger, 1, 0},
+			[]byte{tagInteger, 1, 0},
+			encode(tagOctetString, []byte("admin")),
+			[]byte{tagOctetString, 0},
+			[]byte{tagOctetString, 0},
+		)),
+		encode(tagSequence,
+			[]byte{tagOctetString, 0},
+			[]byte{tagOctetString, 0},
+			encode(0xa5,
+				[]byte{tagInteger, 2, 0x37, 0xf0},
+				[]byte{tagInteger, 1, 0},
+				[]byte{tagInteger, 1, 10},
+				encode(tagSequence, encode(tagSequence, encode(tagOID, sysDescr), []byte{0x05, 0})),
+			),
+		),
+	)
+
+	tests := []struct {
+		Name      string
+		Payload   []byte
+		Version   string
+		Community string
+		User      string
+		PDUType   string
+		OIDs      []string
+	}{
+		{"v2c", v2c, "2c", "public", "", "get-request", []string{"1.3.6.1.2.1.1.1.0"}},
+		{"v3", v3, "3", "", "admin", "get-bulk-request", []string{"1.3.6.1.2.1.1.1.0"}},
+	}
+
+	for _, test := range tests {
+		msg, err := Parse(test.Payload)
+		if err != nil {
+			t.Error(test.Name, "FAILED :", err)
+			continue
+		}
+
+		if msg.Version != test.Version || msg.Community != test.Community || msg.User != test.User || msg.PDUType != test.PDUType {
+			t.Error(test.Name, "FAILED : got", msg)
+			continue
+		}
+
+		if len(msg.OIDs) != len(test.OIDs) || msg.OIDs[0] != test.OIDs[0] {
+			t.Error(test.Name, "FAILED : got OIDs", msg.OIDs)
+		}
+	}
+
+	for _, garbage := range []string{"", "3000", "GET / HTTP/1.1\r\n\r\n", "302902010204067075626c6963a01c"} {
+		if _, err := Parse([]byte(garbage)); err == nil {
+			t.Error(garbage, "FAILED : got no error")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package sshd
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// LoadHostKeys loads the private host keys at the given paths. The missing keys are generated and saved, using the
+// type named in their file name (ed25519, ecdsa, or rsa by default), so that the fingerprints of the server stay the
+// same across restarts
+func LoadHostKeys(paths []string) ([]ssh.Signer, error) {
+	var signers []ssh.Signer
+
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			data, err = generateHostKey(path)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH host key [%s] : %s", path, err)
+		}
+
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSH host key [%s] : %s", path, err)
+		}
+
+		signers = append(signers, signer)
+	}
+
+	return signers, nil
+}
+
+func generateHostKey(path string) ([]byte, error) {
+	var block *pem.Block
+	name := strings.ToLower(filepath.Base(path))
+
+	switch {
+	case strings.Contains(name, "ed25519"):
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	case strings.Contains(name, "ecdsa"):
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	default:
+		key, err := rsa.GenerateKey(rand.Reader, 3072)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	}
+
+	data := pem.EncodeToMemory(block)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	return data, ioutil.WriteFile(path, data, 0600)
+}
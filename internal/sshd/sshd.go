@@ -0,0 +1,309 @@
+package sshd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/netutils"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// ActionAuth is the action of the authentication attempts
+	ActionAuth = "auth"
+
+	// ActionExec is the action of the commands sent with an exec request
+	ActionExec = "exec"
+
+	// ActionShell is the action of the command lines typed in an interactive shell
+	ActionShell = "shell"
+
+	// ActionSubsystem is the action of the subsystem requests, such as sftp, which are refused
+	ActionSubsystem = "subsystem"
+
+	// ActionForward is the action of the port forwarding requests, which are refused
+	ActionForward = "forward"
+
+	// MethodPassword is the method of the password authentication attempts
+	MethodPassword = "password"
+
+	// MethodPublicKey is the method of the public key authentication attempts
+	MethodPublicKey = "publickey"
+
+	// MethodKeyboardInteractive is the method of the keyboard-interactive authentication attempts
+	MethodKeyboardInteractive = "keyboard-interactive"
+
+	// maxLineLength is the maximum length of the command lines typed in the interactive shells
+	maxLineLength = 4096
+)
+
+var errDenied = errors.New("permission denied")
+
+// Options describes the behavior of a Server
+type Options struct {
+	// Version is the identification string announced to the clients, starting with "SSH-2.0-"
+	Version string
+	// Hostname is used in the prompt of the interactive shells
+	Hostname string
+	Signers  []ssh.Signer
+	// Accept lists the "username:password" pairs accepted by the server, where "*" matches any value. Everything is
+	// rejected if it is empty
+	Accept []string
+	// MaxDuration is the time after which the connections are closed
+	MaxDuration time.Duration
+}
+
+// Activity describes an action of an SSH client
+type Activity struct {
+	SourceIP       string
+	SourcePort     uint16
+	DestPort       uint16
+	ClientVersion  string
+	Action         string
+	Username       string
+	Method         string
+	Password       string
+	KeyType        string
+	KeyFingerprint string
+	Accepted       bool
+	Command        string
+}
+
+// Server is a low-interaction SSH server, logging the authentication attempts and the commands of its clients
+type Server struct {
+	opts Options
+}
+
+// New creates a Server
+func New(opts Options) *Server {
+	return &Server{opts: opts}
+}
+
+// accepts checks if the given credentials are in the accepted list
+func (s *Server) accepts(username string, password string) bool {
+	for _, pair := range s.opts.Accept {
+		idx := strings.Index(pair, ":")
+		if idx < 0 {
+			continue
+		}
+
+		if matchWildcard(pair[:idx], username) && matchWildcard(pair[idx+1:], password) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchWildcard(pattern string, value string) bool {
+	return pattern == "*" || pattern == value
+}
+
+// ServeConn handles an SSH connection until the client leaves or the maximum duration is reached. The handle function
+// is called for each action of the client
+func (s *Server) ServeConn(conn net.Conn, handle func(Activity)) {
+	defer conn.Close()
+
+	if s.opts.MaxDuration > 0 {
+		_ = conn.SetDeadline(time.Now().Add(s.opts.MaxDuration))
+	}
+
+	base := Activity{}
+	base.SourceIP, base.SourcePort, base.DestPort = netutils.Endpoints(conn)
+
+	newActivity := func(meta ssh.ConnMetadata, action string) Activity {
+		activity := base
+		activity.ClientVersion = string(meta.ClientVersion())
+		activity.Username = meta.User()
+		activity.Action = action
+		return activity
+	}
+
+	cfg := &ssh.ServerConfig{
+		ServerVersion: s.opts.Version,
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			activity := newActivity(meta, ActionAuth)
+			activity.Method = MethodPassword
+			activity.Password = string(password)
+			activity.Accepted = s.accepts(meta.User(), activity.Password)
+			handle(activity)
+
+			if !activity.Accepted {
+				return nil, errDenied
+			}
+			return nil, nil
+		},
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			activity := newActivity(meta, ActionAuth)
+			activity.Method = MethodPublicKey
+			activity.KeyType = key.Type()
+			activity.KeyFingerprint = ssh.FingerprintSHA256(key)
+			handle(activity)
+
+			return nil, errDenied
+		},
+		KeyboardInteractiveCallback: func(meta ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := client("", "", []string{"Password: "}, []bool{false})
+			if err != nil || len(answers) != 1 {
+				return nil, errDenied
+			}
+
+			activity := newActivity(meta, ActionAuth)
+			activity.Method = MethodKeyboardInteractive
+			activity.Password = answers[0]
+			activity.Accepted = s.accepts(meta.User(), activity.Password)
+			handle(activity)
+
+			if !activity.Accepted {
+				return nil, errDenied
+			}
+			return nil, nil
+		},
+	}
+
+	for _, signer := range s.opts.Signers {
+		cfg.AddHostKey(signer)
+	}
+
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	defer sconn.Close()
+
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		activity := newActivity(sconn, "")
+
+		switch newChan.ChannelType() {
+		case "session":
+			channel, requests, err := newChan.Accept()
+			if err != nil {
+				continue
+			}
+
+			go s.serveSession(channel, requests, activity, handle)
+		case "direct-tcpip":
+			activity.Action = ActionForward
+			activity.Command = forwardDestination(newChan.ExtraData())
+			handle(activity)
+
+			_ = newChan.Reject(ssh.Prohibited, "administratively prohibited")
+		default:
+			_ = newChan.Reject(ssh.UnknownChannelType, "unknown channel type")
+		}
+	}
+}
+
+// forwardDestination returns the destination of a direct-tcpip channel request
+func forwardDestination(data []byte) string {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+
+	if err := ssh.Unmarshal(data, &payload); err != nil {
+		return ""
+	}
+
+	return net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port)))
+}
+
+// serveSession answers the requests of a session channel. The exec requests succeed without any output, while the
+// shells only show a prompt
+func (s *Server) serveSession(channel ssh.Channel, requests <-chan *ssh.Request, base Activity, handle func(Activity)) {
+	defer channel.Close()
+
+	for req := range requests {
+		switch req.Type {
+		case "pty-req", "env", "window-change":
+			_ = req.Reply(req.Type != "window-change", nil)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+
+			activity := base
+			activity.Action = ActionExec
+			activity.Command = payload.Command
+			handle(activity)
+
+			_ = req.Reply(true, nil)
+			exit(channel, 0)
+			return
+		case "shell":
+			_ = req.Reply(true, nil)
+			go ssh.DiscardRequests(requests)
+
+			s.shell(channel, base, handle)
+			exit(channel, 0)
+			return
+		case "subsystem":
+			var payload struct{ Name string }
+			_ = ssh.Unmarshal(req.Payload, &payload)
+
+			activity := base
+			activity.Action = ActionSubsystem
+			activity.Command = payload.Name
+			handle(activity)
+
+			_ = req.Reply(false, nil)
+		default:
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// shell reads the command lines typed by the client until it leaves
+func (s *Server) shell(channel ssh.Channel, base Activity, handle func(Activity)) {
+	prompt := fmt.Sprintf("%s@%s:~$ ", base.Username, s.opts.Hostname)
+	if base.Username == "root" {
+		prompt = fmt.Sprintf("root@%s:~# ", s.opts.Hostname)
+	}
+
+	term := terminal.NewTerminal(channel, prompt)
+	for {
+		line, err := term.ReadLine()
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if len(line) > maxLineLength {
+			line = line[:maxLineLength]
+		}
+
+		activity := base
+		activity.Action = ActionShell
+		activity.Command = line
+		handle(activity)
+
+		if line == "exit" || line == "logout" {
+			return
+		}
+	}
+}
+
+func exit(channel ssh.Channel, status uint32) {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, status)
+	_, _ = channel.SendRequest("exit-status", false, payload)
+}
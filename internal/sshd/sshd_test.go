@@ -0,0 +1,141 @@
+package sshd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestLoadHostKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sshd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths := []string{filepath.Join(dir, "ssh_host_ed25519_key"), filepath.Join(dir, "ssh_host_ecdsa_key")}
+	signers, err := LoadHostKeys(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signers[0].PublicKey().Type() != ssh.KeyAlgoED25519 || signers[1].PublicKey().Type() != ssh.KeyAlgoECDSA256 {
+		t.Fatalf("unexpected key types %s, %s", signers[0].PublicKey().Type(), signers[1].PublicKey().Type())
+	}
+
+	// The generated keys are loaded again on the next start
+	reloaded, err := LoadHostKeys(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ssh.FingerprintSHA256(reloaded[0].PublicKey()) != ssh.FingerprintSHA256(signers[0].PublicKey()) {
+		t.Error("the host key changed after a reload")
+	}
+}
+
+func TestServeConn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sshd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	signers, err := LoadHostKeys([]string{filepath.Join(dir, "ssh_host_ed25519_key")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := New(Options{
+		Version:     "SSH-2.0-OpenSSH_7.9p1 Debian-10+deb10u2",
+		Hostname:    "debian",
+		Signers:     signers,
+		Accept:      []string{"root:*"},
+		MaxDuration: 5 * time.Second,
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var lock sync.Mutex
+	var activities []Activity
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go server.ServeConn(conn, func(activity Activity) {
+				lock.Lock()
+				activities = append(activities, activity)
+				lock.Unlock()
+			})
+		}
+	}()
+
+	dial := func(user string, password string) (*ssh.Client, error) {
+		return ssh.Dial("tcp", ln.Addr().String(), &ssh.ClientConfig{
+			User:            user,
+			Auth:            []ssh.AuthMethod{ssh.Password(password)},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			ClientVersion:   "SSH-2.0-Go-test",
+			Timeout:         time.Second,
+		})
+	}
+
+	if _, err := dial("admin", "admin"); err == nil {
+		t.Fatal("expected the credentials to be rejected")
+	}
+
+	client, err := dial("root", "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := session.Run("uname -a"); err != nil {
+		t.Errorf("unexpected exec error : %s", err)
+	}
+	client.Close()
+
+	// Wait for the server to handle the end of the connection
+	time.Sleep(50 * time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	expected := []Activity{
+		{Action: ActionAuth, Username: "admin", Method: MethodPassword, Password: "admin"},
+		{Action: ActionAuth, Username: "root", Method: MethodPassword, Password: "123456", Accepted: true},
+		{Action: ActionExec, Username: "root", Command: "uname -a"},
+	}
+
+	if len(activities) != len(expected) {
+		t.Fatalf("unexpected activities %+v", activities)
+	}
+
+	for idx, activity := range activities {
+		if activity.ClientVersion != "SSH-2.0-Go-test" || activity.SourceIP != "127.0.0.1" {
+			t.Errorf("unexpected activity %+v", activity)
+		}
+
+		activity.ClientVersion, activity.SourceIP, activity.SourcePort, activity.DestPort = "", "", 0, 0
+		if activity != expected[idx] {
+			t.Errorf("unexpected activity %+v, expected %+v", activity, expected[idx])
+		}
+	}
+}
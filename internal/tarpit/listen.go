@@ -0,0 +1,31 @@
+package tarpit
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// receiveBufferSize is the receive buffer requested for the TCP tarpit sockets. The kernel rounds it up to its
+// minimum, which keeps the window advertised to the clients as small as possible
+const receiveBufferSize = 1
+
+// ListenTCP opens a TCP listener on the given address and port, whose connections advertise the smallest window
+// possible from the handshake on
+func ListenTCP(address string, port int) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(network string, address string, c syscall.RawConn) error {
+			var sockErr error
+			if err := c.Control(func(fd uintptr) {
+				sockErr = setReceiveBuffer(fd, receiveBufferSize)
+			}); err != nil {
+				return err
+			}
+
+			return sockErr
+		},
+	}
+
+	return lc.Listen(context.Background(), "tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+}
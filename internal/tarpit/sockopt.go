@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package tarpit
+
+import "syscall"
+
+func setReceiveBuffer(fd uintptr, size int) error {
+	return syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF, size)
+}
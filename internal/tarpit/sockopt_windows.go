@@ -0,0 +1,7 @@
+package tarpit
+
+import "syscall"
+
+func setReceiveBuffer(fd uintptr, size int) error {
+	return syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF, size)
+}
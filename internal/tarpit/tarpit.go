@@ -0,0 +1,260 @@
+package tarpit
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// ModeHeaders sends an endless stream of headers, one line at a time
+	ModeHeaders = "headers"
+
+	// ModeBody announces a huge body and sends it one byte at a time
+	ModeBody = "body"
+
+	// ModeChunked sends an endless chunked body, one small chunk at a time
+	ModeChunked = "chunked"
+
+	// ModeTCP holds the TCP connections open without answering, reading as little as possible so that the advertised
+	// window stays closed
+	ModeTCP = "tcp"
+
+	// ReleasedByClient is the reason of the summaries of the connections closed by the client
+	ReleasedByClient = "client"
+
+	// ReleasedByTimeout is the reason of the summaries of the connections closed after the maximum duration
+	ReleasedByTimeout = "timeout"
+
+	// bodyLength is the Content-Length announced in the body mode
+	bodyLength = 1 << 30
+
+	letters = "abcdefghijklmnopqrstuvwxyz"
+)
+
+var (
+	// HTTPModes lists the tarpit modes available to the dummy HTTP/S servers
+	HTTPModes = []string{ModeHeaders, ModeBody, ModeChunked}
+)
+
+// Options describes the behavior of a Tarpit
+type Options struct {
+	// Interval is the time between two writes to the trapped clients
+	Interval time.Duration
+	// MaxDuration is the time after which the clients are released, 0 to hold them until they leave
+	MaxDuration time.Duration
+	// MaxConnections is the maximum number of clients trapped at the same time, 0 for no limit
+	MaxConnections int
+}
+
+// Summary describes the time spent by a client in the tarpit
+type Summary struct {
+	SourceIP      string
+	SourcePort    uint16
+	DestPort      uint16
+	Mode          string
+	Start         time.Time
+	Duration      time.Duration
+	BytesSent     int64
+	BytesReceived int64
+	ReleasedBy    string
+}
+
+// Tarpit holds the clients as long as possible, sending them data slowly enough to keep them waiting
+type Tarpit struct {
+	opts  Options
+	slots chan struct{}
+}
+
+// New creates a Tarpit
+func New(opts Options) *Tarpit {
+	t := &Tarpit{opts: opts}
+	if opts.MaxConnections > 0 {
+		t.slots = make(chan struct{}, opts.MaxConnections)
+	}
+
+	return t
+}
+
+// acquire reserves a slot for a new client, and returns false if the tarpit is full
+func (t *Tarpit) acquire() bool {
+	if t.slots == nil {
+		return true
+	}
+
+	select {
+	case t.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *Tarpit) release() {
+	if t.slots != nil {
+		<-t.slots
+	}
+}
+
+// hold calls write at each interval until it fails, done is closed, or the maximum duration is reached
+func (t *Tarpit) hold(done <-chan struct{}, write func() (int, error)) (int64, string) {
+	var sent int64
+
+	ticker := time.NewTicker(t.opts.Interval)
+	defer ticker.Stop()
+
+	var timeout <-chan time.Time
+	if t.opts.MaxDuration > 0 {
+		timer := time.NewTimer(t.opts.MaxDuration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		select {
+		case <-done:
+			return sent, ReleasedByClient
+		case <-timeout:
+			return sent, ReleasedByTimeout
+		case <-ticker.C:
+			n, err := write()
+			sent += int64(n)
+			if err != nil {
+				return sent, ReleasedByClient
+			}
+		}
+	}
+}
+
+// ServeHTTP traps the client of the request using the given mode. It returns once the client is released, along with
+// false if the tarpit was full, in which case nothing has been written
+func (t *Tarpit) ServeHTTP(w http.ResponseWriter, r *http.Request, mode string) (Summary, bool) {
+	if !t.acquire() {
+		return Summary{}, false
+	}
+	defer t.release()
+
+	summary := newSummary(r.RemoteAddr, mode)
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		summary.DestPort = addrPort(addr)
+	}
+
+	switch mode {
+	case ModeHeaders:
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, buf, err := hijacker.Hijack(); err == nil {
+				defer conn.Close()
+
+				// Discard what the client sends, to notice when it leaves
+				done := make(chan struct{})
+				go func() {
+					summary.BytesReceived, _ = io.Copy(ioutil.Discard, buf.Reader)
+					close(done)
+				}()
+
+				n, _ := conn.Write([]byte("HTTP/1.1 200 OK\r\n"))
+				summary.BytesSent, summary.ReleasedBy = t.hold(done, func() (int, error) {
+					return conn.Write([]byte(randomString(4) + ": " + randomString(12) + "\r\n"))
+				})
+				summary.BytesSent += int64(n)
+
+				// Wait for the reader to count the received bytes
+				_ = conn.Close()
+				<-done
+				break
+			}
+		}
+
+		// HTTP/2 connections cannot be hijacked, use an endless body instead
+		fallthrough
+	case ModeChunked:
+		flusher, _ := w.(http.Flusher)
+		w.WriteHeader(http.StatusOK)
+		summary.BytesSent, summary.ReleasedBy = t.hold(r.Context().Done(), func() (int, error) {
+			n, err := w.Write([]byte(randomString(8)))
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return n, err
+		})
+	case ModeBody:
+		flusher, _ := w.(http.Flusher)
+		w.Header().Set("Content-Length", strconv.Itoa(bodyLength))
+		w.WriteHeader(http.StatusOK)
+		summary.BytesSent, summary.ReleasedBy = t.hold(r.Context().Done(), func() (int, error) {
+			n, err := w.Write([]byte(randomString(1)))
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return n, err
+		})
+	}
+
+	summary.Duration = time.Since(summary.Start)
+	return summary, true
+}
+
+// ServeConn traps the given TCP connection and closes it once released. It returns false if the tarpit was full, in
+// which case the connection is closed immediately
+func (t *Tarpit) ServeConn(conn net.Conn) (Summary, bool) {
+	defer conn.Close()
+
+	if !t.acquire() {
+		return Summary{}, false
+	}
+	defer t.release()
+
+	summary := newSummary(conn.RemoteAddr().String(), ModeTCP)
+	summary.DestPort = addrPort(conn.LocalAddr())
+
+	// Read a single byte at each interval, which is not enough for the kernel to reopen the window
+	buf := make([]byte, 1)
+	_, summary.ReleasedBy = t.hold(nil, func() (int, error) {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+		n, err := conn.Read(buf)
+		summary.BytesReceived += int64(n)
+
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return 0, nil
+		}
+
+		return 0, err
+	})
+
+	summary.Duration = time.Since(summary.Start)
+	return summary, true
+}
+
+func newSummary(remoteAddr string, mode string) Summary {
+	summary := Summary{Mode: mode, Start: time.Now()}
+
+	host, port, err := net.SplitHostPort(remoteAddr)
+	if err == nil {
+		srcPort, _ := strconv.ParseUint(port, 10, 16)
+		summary.SourceIP = host
+		summary.SourcePort = uint16(srcPort)
+	}
+
+	return summary
+}
+
+func addrPort(addr net.Addr) uint16 {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return uint16(tcpAddr.Port)
+	}
+
+	return 0
+}
+
+func randomString(length int) string {
+	b := make([]byte, length)
+	for idx := range b {
+		b[idx] = letters[rand.Intn(len(letters))]
+	}
+
+	return string(b)
+}
@@ -0,0 +1,106 @@
+package tarpit
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeHTTP(t *testing.T) {
+	trap := New(Options{Interval: 5 * time.Millisecond, MaxDuration: 100 * time.Millisecond})
+
+	for _, mode := range HTTPModes {
+		summaries := make(chan Summary, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			summary, ok := trap.ServeHTTP(w, r, mode)
+			if !ok {
+				t.Error("unexpected full tarpit")
+			}
+			summaries <- summary
+		}))
+
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		status, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil || !strings.HasPrefix(status, "HTTP/1.1 200") {
+			t.Errorf("%s : unexpected status line %q (%v)", mode, status, err)
+		}
+
+		summary := <-summaries
+		if summary.Mode != mode || summary.ReleasedBy != ReleasedByTimeout || summary.BytesSent == 0 || summary.Duration < 100*time.Millisecond {
+			t.Errorf("%s : unexpected summary %+v", mode, summary)
+		}
+
+		conn.Close()
+		srv.Close()
+	}
+}
+
+func TestServeConn(t *testing.T) {
+	ln, err := ListenTCP("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	trap := New(Options{Interval: 5 * time.Millisecond, MaxConnections: 1})
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summaries := make(chan Summary, 1)
+	go func() {
+		summary, _ := trap.ServeConn(conn)
+		summaries <- summary
+	}()
+
+	if _, err := client.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// The only slot is taken
+	other, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	otherConn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := trap.ServeConn(otherConn); ok {
+		t.Error("expected the tarpit to be full")
+	}
+
+	client.Close()
+
+	select {
+	case summary := <-summaries:
+		if summary.Mode != ModeTCP || summary.ReleasedBy != ReleasedByClient || summary.BytesReceived != 5 {
+			t.Errorf("unexpected summary %+v", summary)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the connection was not released")
+	}
+}
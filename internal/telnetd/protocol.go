@@ -0,0 +1,183 @@
+package telnetd
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"time"
+)
+
+// Telnet commands and options (RFC 854, RFC 857, RFC 858, RFC 1073)
+const (
+	cmdSE   = 240
+	cmdSB   = 250
+	cmdWill = 251
+	cmdWont = 252
+	cmdDo   = 253
+	cmdDont = 254
+	cmdIAC  = 255
+
+	optEcho = 1
+	optSGA  = 3
+	optNAWS = 31
+)
+
+// negotiation is sent to the clients as soon as they connect, like the BusyBox telnetd does : the server echoes the
+// input and suppresses the go-ahead, and asks for the window size
+var negotiation = []byte{
+	cmdIAC, cmdDo, optEcho,
+	cmdIAC, cmdDo, optNAWS,
+	cmdIAC, cmdWill, optEcho,
+	cmdIAC, cmdWill, optSGA,
+}
+
+// conn reads the lines typed by a Telnet client, answering its option negotiations along the way
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+
+	timeout  time.Duration
+	deadline time.Time
+
+	// skipLF is set after a carriage return, as it can be followed by a line feed or a NUL byte
+	skipLF bool
+	echo   []byte
+}
+
+func newConn(c net.Conn, timeout time.Duration, deadline time.Time) *conn {
+	return &conn{
+		Conn:     c,
+		reader:   bufio.NewReader(c),
+		timeout:  timeout,
+		deadline: deadline,
+	}
+}
+
+// readByte reads the next byte sent by the client. The pending echo is flushed and the read deadline is extended
+// before waiting for more data
+func (c *conn) readByte() (byte, error) {
+	if c.reader.Buffered() == 0 {
+		if err := c.flushEcho(); err != nil {
+			return 0, err
+		}
+
+		deadline := time.Now().Add(c.timeout)
+		if !c.deadline.IsZero() && c.deadline.Before(deadline) {
+			deadline = c.deadline
+		}
+		_ = c.SetReadDeadline(deadline)
+	}
+
+	return c.reader.ReadByte()
+}
+
+// flushEcho sends the pending echo of the typed characters
+func (c *conn) flushEcho() error {
+	if len(c.echo) == 0 {
+		return nil
+	}
+
+	_, err := c.Write(c.echo)
+	c.echo = c.echo[:0]
+	return err
+}
+
+// readLine returns the next line typed by the client, without its line ending. The typed characters are echoed back
+// if echo is set
+func (c *conn) readLine(echo bool) ([]byte, error) {
+	var line []byte
+
+	for {
+		b, err := c.readByte()
+		if err != nil {
+			return line, err
+		}
+
+		skipLF := c.skipLF
+		c.skipLF = false
+
+		switch {
+		case b == cmdIAC:
+			escaped, err := c.command()
+			if err != nil {
+				return line, err
+			}
+
+			// IAC IAC stands for a 0xff data byte
+			if escaped && len(line) < maxLineLength {
+				line = append(line, cmdIAC)
+			}
+			c.skipLF = skipLF && !escaped
+		case skipLF && (b == '\n' || b == 0):
+			continue
+		case b == '\r' || b == '\n':
+			c.skipLF = b == '\r'
+			if echo {
+				c.echo = append(c.echo, '\r', '\n')
+			}
+			return line, c.flushEcho()
+		case b == 0x04 && len(line) == 0:
+			// Ctrl-D on an empty line
+			return line, io.EOF
+		case b == 0x7f || b == 0x08:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				if echo {
+					c.echo = append(c.echo, '\b', ' ', '\b')
+				}
+			}
+		case len(line) < maxLineLength:
+			line = append(line, b)
+			if echo {
+				c.echo = append(c.echo, b)
+			}
+		}
+	}
+}
+
+// command handles a command following an IAC byte, and reports whether it was an escaped 0xff data byte. The options
+// requested by the client are refused, except the ones offered in the initial negotiation
+func (c *conn) command() (bool, error) {
+	cmd, err := c.readByte()
+	if err != nil {
+		return false, err
+	}
+
+	switch cmd {
+	case cmdIAC:
+		return true, nil
+	case cmdWill, cmdWont, cmdDo, cmdDont:
+		opt, err := c.readByte()
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case cmd == cmdDo && opt != optEcho && opt != optSGA:
+			_, err = c.Write([]byte{cmdIAC, cmdWont, opt})
+		case cmd == cmdWill && opt != optEcho && opt != optNAWS:
+			_, err = c.Write([]byte{cmdIAC, cmdDont, opt})
+		}
+		return false, err
+	case cmdSB:
+		// Skip the subnegotiation, such as the window size, until IAC SE
+		var prev byte
+		for {
+			b, err := c.readByte()
+			if err != nil {
+				return false, err
+			}
+
+			if prev == cmdIAC && b == cmdSE {
+				return false, nil
+			}
+
+			if prev == cmdIAC && b == cmdIAC {
+				b = 0
+			}
+			prev = b
+		}
+	}
+
+	return false, nil
+}
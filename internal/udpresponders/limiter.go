@@ -0,0 +1,49 @@
+package udpresponders
+
+import (
+	"sync"
+	"time"
+)
+
+// limiter counts the replies sent to each source over fixed windows, as well as the replies sent to all of them, so
+// that the sensor cannot be used as a reflector even with spoofed sources
+type limiter struct {
+	perSource int
+	global    int
+	window    time.Duration
+
+	lock        sync.Mutex
+	windowStart time.Time
+	total       int
+	sources     map[string]int
+}
+
+func newLimiter(perSource int, global int, window time.Duration) *limiter {
+	return &limiter{
+		perSource: perSource,
+		global:    global,
+		window:    window,
+		sources:   make(map[string]int),
+	}
+}
+
+// allow checks if a reply can be sent to the given source, and counts it if so
+func (l *limiter) allow(source string, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart = now
+		l.total = 0
+		l.sources = make(map[string]int)
+	}
+
+	if l.total >= l.global || l.sources[source] >= l.perSource {
+		return false
+	}
+
+	l.total++
+	l.sources[source]++
+
+	return true
+}
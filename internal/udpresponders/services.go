@@ -0,0 +1,246 @@
+package udpresponders
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// The replies are small and harmless : they never carry more than the few records needed to look like a real service,
+// so that the amplification factor stays low
+
+// DNS constants (RFC 1035)
+const (
+	dnsHeaderSize = 12
+	dnsTypeA      = 1
+	dnsTypeTXT    = 16
+	dnsTypeAAAA   = 28
+	dnsClassIN    = 1
+	dnsClassCH    = 3
+	dnsMaxName    = 255
+	dnsTTL        = 300
+)
+
+const (
+	dnsVersion  = "9.11.5-P4-5.1+deb10u5-Debian"
+	ntpVersion  = `version="ntpd 4.2.8p12@1.3728-o (1)", processor="x86_64", system="Linux/4.19.0-16-amd64", leap=0, stratum=2`
+	ssdpServer  = "Linux/3.14.0 UPnP/1.0 MiniUPnPd/1.9"
+	ssdpUUID    = "uuid:4d696e69-444c-164e-9d41-b0c5ca2b2e01"
+	memcVersion = "1.5.6"
+
+	// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and the Unix epoch (1970)
+	ntpEpochOffset = 2208988800
+)
+
+// answerDNS answers the A and AAAA queries with the address of the sensor, and the version.bind CHAOS query with the
+// version of the server. The other queries get an empty answer. The additional records of the query, such as the
+// EDNS options, are not sent back
+func answerDNS(query []byte, local net.IP) []byte {
+	if len(query) < dnsHeaderSize {
+		return nil
+	}
+
+	flags := binary.BigEndian.Uint16(query[2:4])
+	opcode := (flags >> 11) & 0xf
+	if flags&0x8000 != 0 || opcode != 0 || binary.BigEndian.Uint16(query[4:6]) != 1 {
+		return nil
+	}
+
+	// The names of the queries are not compressed
+	end := dnsHeaderSize
+	for end < len(query) && query[end] != 0 {
+		end += int(query[end]) + 1
+		if end-dnsHeaderSize > dnsMaxName {
+			return nil
+		}
+	}
+
+	end += 5
+	if end > len(query) {
+		return nil
+	}
+
+	question := query[dnsHeaderSize:end]
+	qtype := binary.BigEndian.Uint16(question[len(question)-4:])
+	qclass := binary.BigEndian.Uint16(question[len(question)-2:])
+
+	var rtype uint16
+	var rdata []byte
+
+	switch {
+	case qclass == dnsClassIN && qtype == dnsTypeA && local.To4() != nil:
+		rtype, rdata = dnsTypeA, local.To4()
+	case qclass == dnsClassIN && qtype == dnsTypeAAAA && local.To4() == nil && local.To16() != nil:
+		rtype, rdata = dnsTypeAAAA, local.To16()
+	case qclass == dnsClassCH && qtype == dnsTypeTXT && strings.EqualFold(string(question[:len(question)-4]), "\x07version\x04bind\x00"):
+		rtype, rdata = dnsTypeTXT, append([]byte{byte(len(dnsVersion))}, dnsVersion...)
+	}
+
+	var reply bytes.Buffer
+	header := make([]byte, dnsHeaderSize)
+	copy(header[0:2], query[0:2])
+	// Response, recursion desired copied from the query, recursion available
+	binary.BigEndian.PutUint16(header[2:4], 0x8000|flags&0x0100|0x0080)
+	binary.BigEndian.PutUint16(header[4:6], 1)
+	if rdata != nil {
+		binary.BigEndian.PutUint16(header[6:8], 1)
+	}
+
+	reply.Write(header)
+	reply.Write(question)
+
+	if rdata != nil {
+		record := make([]byte, 12)
+		// Pointer to the name of the question
+		binary.BigEndian.PutUint16(record[0:2], 0xc000|dnsHeaderSize)
+		binary.BigEndian.PutUint16(record[2:4], rtype)
+		binary.BigEndian.PutUint16(record[4:6], qclass)
+		binary.BigEndian.PutUint32(record[6:10], dnsTTL)
+		binary.BigEndian.PutUint16(record[10:12], uint16(len(rdata)))
+
+		reply.Write(record)
+		reply.Write(rdata)
+	}
+
+	return reply.Bytes()
+}
+
+// answerNTP answers the client requests as a stratum 2 server, the control requests with the version of the server,
+// and the monlist requests with an error, as if the feature was disabled
+func answerNTP(query []byte, _ net.IP) []byte {
+	if len(query) < 8 {
+		return nil
+	}
+
+	version := (query[0] >> 3) & 0x7
+
+	switch query[0] & 0x7 {
+	case 3:
+		if len(query) < 48 {
+			return nil
+		}
+
+		now := time.Now()
+		reply := make([]byte, 48)
+		reply[0] = version<<3 | 4
+		reply[1] = 2
+		reply[2] = query[2]
+		// Precision of about 100 ns
+		reply[3] = 0xe9
+		binary.BigEndian.PutUint32(reply[4:8], 0x00000a3c)
+		binary.BigEndian.PutUint32(reply[8:12], 0x00001b58)
+		// Reference ID of the upstream server
+		copy(reply[12:16], []byte{192, 0, 2, 123})
+		putNTPTime(reply[16:24], now.Add(-17*time.Minute))
+		copy(reply[24:32], query[40:48])
+		putNTPTime(reply[32:40], now)
+		putNTPTime(reply[40:48], now)
+
+		return reply
+	case 6:
+		if len(query) < 12 {
+			return nil
+		}
+
+		var data []byte
+		// Read variables
+		if query[1]&0x1f == 2 {
+			data = []byte(ntpVersion)
+		}
+
+		reply := make([]byte, 12, 12+len(data)+3)
+		reply[0] = version<<3 | 6
+		reply[1] = 0x80 | query[1]&0x1f
+		copy(reply[2:4], query[2:4])
+		copy(reply[6:8], query[6:8])
+		binary.BigEndian.PutUint16(reply[10:12], uint16(len(data)))
+		reply = append(reply, data...)
+
+		// Padding to a multiple of 4 bytes
+		for len(reply)%4 != 0 {
+			reply = append(reply, 0)
+		}
+
+		return reply
+	case 7:
+		// No data error
+		return []byte{0x80 | version<<3 | 7, 0, query[2], query[3], 0x40, 0, 0, 0}
+	}
+
+	return nil
+}
+
+func putNTPTime(dst []byte, t time.Time) {
+	binary.BigEndian.PutUint32(dst[0:4], uint32(t.Unix()+ntpEpochOffset))
+	binary.BigEndian.PutUint32(dst[4:8], uint32((uint64(t.Nanosecond())<<32)/uint64(time.Second)))
+}
+
+// answerSSDP answers the M-SEARCH requests as a MiniUPnP router, pointing to a description on the sensor
+func answerSSDP(query []byte, local net.IP) []byte {
+	if !bytes.HasPrefix(query, []byte("M-SEARCH ")) || local == nil {
+		return nil
+	}
+
+	st := "upnp:rootdevice"
+	for _, line := range strings.Split(string(query), "\r\n") {
+		idx := strings.IndexByte(line, ':')
+		if idx < 0 || !strings.EqualFold(strings.TrimSpace(line[:idx]), "ST") {
+			continue
+		}
+
+		value := strings.TrimSpace(line[idx+1:])
+		if value != "" && value != "ssdp:all" && len(value) <= 128 {
+			st = value
+		}
+	}
+
+	return []byte(fmt.Sprintf("HTTP/1.1 200 OK\r\n"+
+		"CACHE-CONTROL: max-age=120\r\n"+
+		"ST: %s\r\n"+
+		"USN: %s::%s\r\n"+
+		"EXT:\r\n"+
+		"SERVER: %s\r\n"+
+		"LOCATION: http://%s/rootDesc.xml\r\n"+
+		"\r\n", st, ssdpUUID, st, ssdpServer, net.JoinHostPort(local.String(), "1900")))
+}
+
+// answerMemcached answers the memcached commands sent in a UDP frame. The stored values are never returned
+func answerMemcached(query []byte, _ net.IP) []byte {
+	// Request ID, sequence number, number of datagrams, reserved
+	if len(query) < 9 {
+		return nil
+	}
+
+	command := string(query[8:])
+	if idx := strings.IndexAny(command, "\r\n"); idx >= 0 {
+		command = command[:idx]
+	}
+
+	var response string
+	switch verb := strings.ToLower(strings.SplitN(command, " ", 2)[0]); verb {
+	case "stats":
+		response = fmt.Sprintf("STAT pid 612\r\nSTAT uptime %d\r\nSTAT time %d\r\nSTAT version %s\r\nSTAT curr_connections 2\r\nSTAT curr_items 0\r\nEND\r\n",
+			int(time.Since(startTime).Seconds())+86400*12, time.Now().Unix(), memcVersion)
+	case "version":
+		response = "VERSION " + memcVersion + "\r\n"
+	case "get", "gets":
+		response = "END\r\n"
+	case "set", "add", "replace", "append", "prepend", "cas":
+		response = "STORED\r\n"
+	case "delete":
+		response = "NOT_FOUND\r\n"
+	default:
+		response = "ERROR\r\n"
+	}
+
+	reply := make([]byte, 8, 8+len(response))
+	copy(reply[0:2], query[0:2])
+	binary.BigEndian.PutUint16(reply[4:6], 1)
+
+	return append(reply, response...)
+}
+
+var startTime = time.Now()
@@ -0,0 +1,171 @@
+package udpresponders
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// ServiceDNS answers the DNS queries
+	ServiceDNS = "dns"
+
+	// ServiceNTP answers the NTP client, control and monlist requests
+	ServiceNTP = "ntp"
+
+	// ServiceSSDP answers the SSDP M-SEARCH discovery requests
+	ServiceSSDP = "ssdp"
+
+	// ServiceMemcached answers the memcached commands sent over UDP
+	ServiceMemcached = "memcached"
+
+	// rateLimitWindow is the period over which the replies are counted
+	rateLimitWindow = time.Minute
+)
+
+var (
+	// Default is the server used by the sensor, nil if the UDP responders are disabled
+	Default *Server
+
+	// Services lists the supported services
+	Services = []string{ServiceDNS, ServiceNTP, ServiceSSDP, ServiceMemcached}
+
+	handlers = map[string]func(query []byte, local net.IP) []byte{
+		ServiceDNS:       answerDNS,
+		ServiceNTP:       answerNTP,
+		ServiceSSDP:      answerSSDP,
+		ServiceMemcached: answerMemcached,
+	}
+)
+
+// Reply describes the answer to a query. The payload is empty if the query was not understood, if the reply would have
+// been too large, or if the source went over the rate limits
+type Reply struct {
+	Service     string
+	Payload     []byte
+	RateLimited bool
+	Oversized   bool
+}
+
+// Options describes the behavior of a Server
+type Options struct {
+	// Services maps the name of the services to the ports they answer on
+	Services map[string][]int
+	// MaxReplySize is the size of the largest reply sent
+	MaxReplySize int
+	// PerSourceRateLimit is the number of replies sent to a single source address per minute
+	PerSourceRateLimit int
+	// GlobalRateLimit is the number of replies sent to all the sources per minute
+	GlobalRateLimit int
+}
+
+// Server answers the UDP queries seen by the sensor from the sockets bound to the ports of the services, so that the
+// replies come from the expected port
+type Server struct {
+	opts    Options
+	ports   map[uint16]string
+	limiter *limiter
+
+	lock  sync.RWMutex
+	conns map[uint16]*net.UDPConn
+}
+
+// New creates a Server
+func New(opts Options) (*Server, error) {
+	ports := make(map[uint16]string)
+
+	for service, servicePorts := range opts.Services {
+		if _, ok := handlers[service]; !ok {
+			return nil, fmt.Errorf("unknown UDP service '%s'", service)
+		}
+
+		for _, port := range servicePorts {
+			if other, ok := ports[uint16(port)]; ok {
+				return nil, fmt.Errorf("port %d is used by both the %s and %s UDP services", port, other, service)
+			}
+			ports[uint16(port)] = service
+		}
+	}
+
+	return &Server{
+		opts:    opts,
+		ports:   ports,
+		limiter: newLimiter(opts.PerSourceRateLimit, opts.GlobalRateLimit, rateLimitWindow),
+		conns:   make(map[uint16]*net.UDPConn),
+	}, nil
+}
+
+// Ports returns the ports of the enabled services, in ascending order
+func (s *Server) Ports() []int {
+	var ports []int
+	for port := range s.ports {
+		ports = append(ports, int(port))
+	}
+
+	sort.Ints(ports)
+	return ports
+}
+
+// Listen binds the socket used to answer the queries sent to the given port. The socket has to be drained by the
+// caller, as the queries are read from the captured packets instead
+func (s *Server) Listen(address string, port int) (*net.UDPConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	s.conns[uint16(port)] = conn
+	s.lock.Unlock()
+
+	return conn, nil
+}
+
+// Answer sends the reply of the service listening on the destination port of a query, and returns it. It returns nil
+// if no service listens on the port
+func (s *Server) Answer(src *net.UDPAddr, dst *net.UDPAddr, query []byte) *Reply {
+	service, ok := s.ports[uint16(dst.Port)]
+	if !ok {
+		return nil
+	}
+
+	s.lock.RLock()
+	conn := s.conns[uint16(dst.Port)]
+	s.lock.RUnlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	reply := &Reply{Service: service}
+
+	payload := handlers[service](query, dst.IP)
+	if len(payload) == 0 {
+		return reply
+	}
+
+	if len(payload) > s.opts.MaxReplySize {
+		reply.Oversized = true
+		return reply
+	}
+
+	if !s.limiter.allow(src.IP.String(), time.Now()) {
+		reply.RateLimited = true
+		return reply
+	}
+
+	if _, err := conn.WriteToUDP(payload, src); err != nil {
+		return reply
+	}
+
+	reply.Payload = payload
+	return reply
+}
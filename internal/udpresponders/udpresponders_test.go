@@ -0,0 +1,191 @@
+package udpresponders
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dnsQuery(name string, qtype uint16, qclass uint16) []byte {
+	query := []byte{0x13, 0x37, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}
+	for _, label := range strings.Split(name, ".") {
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0, byte(qtype>>8), byte(qtype), byte(qclass>>8), byte(qclass))
+
+	// EDNS OPT record
+	return append(query, 0, 0, 41, 0x10, 0, 0, 0, 0, 0, 0, 0)
+}
+
+func TestAnswerDNS(t *testing.T) {
+	local := net.ParseIP("192.0.2.1")
+
+	reply := answerDNS(dnsQuery("example.com", dnsTypeA, dnsClassIN), local)
+	if len(reply) != 12+17+16 || reply[0] != 0x13 || reply[1] != 0x37 || reply[2]&0x80 == 0 {
+		t.Fatalf("unexpected reply %x", reply)
+	}
+
+	if ancount := binary.BigEndian.Uint16(reply[6:8]); ancount != 1 || binary.BigEndian.Uint16(reply[10:12]) != 0 {
+		t.Errorf("unexpected counts %x", reply[4:12])
+	}
+
+	if !bytes.Equal(reply[len(reply)-4:], local.To4()) {
+		t.Errorf("unexpected address %x", reply[len(reply)-4:])
+	}
+
+	reply = answerDNS(dnsQuery("version.bind", dnsTypeTXT, dnsClassCH), local)
+	if !bytes.HasSuffix(reply, []byte(dnsVersion)) {
+		t.Errorf("unexpected version reply %q", reply)
+	}
+
+	reply = answerDNS(dnsQuery("example.com", 255, dnsClassIN), local)
+	if binary.BigEndian.Uint16(reply[6:8]) != 0 || len(reply) != 12+17 {
+		t.Errorf("unexpected ANY reply %x", reply)
+	}
+
+	if reply := answerDNS([]byte{0x13, 0x37, 0x81, 0x80}, local); reply != nil {
+		t.Errorf("expected the truncated query to be ignored, got %x", reply)
+	}
+}
+
+func TestAnswerNTP(t *testing.T) {
+	query := make([]byte, 48)
+	query[0] = 0x23
+	copy(query[40:48], "origin!!")
+
+	reply := answerNTP(query, nil)
+	if len(reply) != 48 || reply[0] != 0x24 || reply[1] != 2 || string(reply[24:32]) != "origin!!" {
+		t.Errorf("unexpected client reply %x", reply)
+	}
+
+	reply = answerNTP([]byte{0x16, 0x02, 0x00, 0x01, 0, 0, 0, 0, 0, 0, 0, 0}, nil)
+	if len(reply)%4 != 0 || reply[1] != 0x82 || !bytes.Contains(reply, []byte("ntpd 4.2.8")) {
+		t.Errorf("unexpected control reply %q", reply)
+	}
+
+	// monlist
+	reply = answerNTP([]byte{0x17, 0x00, 0x03, 0x2a, 0, 0, 0, 0}, nil)
+	if len(reply) != 8 || reply[3] != 0x2a || reply[4]>>4 != 4 {
+		t.Errorf("unexpected monlist reply %x", reply)
+	}
+}
+
+func TestAnswerSSDP(t *testing.T) {
+	query := "M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n\r\n"
+
+	reply := string(answerSSDP([]byte(query), net.ParseIP("192.0.2.1")))
+	if !strings.HasPrefix(reply, "HTTP/1.1 200 OK\r\n") ||
+		!strings.Contains(reply, "ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n") ||
+		!strings.Contains(reply, "LOCATION: http://192.0.2.1:1900/rootDesc.xml\r\n") {
+		t.Errorf("unexpected reply %q", reply)
+	}
+
+	if reply := answerSSDP([]byte("NOTIFY * HTTP/1.1\r\n\r\n"), net.ParseIP("192.0.2.1")); reply != nil {
+		t.Errorf("expected NOTIFY to be ignored, got %q", reply)
+	}
+}
+
+func TestAnswerMemcached(t *testing.T) {
+	for command, expected := range map[string]string{
+		"stats\r\n":          "STAT pid",
+		"version\r\n":        "VERSION 1.5.6\r\n",
+		"get key\r\n":        "END\r\n",
+		"set key 0 0 1\r\nA": "STORED\r\n",
+		"flush_all\r\n":      "ERROR\r\n",
+	} {
+		reply := answerMemcached(append([]byte{0xbe, 0xef, 0, 0, 0, 1, 0, 0}, command...), nil)
+		if len(reply) < 8 || reply[0] != 0xbe || reply[1] != 0xef || !strings.HasPrefix(string(reply[8:]), expected) {
+			t.Errorf("unexpected reply to %q : %q", command, reply)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(2, 3, time.Minute)
+	now := time.Now()
+
+	for idx, expected := range []bool{true, true, false} {
+		if l.allow("192.0.2.1", now) != expected {
+			t.Errorf("unexpected result for reply %d", idx)
+		}
+	}
+
+	if !l.allow("192.0.2.2", now) || l.allow("192.0.2.3", now) {
+		t.Error("expected the global limit to be enforced")
+	}
+
+	if !l.allow("192.0.2.1", now.Add(time.Minute)) {
+		t.Error("expected the limits to be reset")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Options{Services: map[string][]int{"chargen": {19}}}); err == nil {
+		t.Error("expected an unknown service to be rejected")
+	}
+
+	if _, err := New(Options{Services: map[string][]int{ServiceDNS: {53}, ServiceNTP: {53}}}); err == nil {
+		t.Error("expected a port used twice to be rejected")
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	server, err := New(Options{
+		Services:           map[string][]int{ServiceMemcached: {0}},
+		MaxReplySize:       64,
+		PerSourceRateLimit: 1,
+		GlobalRateLimit:    10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := server.Listen("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	src := client.LocalAddr().(*net.UDPAddr)
+	dst := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0}
+	query := append([]byte{0, 1, 0, 0, 0, 1, 0, 0}, "version\r\n"...)
+
+	if reply := server.Answer(src, &net.UDPAddr{IP: dst.IP, Port: 11211}, query); reply != nil {
+		t.Errorf("expected no reply on an unused port, got %+v", reply)
+	}
+
+	// The stats reply is too large
+	if reply := server.Answer(src, dst, append([]byte{0, 1, 0, 0, 0, 1, 0, 0}, "stats\r\n"...)); reply == nil || !reply.Oversized || reply.Payload != nil {
+		t.Errorf("expected the reply to be too large, got %+v", reply)
+	}
+
+	reply := server.Answer(src, dst, query)
+	if reply == nil || reply.Service != ServiceMemcached || reply.RateLimited || len(reply.Payload) == 0 {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+
+	buf := make([]byte, 128)
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	n, from, err := client.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf[:n], reply.Payload) || from.Port != conn.LocalAddr().(*net.UDPAddr).Port {
+		t.Errorf("unexpected datagram %q from %s", buf[:n], from)
+	}
+
+	if reply := server.Answer(src, dst, query); reply == nil || !reply.RateLimited || reply.Payload != nil {
+		t.Errorf("expected the source to be rate limited, got %+v", reply)
+	}
+}
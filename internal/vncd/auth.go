@@ -0,0 +1,58 @@
+package vncd
+
+import (
+	"bytes"
+	"crypto/des"
+	"encoding/hex"
+	"strings"
+)
+
+// Encrypt computes the response of the VNC authentication to a challenge for the given password. The password is cut
+// to 8 characters, and the bits of each of its bytes are mirrored to form the DES key
+func Encrypt(challenge []byte, password string) []byte {
+	key := make([]byte, 8)
+	copy(key, password)
+	for idx, b := range key {
+		var mirrored byte
+		for bit := 0; bit < 8; bit++ {
+			mirrored = mirrored<<1 | (b>>uint(bit))&1
+		}
+		key[idx] = mirrored
+	}
+
+	// The key is always 8 bytes long
+	block, _ := des.NewCipher(key)
+
+	response := make([]byte, len(challenge)-len(challenge)%des.BlockSize)
+	for idx := 0; idx < len(response); idx += des.BlockSize {
+		block.Encrypt(response[idx:], challenge[idx:])
+	}
+
+	return response
+}
+
+// RecoverPassword looks for the password matching a response to a challenge of the VNC authentication among the
+// given candidates
+func RecoverPassword(challenge []byte, response []byte, candidates []string) (string, bool) {
+	if len(challenge) != challengeLength || len(response) != challengeLength {
+		return "", false
+	}
+
+	for _, candidate := range candidates {
+		if bytes.Equal(Encrypt(challenge, candidate), response) {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// Hash formats a challenge and its response as "$vnc$*<challenge>*<response>", the format of the VNC hashes of John
+// the Ripper. It is empty if the response is incomplete
+func Hash(challenge []byte, response []byte) string {
+	if len(challenge) != challengeLength || len(response) != challengeLength {
+		return ""
+	}
+
+	return "$vnc$*" + strings.ToUpper(hex.EncodeToString(challenge)) + "*" + strings.ToUpper(hex.EncodeToString(response))
+}
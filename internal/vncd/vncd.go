@@ -0,0 +1,260 @@
+package vncd
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bonjourmalware/melody/internal/netutils"
+)
+
+const (
+	// ClosedByClient is the reason of the sessions closed by the client before the end of the handshake
+	ClosedByClient = "client"
+
+	// ClosedByServer is the reason of the sessions closed at the end of the handshake, or after an invalid message
+	ClosedByServer = "server"
+
+	// ClosedByTimeout is the reason of the sessions closed after the client stayed idle for too long
+	ClosedByTimeout = "timeout"
+
+	// SecurityNone is the security type without authentication
+	SecurityNone = 1
+
+	// SecurityVNCAuth is the security type of the DES challenge-response authentication
+	SecurityVNCAuth = 2
+
+	// challengeLength is the length of the challenge of the VNC authentication, and of its response
+	challengeLength = 16
+)
+
+var versionRe = regexp.MustCompile(`^RFB (\d{3})\.(\d{3})\n$`)
+
+// ValidVersion checks if a protocol version is in the "RFB xxx.yyy" form
+func ValidVersion(version string) bool {
+	return versionRe.MatchString(version + "\n")
+}
+
+// Options describes the behavior of a Server
+type Options struct {
+	// Version is the protocol version announced by the server, such as "RFB 003.008"
+	Version string
+	// SecurityTypes lists the security types offered to the clients, by order of preference
+	SecurityTypes []uint8
+	// Timeout is the time after which the idle clients are disconnected
+	Timeout time.Duration
+}
+
+// Session describes the handshake of a client with the server
+type Session struct {
+	SourceIP   string
+	SourcePort uint16
+	DestPort   uint16
+	Start      time.Time
+	Duration   time.Duration
+	// ServerVersion and ClientVersion are the protocol versions sent by both sides, without their trailing newline
+	ServerVersion string
+	ClientVersion string
+	// SecurityType is the security type selected by the client, or by the server with the version 3.3 of the
+	// protocol. It is 0 if none has been selected
+	SecurityType uint8
+	// Challenge and Response are the random challenge of the VNC authentication, and the DES encrypted version sent
+	// back by the client
+	Challenge []byte
+	Response  []byte
+	ClosedBy  string
+}
+
+// Server is a low-interaction VNC server, running the RFB handshake until the authentication. The authentication
+// always fails
+type Server struct {
+	opts  Options
+	minor int
+}
+
+// New creates a Server. The options must hold a valid version
+func New(opts Options) *Server {
+	minor := 8
+	if match := versionRe.FindStringSubmatch(opts.Version + "\n"); match != nil {
+		minor, _ = strconv.Atoi(match[2])
+	}
+
+	return &Server{opts: opts, minor: minor}
+}
+
+// Serve runs the handshake of a VNC connection
+func (s *Server) Serve(c net.Conn) Session {
+	defer c.Close()
+
+	session := Session{Start: time.Now(), ServerVersion: s.opts.Version}
+	session.SourceIP, session.SourcePort, session.DestPort = netutils.Endpoints(c)
+
+	// The whole handshake is expected before the timeout
+	_ = c.SetDeadline(session.Start.Add(s.opts.Timeout))
+
+	session.ClosedBy = s.handshake(c, &session)
+	session.Duration = time.Since(session.Start)
+
+	return session
+}
+
+// handshake runs the version and security negotiations, and returns the reason of the end of the session
+func (s *Server) handshake(c net.Conn, session *Session) string {
+	r := bufio.NewReader(c)
+
+	if _, err := c.Write([]byte(s.opts.Version + "\n")); err != nil {
+		return closedBy(err)
+	}
+
+	version := make([]byte, 12)
+	n, err := io.ReadFull(r, version)
+	session.ClientVersion = strings.TrimSuffix(string(version[:n]), "\n")
+	if err != nil {
+		return closedBy(err)
+	}
+
+	match := versionRe.FindSubmatch(version)
+	if match == nil {
+		return ClosedByServer
+	}
+
+	// Use the highest version supported by both sides, among 3.3, 3.7 and 3.8. The unknown versions are handled as 3.3
+	minor, _ := strconv.Atoi(string(match[2]))
+	if minor > s.minor {
+		minor = s.minor
+	}
+
+	if minor < 7 {
+		return s.securityV3(c, r, session)
+	}
+
+	return s.security(c, r, session, minor)
+}
+
+// securityV3 runs the security handshake of the version 3.3 of the protocol, where the server decides the security
+// type
+func (s *Server) securityV3(c net.Conn, r *bufio.Reader, session *Session) string {
+	for _, secType := range s.opts.SecurityTypes {
+		if secType == SecurityNone || secType == SecurityVNCAuth {
+			session.SecurityType = secType
+			break
+		}
+	}
+
+	if session.SecurityType == 0 {
+		_, _ = c.Write(failure(0, "No supported security type"))
+		return ClosedByServer
+	}
+
+	if _, err := c.Write(uint32Bytes(uint32(session.SecurityType))); err != nil {
+		return closedBy(err)
+	}
+
+	return s.authenticate(c, r, session, 3)
+}
+
+// security runs the security handshake of the versions 3.7 and 3.8 of the protocol, where the client selects one of
+// the security types offered by the server
+func (s *Server) security(c net.Conn, r *bufio.Reader, session *Session, minor int) string {
+	if _, err := c.Write(append([]byte{uint8(len(s.opts.SecurityTypes))}, s.opts.SecurityTypes...)); err != nil {
+		return closedBy(err)
+	}
+
+	secType, err := r.ReadByte()
+	if err != nil {
+		return closedBy(err)
+	}
+	session.SecurityType = secType
+
+	offered := false
+	for _, t := range s.opts.SecurityTypes {
+		offered = offered || t == secType
+	}
+
+	if !offered {
+		if minor == 8 {
+			_, _ = c.Write(securityResult(false, "Security type not offered", minor))
+		}
+		return ClosedByServer
+	}
+
+	return s.authenticate(c, r, session, minor)
+}
+
+// authenticate runs the selected security type. The VNC authentication always fails, and the session ends after the
+// ClientInit message without authentication. The other types are not implemented, so the session ends as soon as they
+// are selected
+func (s *Server) authenticate(c net.Conn, r *bufio.Reader, session *Session, minor int) string {
+	switch session.SecurityType {
+	case SecurityNone:
+		// The result is only sent for None from the version 3.8
+		if minor == 8 {
+			if _, err := c.Write(securityResult(true, "", minor)); err != nil {
+				return closedBy(err)
+			}
+		}
+
+		// Read the shared flag of the ClientInit message
+		if _, err := r.ReadByte(); err != nil {
+			return closedBy(err)
+		}
+	case SecurityVNCAuth:
+		session.Challenge = make([]byte, challengeLength)
+		_, _ = rand.Read(session.Challenge)
+
+		if _, err := c.Write(session.Challenge); err != nil {
+			return closedBy(err)
+		}
+
+		response := make([]byte, challengeLength)
+		n, err := io.ReadFull(r, response)
+		session.Response = response[:n]
+		if err != nil {
+			return closedBy(err)
+		}
+
+		_, _ = c.Write(securityResult(false, "Authentication failed", minor))
+	}
+
+	return ClosedByServer
+}
+
+// securityResult encodes a SecurityResult message. The failure reason is only sent from the version 3.8
+func securityResult(ok bool, reason string, minor int) []byte {
+	if ok {
+		return uint32Bytes(0)
+	}
+
+	if minor < 8 {
+		return uint32Bytes(1)
+	}
+
+	return failure(1, reason)
+}
+
+// failure encodes a failure status followed by its reason
+func failure(status uint32, reason string) []byte {
+	msg := append(uint32Bytes(status), uint32Bytes(uint32(len(reason)))...)
+	return append(msg, reason...)
+}
+
+func uint32Bytes(n uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, n)
+	return b
+}
+
+// closedBy returns the reason of the end of a session interrupted by an error
+func closedBy(err error) string {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return ClosedByTimeout
+	}
+
+	return ClosedByClient
+}
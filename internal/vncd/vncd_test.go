@@ -0,0 +1,146 @@
+package vncd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// serve starts a session of a server with the given security types, and returns the client side of the connection
+func serve(t *testing.T, version string, securityTypes ...uint8) (net.Conn, chan Session) {
+	server := New(Options{Version: version, SecurityTypes: securityTypes, Timeout: time.Second})
+
+	client, conn := net.Pipe()
+	sessions := make(chan Session, 1)
+	go func() {
+		sessions <- server.Serve(conn)
+	}()
+
+	greeting := make([]byte, 12)
+	if _, err := io.ReadFull(client, greeting); err != nil || string(greeting) != version+"\n" {
+		t.Fatalf("unexpected greeting %q (%v)", greeting, err)
+	}
+
+	return client, sessions
+}
+
+func read(t *testing.T, c net.Conn, size int) []byte {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(c, data); err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestVNCAuth(t *testing.T) {
+	client, sessions := serve(t, "RFB 003.008", SecurityVNCAuth)
+	_, _ = client.Write([]byte("RFB 003.008\n"))
+
+	if types := read(t, client, 2); !bytes.Equal(types, []byte{1, SecurityVNCAuth}) {
+		t.Fatalf("unexpected security types %v", types)
+	}
+	_, _ = client.Write([]byte{SecurityVNCAuth})
+
+	challenge := read(t, client, challengeLength)
+	_, _ = client.Write(Encrypt(challenge, "123456"))
+
+	result := "\x00\x00\x00\x01\x00\x00\x00\x15Authentication failed"
+	if got := read(t, client, len(result)); string(got) != result {
+		t.Errorf("unexpected security result %q", got)
+	}
+
+	session := <-sessions
+	if session.ClientVersion != "RFB 003.008" || session.SecurityType != SecurityVNCAuth || session.ClosedBy != ClosedByServer {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	if !bytes.Equal(session.Challenge, challenge) {
+		t.Errorf("unexpected challenge %x", session.Challenge)
+	}
+
+	if password, ok := RecoverPassword(session.Challenge, session.Response, []string{"admin", "123456"}); !ok || password != "123456" {
+		t.Errorf("failed to recover the password from %x", session.Response)
+	}
+}
+
+func TestVersion33(t *testing.T) {
+	client, sessions := serve(t, "RFB 003.008", 16, SecurityVNCAuth)
+	_, _ = client.Write([]byte("RFB 003.003\n"))
+
+	// The server selects the security type
+	if secType := read(t, client, 4); !bytes.Equal(secType, []byte{0, 0, 0, SecurityVNCAuth}) {
+		t.Fatalf("unexpected security type %v", secType)
+	}
+
+	_ = read(t, client, challengeLength)
+	_, _ = client.Write(make([]byte, challengeLength))
+
+	if result := read(t, client, 4); !bytes.Equal(result, []byte{0, 0, 0, 1}) {
+		t.Errorf("unexpected security result %v", result)
+	}
+
+	if session := <-sessions; session.SecurityType != SecurityVNCAuth || len(session.Response) != challengeLength {
+		t.Errorf("unexpected session %+v", session)
+	}
+}
+
+func TestSecurityNone(t *testing.T) {
+	client, sessions := serve(t, "RFB 003.007", SecurityNone)
+	_, _ = client.Write([]byte("RFB 003.008\n"))
+
+	_ = read(t, client, 2)
+	_, _ = client.Write([]byte{SecurityNone})
+
+	// No SecurityResult is sent for None with the version 3.7
+	_, _ = client.Write([]byte{1})
+
+	if session := <-sessions; session.SecurityType != SecurityNone || session.Challenge != nil || session.ClosedBy != ClosedByServer {
+		t.Errorf("unexpected session %+v", session)
+	}
+}
+
+func TestSecurityNotOffered(t *testing.T) {
+	client, sessions := serve(t, "RFB 003.008", SecurityVNCAuth)
+	_, _ = client.Write([]byte("RFB 003.008\n"))
+
+	_ = read(t, client, 2)
+	_, _ = client.Write([]byte{SecurityNone})
+
+	result := "\x00\x00\x00\x01\x00\x00\x00\x19Security type not offered"
+	if got := read(t, client, len(result)); string(got) != result {
+		t.Errorf("unexpected security result %q", got)
+	}
+
+	if session := <-sessions; session.SecurityType != SecurityNone || session.ClosedBy != ClosedByServer {
+		t.Errorf("unexpected session %+v", session)
+	}
+}
+
+func TestInvalidVersion(t *testing.T) {
+	client, sessions := serve(t, "RFB 003.008", SecurityVNCAuth)
+	_, _ = client.Write([]byte("GET / HTTP/1"))
+
+	if session := <-sessions; session.ClientVersion != "GET / HTTP/1" || session.ClosedBy != ClosedByServer {
+		t.Errorf("unexpected session %+v", session)
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	challenge, _ := hex.DecodeString("0123456789abcdef0123456789abcdef")
+	expected := "2b237257e9f90c812b237257e9f90c81"
+
+	// Only the first 8 characters of the password are used
+	for _, password := range []string{"password", "password123"} {
+		if got := hex.EncodeToString(Encrypt(challenge, password)); got != expected {
+			t.Errorf("unexpected response for %s : %s", password, got)
+		}
+	}
+
+	if hash := Hash(challenge, challenge); hash != "$vnc$*0123456789ABCDEF0123456789ABCDEF*0123456789ABCDEF0123456789ABCDEF" {
+		t.Errorf("unexpected hash %s", hash)
+	}
+}
@@ -0,0 +1,265 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// OpContinuation is the opcode of the frames continuing a fragmented message
+	OpContinuation = 0x0
+	// OpText is the opcode of the text frames
+	OpText = 0x1
+	// OpBinary is the opcode of the binary frames
+	OpBinary = 0x2
+	// OpClose is the opcode of the close frames
+	OpClose = 0x8
+	// OpPing is the opcode of the ping frames
+	OpPing = 0x9
+	// OpPong is the opcode of the pong frames
+	OpPong = 0xa
+
+	// acceptGUID is appended to the key of the client to compute the accept header (RFC 6455)
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// maxControlPayload is the largest payload allowed in a control frame
+	maxControlPayload = 125
+)
+
+var (
+	// ErrNotHijackable is returned when the connection of a request cannot be taken over, as with HTTP/2
+	ErrNotHijackable = errors.New("the connection cannot be hijacked")
+
+	errInvalidLength = errors.New("invalid frame length")
+
+	opcodeNames = map[byte]string{
+		OpContinuation: "continuation",
+		OpText:         "text",
+		OpBinary:       "binary",
+		OpClose:        "close",
+		OpPing:         "ping",
+		OpPong:         "pong",
+	}
+)
+
+// OpcodeName returns the name of an opcode, or "reserved" if it is not defined
+func OpcodeName(opcode byte) string {
+	if name, ok := opcodeNames[opcode]; ok {
+		return name
+	}
+
+	return "reserved"
+}
+
+// Options describes the behavior of a Server
+type Options struct {
+	// MaxFrames is the number of frames read before closing the session
+	MaxFrames int
+	// MaxPayloadSize is the size of the payload kept for each frame, the rest is discarded
+	MaxPayloadSize int
+	// Timeout is the time after which the idle clients are disconnected
+	Timeout     time.Duration
+	MaxDuration time.Duration
+}
+
+// Frame describes a frame sent by the client. The payload is unmasked and holds at most Options.MaxPayloadSize bytes,
+// while Length is the length announced by the client
+type Frame struct {
+	Index     int
+	Time      time.Time
+	Fin       bool
+	Opcode    byte
+	Masked    bool
+	Length    uint64
+	Payload   []byte
+	Truncated bool
+}
+
+// Server completes the WebSocket upgrades and reads the frames sent by the clients. It never sends data, except to
+// answer the ping and close frames
+type Server struct {
+	opts Options
+}
+
+// New creates a Server
+func New(opts Options) *Server {
+	return &Server{opts: opts}
+}
+
+// IsUpgrade checks if a request asks to switch to the WebSocket protocol
+func IsUpgrade(r *http.Request) bool {
+	if r.Method != http.MethodGet || r.Header.Get("Sec-WebSocket-Key") == "" {
+		return false
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(r.Header.Get("Upgrade")), "websocket") {
+		return false
+	}
+
+	for _, token := range strings.Split(r.Header.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AcceptKey computes the value of the Sec-WebSocket-Accept header answering the given Sec-WebSocket-Key
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Serve completes the upgrade requested by r, then passes each frame sent by the client to handle until the client
+// leaves, stays idle for too long, reaches the maximum duration or sends too many frames. The first subprotocol
+// offered by the client is selected. It returns ErrNotHijackable if the upgrade could not be completed, in which case
+// nothing has been sent
+func (s *Server) Serve(w http.ResponseWriter, r *http.Request, handle func(Frame)) error {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return ErrNotHijackable
+	}
+
+	c, buf, err := hijacker.Hijack()
+	if err != nil {
+		return ErrNotHijackable
+	}
+	defer c.Close()
+
+	start := time.Now()
+	var deadline time.Time
+	if s.opts.MaxDuration > 0 {
+		deadline = start.Add(s.opts.MaxDuration)
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n"
+	if protocol := strings.TrimSpace(strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",")[0]); protocol != "" {
+		response += "Sec-WebSocket-Protocol: " + protocol + "\r\n"
+	}
+
+	if _, err := buf.WriteString(response + "\r\n"); err != nil {
+		return nil
+	}
+	if err := buf.Flush(); err != nil {
+		return nil
+	}
+
+	for idx := 0; idx < s.opts.MaxFrames; idx++ {
+		readDeadline := time.Now().Add(s.opts.Timeout)
+		if !deadline.IsZero() && deadline.Before(readDeadline) {
+			readDeadline = deadline
+		}
+		_ = c.SetReadDeadline(readDeadline)
+
+		frame, err := s.readFrame(buf.Reader)
+		if err != nil {
+			return nil
+		}
+
+		frame.Index = idx
+		handle(frame)
+
+		switch frame.Opcode {
+		case OpPing:
+			if frame.Length <= maxControlPayload {
+				_ = writeFrame(c, OpPong, frame.Payload)
+			}
+		case OpClose:
+			var status []byte
+			if len(frame.Payload) >= 2 {
+				status = frame.Payload[:2]
+			}
+			_ = writeFrame(c, OpClose, status)
+			return nil
+		}
+	}
+
+	// Normal closure
+	_ = writeFrame(c, OpClose, []byte{0x03, 0xe8})
+	return nil
+}
+
+// readFrame reads a frame, keeping at most MaxPayloadSize bytes of its payload
+func (s *Server) readFrame(r *bufio.Reader) (Frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Frame{}, err
+	}
+
+	frame := Frame{
+		Fin:    header[0]&0x80 != 0,
+		Opcode: header[0] & 0x0f,
+		Masked: header[1]&0x80 != 0,
+		Length: uint64(header[1] & 0x7f),
+	}
+
+	switch frame.Length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return Frame{}, err
+		}
+		frame.Length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return Frame{}, err
+		}
+		frame.Length = binary.BigEndian.Uint64(ext[:])
+		if frame.Length > math.MaxInt64 {
+			return Frame{}, errInvalidLength
+		}
+	}
+
+	var key [4]byte
+	if frame.Masked {
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			return Frame{}, err
+		}
+	}
+
+	kept := frame.Length
+	if kept > uint64(s.opts.MaxPayloadSize) {
+		kept = uint64(s.opts.MaxPayloadSize)
+		frame.Truncated = true
+	}
+
+	frame.Payload = make([]byte, kept)
+	if _, err := io.ReadFull(r, frame.Payload); err != nil {
+		return Frame{}, err
+	}
+
+	if frame.Truncated {
+		if _, err := io.CopyN(ioutil.Discard, r, int64(frame.Length-kept)); err != nil {
+			return Frame{}, err
+		}
+	}
+
+	if frame.Masked {
+		for idx := range frame.Payload {
+			frame.Payload[idx] ^= key[idx%4]
+		}
+	}
+
+	frame.Time = time.Now()
+	return frame, nil
+}
+
+// writeFrame sends an unfragmented and unmasked frame, as sent by a server
+func writeFrame(w io.Writer, opcode byte, payload []byte) error {
+	_, err := w.Write(append([]byte{0x80 | opcode, byte(len(payload))}, payload...))
+	return err
+}
@@ -0,0 +1,121 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// Example of RFC 6455
+	if key := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); key != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected key %s", key)
+	}
+}
+
+func TestIsUpgrade(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/kernels/1/channels", nil)
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+
+	if !IsUpgrade(r) {
+		t.Error("expected the request to be an upgrade")
+	}
+
+	r.Header.Del("Sec-WebSocket-Key")
+	if IsUpgrade(r) {
+		t.Error("expected the request without key not to be an upgrade")
+	}
+}
+
+func maskedFrame(opcode byte, payload []byte) []byte {
+	key := []byte{0x12, 0x34, 0x56, 0x78}
+	frame := []byte{0x80 | opcode}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	}
+
+	frame = append(frame, key...)
+	for idx, b := range payload {
+		frame = append(frame, b^key[idx%4])
+	}
+
+	return frame
+}
+
+func TestServe(t *testing.T) {
+	server := New(Options{MaxFrames: 10, MaxPayloadSize: 16, Timeout: time.Second})
+
+	frames := make(chan Frame, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := server.Serve(w, r, func(frame Frame) { frames <- frame }); err != nil {
+			t.Error(err)
+		}
+		close(frames)
+	}))
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, _ = conn.Write([]byte("GET /exec HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Protocol: v4.channel.k8s.io, base64.channel.k8s.io\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" ||
+		resp.Header.Get("Sec-WebSocket-Protocol") != "v4.channel.k8s.io" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	_, _ = conn.Write(maskedFrame(OpText, []byte("id")))
+	_, _ = conn.Write(maskedFrame(OpBinary, []byte(strings.Repeat("A", 200))))
+	_, _ = conn.Write(maskedFrame(OpPing, []byte("hi")))
+
+	pong := make([]byte, 4)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := reader.Read(pong); err != nil || !bytes.Equal(pong, []byte{0x80 | OpPong, 2, 'h', 'i'}) {
+		t.Errorf("unexpected pong %x (%v)", pong, err)
+	}
+
+	_, _ = conn.Write(maskedFrame(OpClose, []byte{0x03, 0xe8}))
+
+	var received []Frame
+	for frame := range frames {
+		received = append(received, frame)
+	}
+
+	if len(received) != 4 {
+		t.Fatalf("unexpected frames %+v", received)
+	}
+
+	if text := received[0]; !text.Fin || !text.Masked || text.Opcode != OpText || string(text.Payload) != "id" {
+		t.Errorf("unexpected text frame %+v", text)
+	}
+
+	if binary := received[1]; binary.Length != 200 || !binary.Truncated || string(binary.Payload) != strings.Repeat("A", 16) {
+		t.Errorf("unexpected binary frame %+v", binary)
+	}
+
+	if closing := received[3]; closing.Opcode != OpClose || closing.Index != 3 {
+		t.Errorf("unexpected close frame %+v", closing)
+	}
+}